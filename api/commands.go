@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	WebSocket command types
+const (
+	wsSubscribe   = "subscribe"
+	wsUnsubscribe = "unsubscribe"
+	wsPing        = "ping"
+	wsResume      = "resume"
+)
+
+const (
+	//	The largest command a client can send (bigger messages close the connection)
+	maxCommandSize = 4096
+
+	//	The most subscriptions a single connection can have
+	maxSubscriptions = 100
+)
+
+//	Validates and runs a command sent by a client.  Problems are sent back to
+//	the client as Error messages
+func (c *connection) handleCommand(message []byte) {
+	command := datastores.WebSocketCommand{}
+
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&command); err != nil {
+		c.h.reply(c, []byte(getWSError("Invalid command: " + err.Error())))
+		return
+	}
+
+	switch command.Type {
+	case wsSubscribe, wsUnsubscribe:
+		c.handleSubscription(command)
+
+	case wsPing:
+		c.h.reply(c, []byte(getWSResponse("Pong", datastores.ConfigItem{})))
+
+	case wsResume:
+		if command.Since < 0 {
+			c.h.reply(c, []byte(getWSError("Invalid command: since can't be negative")))
+			return
+		}
+		c.h.resume(c, command.Since, false)
+
+	case "":
+		c.h.reply(c, []byte(getWSError("Invalid command: type is required")))
+
+	default:
+		c.h.reply(c, []byte(getWSError(fmt.Sprintf("Unknown command type '%s'.  Should be one of: subscribe, unsubscribe, ping, resume", command.Type))))
+	}
+}
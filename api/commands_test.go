@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/websocket"
+)
+
+//	Starts a test WebSocket server for the hub
+func getTestWsServer(hub *Hub) (*httptest.Server, string) {
+	server := httptest.NewServer(WsHandler{H: hub})
+	return server, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+//	Waits for the hub to have the number of connections
+func waitForConnections(hub *Hub, count int) {
+	for i := 0; i < 100; i++ {
+		hub.connectionsMx.RLock()
+		current := len(hub.connections)
+		hub.connectionsMx.RUnlock()
+		if current == count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//	Reads the next WebSocket response
+func readWSResponse(conn *websocket.Conn) (datastores.WebSocketResponse, error) {
+	response := datastores.WebSocketResponse{}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	err := conn.ReadJSON(&response)
+	return response, err
+}
+
+//	Commands should be validated and answered
+func TestWsHandler_Commands(t *testing.T) {
+	//	Arrange
+	server, url := getTestWsServer(NewHub())
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		command      string
+		responseType string
+	}{
+		{`{"type":"ping"}`, "Pong"},
+		{`{"type":"subscribe","application":"billing"}`, "Subscribed"},
+		{`{"type":"unsubscribe","application":"billing"}`, "Unsubscribed"},
+		{`{"type":"subscribe","name":"db.[a"}`, "Error"},
+		{`{"type":"resume","since":-1}`, "Error"},
+		{`{"type":"shout"}`, "Error"},
+		{`{"application":"billing"}`, "Error"},
+		{`{"type":"ping","extra":true}`, "Error"},
+		{`not json`, "Error"},
+	}
+
+	for _, test := range tests {
+		//	Act
+		conn.WriteMessage(websocket.TextMessage, []byte(test.command))
+		response, err := readWSResponse(conn)
+
+		//	Assert
+		if err != nil || response.Type != test.responseType {
+			t.Errorf("%s should have returned %s but returned %+v / %v", test.command, test.responseType, response, err)
+		}
+	}
+}
+
+//	Events sent by clients shouldn't be broadcast
+func TestWsHandler_ClientEvent_NotBroadcast(t *testing.T) {
+	//	Arrange
+	hub := NewHub()
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	forger, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer forger.Close()
+
+	listener, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer listener.Close()
+	waitForConnections(hub, 2)
+
+	//	Act
+	forger.WriteMessage(websocket.TextMessage, []byte(getWSResponse("Updated", datastores.ConfigItem{Application: "billing", Name: "Forged"})))
+	forgerResponse, forgerErr := readWSResponse(forger)
+	hub.Broadcast <- []byte(getWSResponse("Updated", datastores.ConfigItem{Application: "billing", Name: "Real"}))
+
+	//	Assert
+	if forgerErr != nil || forgerResponse.Type != "Error" {
+		t.Errorf("Forged event should have been rejected: %+v / %v", forgerResponse, forgerErr)
+	}
+
+	event, err := readWSResponse(listener)
+	if err != nil || event.Data.Name != "Real" {
+		t.Errorf("Listener should only have gotten the real event: %+v / %v", event, err)
+	}
+}
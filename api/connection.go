@@ -1,37 +1,208 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/cagedtornado/centralconfig/datastores"
 	"github.com/gorilla/websocket"
 )
 
+//	The number of messages that can be waiting for a connection
+const sendQueueSize = 256
+
+//	WebSocket keepalive settings
+const (
+	//	How long a write to the client can take
+	writeWait = 10 * time.Second
+
+	//	How long we wait to hear from the client (a pong counts)
+	defaultPongWait = 60 * time.Second
+
+	//	How often the client is pinged.  It has to be less than the pong wait
+	defaultPingPeriod = (defaultPongWait * 9) / 10
+)
+
 type connection struct {
 	// Buffered channel of outbound messages.
 	send chan []byte
 
 	// The Hub.
 	h *Hub
+
+	// Protects the send channel.  Once it's closed nothing else is queued, and
+	// dropped counts the messages that didn't fit since the last one that did
+	sendMx  sync.Mutex
+	closed  bool
+	dropped int
+
+	// Subscriptions.  Until the first subscribe, all events are sent
+	subscriptionsMx sync.RWMutex
+	subscribed      bool
+	subscriptions   []subscription
+}
+
+//	Creates a connection to the hub
+func newConnection(h *Hub) *connection {
+	return &connection{send: make(chan []byte, sendQueueSize), h: h}
+}
+
+//	Queues a message for the connection without waiting.  Returns false if the
+//	queue is full (or the connection is closed) and the message was dropped.  If
+//	messages were dropped, the connection is told how many before the next one
+func (c *connection) queue(message []byte) bool {
+	c.sendMx.Lock()
+	defer c.sendMx.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	needed := 1
+	if c.dropped > 0 {
+		needed = 2
+	}
+
+	if cap(c.send)-len(c.send) < needed {
+		c.dropped++
+		return false
+	}
+
+	if c.dropped > 0 {
+		c.send <- []byte(getWSNotice("Dropped", fmt.Sprintf("%d messages were dropped because the connection couldn't keep up", c.dropped)))
+		c.dropped = 0
+	}
+
+	c.send <- message
+	return true
+}
+
+//	Returns the number of messages that can be queued right now
+func (c *connection) available() int {
+	c.sendMx.Lock()
+	defer c.sendMx.Unlock()
+
+	if c.closed {
+		return 0
+	}
+
+	return cap(c.send) - len(c.send)
+}
+
+//	Closes the send channel (so the writer stops)
+func (c *connection) close() {
+	c.sendMx.Lock()
+	defer c.sendMx.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
 }
 
 func (c *connection) reader(wg *sync.WaitGroup, wsConn *websocket.Conn) {
 	defer wg.Done()
+
+	//	When the client goes away (or stops answering pings) the writer is stopped too:
+	defer c.h.removeConnection(c)
+
+	wsConn.SetReadDeadline(time.Now().Add(c.h.pongWait))
+	wsConn.SetPongHandler(func(string) error {
+		return wsConn.SetReadDeadline(time.Now().Add(c.h.pongWait))
+	})
+
 	for {
 		_, message, err := wsConn.ReadMessage()
 		if err != nil {
 			break
 		}
-		c.h.Broadcast <- message
+
+		//	Clients can only send commands.  Nothing they send is broadcast
+		c.handleCommand(message)
 	}
 }
 
+//	Subscribes or unsubscribes and lets the client know how it went
+func (c *connection) handleSubscription(command datastores.WebSocketCommand) {
+	sub, err := newSubscription(command)
+	if err != nil {
+		c.h.reply(c, []byte(getWSError("Invalid pattern: "+err.Error())))
+		return
+	}
+
+	c.subscriptionsMx.Lock()
+	responseType := "Unsubscribed"
+	if command.Type == wsSubscribe {
+		if len(c.subscriptions) >= maxSubscriptions {
+			c.subscriptionsMx.Unlock()
+			c.h.reply(c, []byte(getWSError(fmt.Sprintf("Too many subscriptions (the limit is %d)", maxSubscriptions))))
+			return
+		}
+
+		responseType = "Subscribed"
+		c.subscribed = true
+		c.subscriptions = append(c.subscriptions, sub)
+	} else {
+		for i, existing := range c.subscriptions {
+			if existing == sub {
+				c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+				break
+			}
+		}
+	}
+	c.subscriptionsMx.Unlock()
+
+	c.h.reply(c, []byte(getWSResponse(responseType, datastores.ConfigItem{Application: sub.application, Machine: sub.machine, Name: sub.name})))
+}
+
+//	Returns true if the connection should get events for the config item
+func (c *connection) wants(item datastores.ConfigItem) bool {
+	c.subscriptionsMx.RLock()
+	defer c.subscriptionsMx.RUnlock()
+
+	if !c.subscribed {
+		return true
+	}
+
+	for _, sub := range c.subscriptions {
+		if sub.matches(item) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *connection) writer(wg *sync.WaitGroup, wsConn *websocket.Conn) {
 	defer wg.Done()
-	for message := range c.send {
-		err := wsConn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			break
+
+	//	If we stop writing, close the connection so the reader stops too:
+	defer wsConn.Close()
+
+	ping := time.NewTicker(c.h.pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			wsConn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				//	The hub removed the connection:
+				wsConn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			if err := wsConn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ping.C:
+			wsConn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := wsConn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
@@ -43,18 +214,37 @@ type WsHandler struct {
 }
 
 func (wsh WsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	//	Clients that are reconnecting can ask for the events they missed:
+	since, resume := int64(0), r.URL.Query().Get("since") != ""
+	if resume {
+		var err error
+		since, err = strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "since should be a sequence number", http.StatusBadRequest)
+			return
+		}
+	}
+
 	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		/* Should we do something here?
 		log.Printf("error upgrading %s", err) */
 		return
 	}
-	c := &connection{send: make(chan []byte, 256), h: wsh.H}
-	c.h.addConnection(c)
+	wsConn.SetReadLimit(maxCommandSize)
+
+	c := newConnection(wsh.H)
 	defer c.h.removeConnection(c)
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go c.writer(&wg, wsConn)
+
+	if resume {
+		c.h.resume(c, since, true)
+	} else {
+		c.h.addConnection(c)
+	}
+
 	go c.reader(&wg, wsConn)
 	wg.Wait()
 	wsConn.Close()
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Error codes for request errors that don't come from the datastore
+//	(see the datastores package for the rest)
+const (
+	codeMethodNotAllowed = "method_not_allowed"
+	codeUnprocessable    = "unprocessable"
+)
+
+//	Gets the HTTP status, error code and message to send for an error.  Datastore
+//	errors get the status for their code (so a missing item is a 404) -- other
+//	errors keep the given status.  Unexpected server errors are logged instead of
+//	being sent, so driver messages don't end up in responses
+func getErrorDetails(err error, status int) (int, string, string) {
+	var configErr *datastores.ConfigError
+	if errors.As(err, &configErr) {
+		return getErrorStatus(configErr.Code), configErr.Code, configErr.Message
+	}
+
+	code := getStatusErrorCode(status)
+	if code == datastores.CodeInternal {
+		log.Printf("[ERROR] %v\n", err)
+		return status, code, "An unexpected error occurred"
+	}
+
+	return status, code, err.Error()
+}
+
+//	Gets the HTTP status for an error code
+func getErrorStatus(code string) int {
+	switch code {
+	case datastores.CodeNotFound:
+		return http.StatusNotFound
+	case datastores.CodeConflict:
+		return http.StatusConflict
+	case datastores.CodeValidation:
+		return http.StatusBadRequest
+	case datastores.CodeForbidden:
+		return http.StatusForbidden
+	case datastores.CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case codeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case codeUnprocessable:
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
+
+//	Gets the error code for an HTTP status
+func getStatusErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return datastores.CodeValidation
+	case http.StatusForbidden:
+		return datastores.CodeForbidden
+	case http.StatusNotFound:
+		return datastores.CodeNotFound
+	case http.StatusConflict:
+		return datastores.CodeConflict
+	case http.StatusServiceUnavailable:
+		return datastores.CodeUnavailable
+	case http.StatusMethodNotAllowed:
+		return codeMethodNotAllowed
+	case http.StatusUnprocessableEntity:
+		return codeUnprocessable
+	}
+
+	return datastores.CodeInternal
+}
+
+//	NotFound sends a JSON error for a path that doesn't have a route
+func NotFound(rw http.ResponseWriter, req *http.Request) {
+	sendErrorResponse(rw, fmt.Errorf("%s isn't a centralconfig route", req.URL.Path), http.StatusNotFound)
+}
+
+//	MethodNotAllowed sends a JSON error for a route that doesn't support the method
+func MethodNotAllowed(rw http.ResponseWriter, req *http.Request) {
+	sendErrorResponse(rw, fmt.Errorf("%s isn't supported on %s", req.Method, req.URL.Path), http.StatusMethodNotAllowed)
+}
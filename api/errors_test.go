@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	Sends a request to the handler and decodes the response envelope
+func getTestResponse(t *testing.T, handler http.HandlerFunc, method, body string) (*httptest.ResponseRecorder, datastores.ConfigResponse) {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	response := datastores.ConfigResponse{}
+	if err := json.NewDecoder(rw.Body).Decode(&response); err != nil {
+		t.Fatalf("The response isn't valid JSON: %s", err)
+	}
+
+	return rw, response
+}
+
+//	Getting an item that doesn't exist should be a 404 with a JSON error body
+func TestGetConfig_ItemDoesntExist_NotFound(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	Act
+	rw, response := getTestResponse(t, GetConfig, "POST", `{"application":"billing","name":"Missing"}`)
+
+	//	Assert
+	if rw.Code != http.StatusNotFound || response.Status != http.StatusNotFound || response.Code != datastores.CodeNotFound {
+		t.Errorf("Should have returned a not_found 404: %v / %+v", rw.Code, response)
+	}
+
+	if !strings.HasPrefix(rw.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("Error responses should be JSON: %s", rw.Header().Get("Content-Type"))
+	}
+}
+
+//	Datastore errors should use the status for their code, and unexpected errors shouldn't be sent
+func TestSendErrorResponse_Codes(t *testing.T) {
+	tests := []struct {
+		err     error
+		status  int
+		want    int
+		code    string
+		message string
+	}{
+		{fmt.Errorf("bad JSON"), http.StatusBadRequest, http.StatusBadRequest, datastores.CodeValidation, "bad JSON"},
+		{fmt.Errorf("dial tcp: connection refused by db01"), http.StatusInternalServerError, http.StatusInternalServerError, datastores.CodeInternal, "An unexpected error occurred"},
+		{&datastores.ConfigError{Code: datastores.CodeUnavailable, Message: "test"}, http.StatusInternalServerError, http.StatusServiceUnavailable, datastores.CodeUnavailable, "test"},
+		{&datastores.ConfigError{Code: datastores.CodeConflict, Message: "test"}, http.StatusInternalServerError, http.StatusConflict, datastores.CodeConflict, "test"},
+	}
+
+	for _, test := range tests {
+		//	Act
+		rw, response := getTestResponse(t, func(rw http.ResponseWriter, req *http.Request) {
+			sendErrorResponse(rw, test.err, test.status)
+		}, "GET", "")
+
+		//	Assert
+		if rw.Code != test.want || response.Status != test.want || response.Code != test.code || response.Message != test.message {
+			t.Errorf("%v should have been %d %s '%s': %d %+v", test.err, test.want, test.code, test.message, rw.Code, response)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	How often a comment is sent to keep idle event streams open
+var sseHeartbeat = 15 * time.Second
+
+//	EventsHandler streams config item events as Server-Sent Events.  It's the
+//	same stream as the /ws WebSocket, for clients that can't use WebSockets
+type EventsHandler struct {
+	H *Hub
+}
+
+func (eh EventsHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		sendErrorResponse(rw, fmt.Errorf("Streaming isn't supported"), http.StatusInternalServerError)
+		return
+	}
+
+	//	The application, machine and name patterns work just like a WebSocket subscription:
+	c := newConnection(eh.H)
+	query := req.URL.Query()
+	if query.Get("application") != "" || query.Get("machine") != "" || query.Get("name") != "" {
+		sub, err := newSubscription(datastores.WebSocketCommand{
+			Type:        wsSubscribe,
+			Application: query.Get("application"),
+			Machine:     query.Get("machine"),
+			Name:        query.Get("name")})
+		if err != nil {
+			sendErrorResponse(rw, fmt.Errorf("Invalid pattern: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		c.subscribed = true
+		c.subscriptions = []subscription{sub}
+	}
+
+	//	Browsers send the last event id when they reconnect:
+	lastEventID := req.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("since")
+	}
+
+	since := int64(0)
+	if lastEventID != "" {
+		var err error
+		since, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || since < 0 {
+			sendErrorResponse(rw, fmt.Errorf("Last-Event-ID should be a sequence number"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	//	Send events as they come in:
+	done := make(chan struct{})
+	defer func() {
+		c.h.removeConnection(c)
+		<-done
+	}()
+
+	stop := req.Context().Done()
+	go func() {
+		defer close(done)
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case message, ok := <-c.send:
+				if !ok {
+					return
+				}
+				if err := writeSSEEvent(rw, message); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(rw, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			case <-stop:
+				//	Drain anything the hub is still sending until the connection is removed:
+				for range c.send {
+				}
+				return
+			}
+			flusher.Flush()
+		}
+	}()
+
+	if lastEventID != "" {
+		c.h.resume(c, since, true)
+	} else {
+		c.h.addConnection(c)
+	}
+
+	select {
+	case <-stop:
+	case <-done:
+	}
+}
+
+//	Writes a WebSocket message as a Server-Sent Event.  The event name is the
+//	message type and the id is its sequence number (if it has one)
+func writeSSEEvent(rw http.ResponseWriter, message []byte) error {
+	event := datastores.WebSocketResponse{}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return err
+	}
+
+	frame := new(bytes.Buffer)
+	if event.Sequence > 0 {
+		fmt.Fprintf(frame, "id: %d\n", event.Sequence)
+	}
+	fmt.Fprintf(frame, "event: %s\n", event.Type)
+	fmt.Fprintf(frame, "data: %s\n\n", bytes.TrimSpace(message))
+
+	_, err := rw.Write(frame.Bytes())
+	return err
+}
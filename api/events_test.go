@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	A Server-Sent Event read from a stream
+type testSSEEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+//	Reads events (and comments) from an event stream
+func readSSEEvents(t *testing.T, resp *http.Response, count int) []testSSEEvent {
+	events := make(chan testSSEEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		event := testSSEEvent{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				events <- event
+				event = testSSEEvent{}
+			case strings.HasPrefix(line, ":"):
+				event.event = "comment"
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	retval := []testSSEEvent{}
+	timeout := time.After(5 * time.Second)
+	for len(retval) < count {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("Stream ended after %d events", len(retval))
+			}
+			retval = append(retval, event)
+		case <-timeout:
+			t.Fatalf("Timed out after %d events", len(retval))
+		}
+	}
+
+	return retval
+}
+
+//	Opens an event stream
+func getTestEventStream(t *testing.T, url, lastEventID string) *http.Response {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Should have returned an event stream but returned %s", resp.Header.Get("Content-Type"))
+	}
+
+	return resp
+}
+
+//	Events should be streamed with filtering and resume
+func TestEventsHandler_StreamsEvents(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+
+	hub := NewHub()
+	server := httptest.NewServer(EventsHandler{H: hub})
+	defer server.Close()
+
+	hub.publish(store, "Updated", datastores.ConfigItem{Application: "billing", Name: "Item1"})
+	waitForLog(hub, 1)
+
+	resumed := getTestEventStream(t, server.URL+"?application=billing", "0")
+	defer resumed.Body.Close()
+
+	filtered := getTestEventStream(t, server.URL+"?application=billing", "")
+	defer filtered.Body.Close()
+	waitForConnections(hub, 2)
+
+	//	Act
+	hub.publish(store, "Updated", datastores.ConfigItem{Application: "payroll", Name: "Item2"})
+	hub.publish(store, "Removed", datastores.ConfigItem{Application: "billing", Name: "Item3"})
+
+	//	Assert
+	events := readSSEEvents(t, filtered, 1)
+	if events[0].id != "3" || events[0].event != "Removed" || !strings.Contains(events[0].data, `"name":"Item3"`) {
+		t.Errorf("Filtered stream should only have gotten Item3: %+v", events)
+	}
+
+	events = readSSEEvents(t, resumed, 3)
+	if events[0].id != "1" || !strings.Contains(events[0].data, `"name":"Item1"`) {
+		t.Errorf("Resumed stream should have replayed Item1: %+v", events[0])
+	}
+	if events[1].event != "Resumed" || events[2].id != "3" {
+		t.Errorf("Resumed stream should have gotten Resumed and then Item3: %+v", events[1:])
+	}
+}
+
+//	Idle streams should get heartbeats
+func TestEventsHandler_Heartbeat(t *testing.T) {
+	//	Arrange
+	defer func(heartbeat time.Duration) { sseHeartbeat = heartbeat }(sseHeartbeat)
+	sseHeartbeat = 10 * time.Millisecond
+
+	server := httptest.NewServer(EventsHandler{H: NewHub()})
+	defer server.Close()
+
+	//	Act
+	resp := getTestEventStream(t, server.URL, "")
+	defer resp.Body.Close()
+
+	//	Assert
+	events := readSSEEvents(t, resp, 1)
+	if events[0].event != "comment" {
+		t.Errorf("Should have gotten a heartbeat comment: %+v", events[0])
+	}
+}
+
+//	Bad parameters should be rejected
+func TestEventsHandler_BadRequest(t *testing.T) {
+	//	Arrange
+	server := httptest.NewServer(EventsHandler{H: NewHub()})
+	defer server.Close()
+
+	for _, url := range []string{server.URL + "?name=db.[a", server.URL + "?since=abc"} {
+		//	Act
+		resp, err := http.Get(url)
+
+		//	Assert
+		if err != nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s should have returned 400: %v / %v", url, resp, err)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"log"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	The default time between expiry sweeps
+const defaultExpirySweepInterval = time.Minute
+
+//	SweepExpired removes the config items that have expired, and sends a
+//	Removed event for each one.  Returns the removed items
+func (h *Hub) SweepExpired() ([]datastores.ConfigItem, error) {
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	removed, err := ds.RemoveExpired(time.Now())
+	for _, item := range removed {
+		h.publish(ds, "Removed", item)
+	}
+
+	return removed, err
+}
+
+//	RunExpirySweeper sweeps expired config items at the interval (every minute
+//	if the interval isn't positive).  It doesn't return, so run it in a goroutine
+func (h *Hub) RunExpirySweeper(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultExpirySweepInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removed, err := h.SweepExpired()
+		if err != nil {
+			log.Printf("[WARN] Can't remove expired config items: %v\n", err)
+		}
+
+		if len(removed) > 0 {
+			log.Printf("[INFO] Removed %d expired config items\n", len(removed))
+		}
+	}
+}
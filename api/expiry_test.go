@@ -0,0 +1,55 @@
+package api
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	Sweeping should remove the expired items and send a Removed event for each
+func TestHub_SweepExpired_Removed(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "Maintenance", Value: "true", Expires: &past},
+		{Application: "billing", Name: "Banner", Value: "Sale", Expires: &future},
+		{Application: "billing", Name: "Timeout", Value: "30"},
+	} {
+		if _, err := ds.Set(item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	hub := NewHub()
+
+	//	Act
+	removed, err := hub.SweepExpired()
+
+	//	Assert
+	if err != nil || len(removed) != 1 || removed[0].Name != "Maintenance" {
+		t.Fatalf("Sweep should have removed Maintenance: %+v / %v", removed, err)
+	}
+
+	waitForLog(hub, 1)
+	hub.logMx.RLock()
+	defer hub.logMx.RUnlock()
+	if len(hub.log) != 1 || hub.log[0].eventType != "Removed" || hub.log[0].item.Name != "Maintenance" {
+		t.Errorf("Sweep should have sent a Removed event: %+v", hub.log)
+	}
+
+	items, _ := ds.GetAllForApplication("billing")
+	if len(items) != 2 {
+		t.Errorf("Sweep should have left 2 items: %+v", items)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/formats"
+)
+
+//	Exports the resolved config for an application (and optional machine)
+//	as a config file.  Query parameters:
+//
+//	app: the application name (required)
+//	machine: the machine name
+//	format: one of env, yaml, json, toml, ini, properties (defaults to env)
+//	prefix: only items with the prefix as their name, or under it (like db.primary)
+//	label: only items with the label (like pii or owner:payments -- repeat it for more labels)
+//	nest: if true, nest dotted names into trees / sections
+func ExportConfig(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	application := query.Get("app")
+	if application == "" {
+		sendErrorResponse(rw, fmt.Errorf("The app parameter is required"), http.StatusBadRequest)
+		return
+	}
+
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = string(formats.DotEnv)
+	}
+
+	format, err := formats.ParseFormat(formatName)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	opts := formats.Options{}
+	opts.Nest, err = getBoolParameter(req, "nest")
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Get the config items and resolve them for the machine:
+	resolved, err := getResolvedConfig(ds, application, query.Get("machine"), query.Get("prefix"), getLabelParameters(req))
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	//	Render the config file:
+	output, err := formats.Encode(resolved, format, opts)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	rw.Header().Set("Content-Type", format.ContentType())
+	rw.Write(output)
+}
@@ -0,0 +1,609 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//	The GraphQL schema for the config model
+const graphqlSchema = `
+schema {
+	query: Query
+	mutation: Mutation
+	subscription: Subscription
+}
+
+# A 64 bit integer (like event sequence numbers)
+scalar Long
+
+type Query {
+	# All applications (optionally only the ones whose names start with prefix)
+	applications(prefix: String): [Application!]!
+
+	# A single application
+	application(name: String!): Application
+
+	# Config items.  Without an application, items for every application are returned
+	items(application: String, machine: String, namePrefix: String): [ConfigItem!]!
+
+	# The effective config for an application (and optional machine)
+	resolve(application: String!, machine: String): [ConfigItem!]!
+
+	# Recent changes (from the server's event log), oldest first
+	events(application: String, machine: String, name: String, since: Long): [ConfigEvent!]!
+}
+
+type Mutation {
+	# Creates or updates the config item with the same application, machine and name.
+	# Without a description, labels or expiry time, an existing item keeps the ones it has
+	set(application: String!, machine: String, name: String!, value: String!, description: String, labels: [LabelInput!], expires: Time): ConfigItem!
+
+	# Removes a config item.  Returns false if there wasn't one
+	remove(application: String!, machine: String, name: String!): Boolean!
+}
+
+type Subscription {
+	# Config change events.  Patterns work like a WebSocket subscribe command.
+	# With since, the events missed since that sequence number are sent first
+	changes(application: String, machine: String, name: String, since: Long): ConfigEvent!
+}
+
+type Application {
+	name: String!
+
+	# The application's own config items (not the default * application's)
+	items(machine: String, namePrefix: String): [ConfigItem!]!
+
+	# The effective config for the application (and optional machine)
+	resolved(machine: String): [ConfigItem!]!
+
+	# Increases every time the application's config changes (see /config/watch)
+	index: Long!
+}
+
+type ConfigItem {
+	id: ID!
+	application: String!
+	machine: String!
+	name: String!
+	value: String!
+	updated: Time
+	description: String!
+	labels: [Label!]!
+
+	# When the item expires (null if it doesn't)
+	expires: Time
+}
+
+# A label on a config item (like owner: payments, or pii with a blank value)
+type Label {
+	name: String!
+	value: String!
+}
+
+input LabelInput {
+	name: String!
+	value: String
+}
+
+type ConfigEvent {
+	# Updated, Removed, Resumed, ResyncRequired or Dropped
+	type: String!
+	sequence: Long!
+	item: ConfigItem
+	message: String
+}
+
+scalar Time
+`
+
+//	Long is a 64 bit GraphQL integer
+type Long int64
+
+func (Long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+func (l *Long) UnmarshalGraphQL(input interface{}) error {
+	switch value := input.(type) {
+	case int32:
+		*l = Long(value)
+	case int64:
+		*l = Long(value)
+	case float64:
+		*l = Long(value)
+	case string:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*l = Long(parsed)
+	default:
+		return fmt.Errorf("Wrong type for Long: %T", input)
+	}
+
+	return nil
+}
+
+//	Resolves the root query, mutation and subscription fields
+type graphqlResolver struct {
+	h *Hub
+}
+
+//	Parses the GraphQL schema with resolvers that use the hub for events
+func newGraphQLSchema(h *Hub) *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &graphqlResolver{h: h})
+}
+
+func (r *graphqlResolver) Applications(args struct{ Prefix *string }) ([]*applicationResolver, error) {
+	applications, err := datastores.GetConfigDatastore().GetAllApplications()
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	retval := []*applicationResolver{}
+	for _, application := range applications {
+		if args.Prefix == nil || strings.HasPrefix(application, *args.Prefix) {
+			retval = append(retval, &applicationResolver{name: application})
+		}
+	}
+
+	return retval, nil
+}
+
+func (r *graphqlResolver) Application(args struct{ Name string }) (*applicationResolver, error) {
+	applications, err := datastores.GetConfigDatastore().GetAllApplications()
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	for _, application := range applications {
+		if application == args.Name {
+			return &applicationResolver{name: application}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *graphqlResolver) Items(args struct {
+	Application *string
+	Machine     *string
+	NamePrefix  *string
+}) ([]*configItemResolver, error) {
+	ds := datastores.GetConfigDatastore()
+
+	//	Get everything (or just the application's items):
+	var configItems []datastores.ConfigItem
+	var err error
+	if args.Application != nil {
+		configItems, err = ds.GetAllForApplication(*args.Application)
+	} else {
+		configItems, err = ds.GetAll()
+	}
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	return filterGraphQLItems(configItems, args.Application, args.Machine, args.NamePrefix), nil
+}
+
+func (r *graphqlResolver) Resolve(args struct {
+	Application string
+	Machine     *string
+}) ([]*configItemResolver, error) {
+	return resolveGraphQLItems(args.Application, args.Machine)
+}
+
+func (r *graphqlResolver) Events(args struct {
+	Application *string
+	Machine     *string
+	Name        *string
+	Since       *Long
+}) ([]*eventResolver, error) {
+	sub, err := getGraphQLSubscription(args.Application, args.Machine, args.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	since := int64(0)
+	if args.Since != nil {
+		since = int64(*args.Since)
+	}
+
+	retval := []*eventResolver{}
+	for _, event := range r.h.recentEvents(since) {
+		if sub.matches(event.item) {
+			retval = append(retval, &eventResolver{event: datastores.WebSocketResponse{Type: event.eventType, Data: event.item, Sequence: event.sequence}})
+		}
+	}
+
+	return retval, nil
+}
+
+func (r *graphqlResolver) Set(args struct {
+	Application string
+	Machine     *string
+	Name        string
+	Value       string
+	Description *string
+	Labels      *[]labelInput
+	Expires     *graphql.Time
+}) (*configItemResolver, error) {
+	//	Changes to protected applications have to be proposed with the HTTP API:
+	if err := checkUnprotected(args.Application); err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	request := datastores.ConfigItem{Application: args.Application, Name: args.Name, Value: args.Value}
+	if args.Machine != nil {
+		request.Machine = *args.Machine
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	See if we're updating an existing item:
+	existing, err := findExactConfigItem(ds, request)
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+	request.Id = existing.Id
+
+	//	Keep the description, labels and expiry time unless there are new ones:
+	request.Description = existing.Description
+	if args.Description != nil {
+		request.Description = *args.Description
+	}
+
+	request.Labels = existing.Labels
+	if args.Labels != nil {
+		request.Labels = make(map[string]string)
+		for _, label := range *args.Labels {
+			request.Labels[label.Name] = ""
+			if label.Value != nil {
+				request.Labels[label.Name] = *label.Value
+			}
+		}
+	}
+
+	request.Expires = existing.Expires
+	if args.Expires != nil {
+		request.Expires = &args.Expires.Time
+	}
+
+	//	Send the request to the datastore and get a response:
+	response, err := ds.Set(request)
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	r.h.publish(ds, "Updated", response)
+
+	return &configItemResolver{item: response}, nil
+}
+
+func (r *graphqlResolver) Remove(args struct {
+	Application string
+	Machine     *string
+	Name        string
+}) (bool, error) {
+	//	Changes to protected applications have to be proposed with the HTTP API:
+	if err := checkUnprotected(args.Application); err != nil {
+		return false, getGraphQLError(err)
+	}
+
+	request := datastores.ConfigItem{Application: args.Application, Name: args.Name}
+	if args.Machine != nil {
+		request.Machine = *args.Machine
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Make sure the item exists first:
+	existing, err := findExactConfigItem(ds, request)
+	if err != nil || existing.Name == "" {
+		return false, getGraphQLError(err)
+	}
+
+	//	Send the request to the datastore:
+	if err := ds.Remove(request); err != nil {
+		return false, getGraphQLError(err)
+	}
+
+	r.h.publish(ds, "Removed", request)
+
+	return true, nil
+}
+
+func (r *graphqlResolver) Changes(ctx context.Context, args struct {
+	Application *string
+	Machine     *string
+	Name        *string
+	Since       *Long
+}) (<-chan *eventResolver, error) {
+	sub, err := getGraphQLSubscription(args.Application, args.Machine, args.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if args.Since != nil && *args.Since < 0 {
+		return nil, fmt.Errorf("since can't be negative")
+	}
+
+	c := newConnection(r.h)
+	c.subscribed = true
+	c.subscriptions = []subscription{sub}
+
+	if args.Since != nil {
+		c.h.resume(c, int64(*args.Since), true)
+	} else {
+		c.h.addConnection(c)
+	}
+
+	events := make(chan *eventResolver)
+	go func() {
+		defer close(events)
+		defer c.h.removeConnection(c)
+
+		for {
+			select {
+			case message, ok := <-c.send:
+				//	If the hub removed us, the subscription is over:
+				if !ok {
+					return
+				}
+
+				event := datastores.WebSocketResponse{}
+				if err := json.Unmarshal(message, &event); err != nil {
+					return
+				}
+
+				select {
+				case events <- &eventResolver{event: event}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+//	Resolves an application's fields
+type applicationResolver struct {
+	name string
+}
+
+func (r *applicationResolver) Name() string {
+	return r.name
+}
+
+func (r *applicationResolver) Items(args struct {
+	Machine    *string
+	NamePrefix *string
+}) ([]*configItemResolver, error) {
+	configItems, err := datastores.GetConfigDatastore().GetAllForApplication(r.name)
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	return filterGraphQLItems(configItems, &r.name, args.Machine, args.NamePrefix), nil
+}
+
+func (r *applicationResolver) Resolved(args struct{ Machine *string }) ([]*configItemResolver, error) {
+	return resolveGraphQLItems(r.name, args.Machine)
+}
+
+func (r *applicationResolver) Index() (Long, error) {
+	index, err := getWatchIndex(datastores.GetConfigDatastore(), r.name)
+	return Long(index), getGraphQLError(err)
+}
+
+//	Resolves a config item's fields
+type configItemResolver struct {
+	item datastores.ConfigItem
+}
+
+func (r *configItemResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(r.item.Id, 10))
+}
+
+func (r *configItemResolver) Application() string {
+	return r.item.Application
+}
+
+func (r *configItemResolver) Machine() string {
+	return r.item.Machine
+}
+
+func (r *configItemResolver) Name() string {
+	return r.item.Name
+}
+
+func (r *configItemResolver) Value() string {
+	return r.item.Value
+}
+
+func (r *configItemResolver) Updated() *graphql.Time {
+	if r.item.LastUpdated.IsZero() {
+		return nil
+	}
+
+	return &graphql.Time{Time: r.item.LastUpdated}
+}
+
+func (r *configItemResolver) Expires() *graphql.Time {
+	if r.item.Expires == nil {
+		return nil
+	}
+
+	return &graphql.Time{Time: *r.item.Expires}
+}
+
+func (r *configItemResolver) Description() string {
+	return r.item.Description
+}
+
+func (r *configItemResolver) Labels() []*labelResolver {
+	retval := []*labelResolver{}
+	for name, value := range r.item.Labels {
+		retval = append(retval, &labelResolver{name: name, value: value})
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].name < retval[j].name
+	})
+
+	return retval
+}
+
+//	A label for the set mutation
+type labelInput struct {
+	Name  string
+	Value *string
+}
+
+//	Resolves a label's fields
+type labelResolver struct {
+	name  string
+	value string
+}
+
+func (r *labelResolver) Name() string {
+	return r.name
+}
+
+func (r *labelResolver) Value() string {
+	return r.value
+}
+
+//	Resolves a change event's fields
+type eventResolver struct {
+	event datastores.WebSocketResponse
+}
+
+func (r *eventResolver) Type() string {
+	return r.event.Type
+}
+
+func (r *eventResolver) Sequence() Long {
+	return Long(r.event.Sequence)
+}
+
+func (r *eventResolver) Item() *configItemResolver {
+	if r.event.Type != "Updated" && r.event.Type != "Removed" {
+		return nil
+	}
+
+	return &configItemResolver{item: r.event.Data}
+}
+
+func (r *eventResolver) Message() *string {
+	if r.event.Message == "" {
+		return nil
+	}
+
+	return &r.event.Message
+}
+
+//	Gets the config items that belong to the application (if there is one)
+//	with the machine (if there is one) and names starting with the prefix
+func filterGraphQLItems(configItems []datastores.ConfigItem, application, machine, namePrefix *string) []*configItemResolver {
+	retval := []*configItemResolver{}
+	for _, item := range configItems {
+		if application != nil && item.Application != *application {
+			continue
+		}
+
+		if machine != nil && item.Machine != *machine {
+			continue
+		}
+
+		if namePrefix != nil && !strings.HasPrefix(item.Name, *namePrefix) {
+			continue
+		}
+
+		retval = append(retval, &configItemResolver{item: item})
+	}
+
+	return retval
+}
+
+//	Gets the effective config for an application (and optional machine)
+func resolveGraphQLItems(application string, machine *string) ([]*configItemResolver, error) {
+	configItems, err := datastores.GetConfigDatastore().GetAllForApplication(application)
+	if err != nil {
+		return nil, getGraphQLError(err)
+	}
+
+	machineName := ""
+	if machine != nil {
+		machineName = *machine
+	}
+
+	retval := []*configItemResolver{}
+	for _, item := range datastores.ResolveConfigItems(configItems, application, machineName) {
+		retval = append(retval, &configItemResolver{item: item})
+	}
+
+	return retval, nil
+}
+
+//	A GraphQL error with its error code in the extensions
+type graphqlError struct {
+	code    string
+	message string
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+func (e *graphqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+//	Gets the GraphQL error for a datastore error (like the HTTP API, see
+//	getErrorDetails).  Unexpected errors are logged instead of being sent
+func getGraphQLError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	_, code, message := getErrorDetails(err, http.StatusInternalServerError)
+	return &graphqlError{code: code, message: message}
+}
+
+//	Creates a subscription from optional GraphQL patterns
+func getGraphQLSubscription(application, machine, name *string) (subscription, error) {
+	command := datastores.WebSocketCommand{Type: wsSubscribe}
+	if application != nil {
+		command.Application = *application
+	}
+	if machine != nil {
+		command.Machine = *machine
+	}
+	if name != nil {
+		command.Name = *name
+	}
+
+	sub, err := newSubscription(command)
+	if err != nil {
+		return sub, fmt.Errorf("Invalid pattern: %s", err)
+	}
+
+	return sub, nil
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+//	Runs a GraphQL query and decodes the data into target
+func runTestGraphQL(t *testing.T, url, query string, variables map[string]interface{}, target interface{}) {
+	body, _ := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	response := struct {
+		Errors []struct{ Message string }
+		Data   json.RawMessage
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Decoding the response failed: %s", err)
+	}
+
+	if len(response.Errors) > 0 {
+		t.Fatalf("Query failed: %+v", response.Errors)
+	}
+
+	if err := json.Unmarshal(response.Data, target); err != nil {
+		t.Fatalf("Decoding the data failed: %s", err)
+	}
+}
+
+//	A config item returned from GraphQL
+type testGraphQLItem struct {
+	ID          string
+	Application string
+	Machine     string
+	Name        string
+	Value       string
+}
+
+//	Queries and mutations should work over HTTP
+func TestGraphQLHandler_QueriesAndMutations(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	hub := NewHub()
+	server := httptest.NewServer(NewGraphQLHandler(hub))
+	defer server.Close()
+
+	mutation := `mutation($app: String!, $machine: String, $name: String!, $value: String!) {
+		set(application: $app, machine: $machine, name: $name, value: $value) { id name value }
+	}`
+
+	//	Act
+	for _, item := range []map[string]interface{}{
+		{"app": "*", "name": "db.timeout", "value": "30"},
+		{"app": "billing", "name": "db.timeout", "value": "10"},
+		{"app": "billing", "name": "db.host", "value": "db01"},
+		{"app": "billing", "machine": "WEB01", "name": "db.host", "value": "db02"},
+		{"app": "billing", "name": "ui.theme", "value": "dark"},
+		{"app": "payroll", "name": "db.host", "value": "db03"},
+	} {
+		set := struct{ Set testGraphQLItem }{}
+		runTestGraphQL(t, server.URL, mutation, item, &set)
+		if set.Set.ID == "" || set.Set.Value != item["value"] {
+			t.Errorf("set should have returned the item: %+v", set)
+		}
+	}
+
+	removed := struct{ Remove bool }{}
+	runTestGraphQL(t, server.URL, `mutation { remove(application: "billing", name: "ui.theme") }`, nil, &removed)
+
+	missing := struct{ Remove bool }{}
+	runTestGraphQL(t, server.URL, `mutation { remove(application: "billing", name: "ui.theme") }`, nil, &missing)
+
+	waitForLog(hub, 7)
+
+	result := struct {
+		Applications []struct{ Name string }
+		Application  struct {
+			Items    []testGraphQLItem
+			Resolved []testGraphQLItem
+			Index    int64
+		}
+		Items   []testGraphQLItem
+		Resolve []testGraphQLItem
+		Events  []struct {
+			Type     string
+			Sequence int64
+			Item     testGraphQLItem
+		}
+	}{}
+	runTestGraphQL(t, server.URL, `{
+		applications(prefix: "b") { name }
+		application(name: "billing") {
+			items(namePrefix: "db.") { name machine value }
+			resolved(machine: "WEB01") { name value }
+			index
+		}
+		items(machine: "WEB01") { application name value }
+		resolve(application: "billing") { name value }
+		events(application: "billing", since: 5) { type sequence item { name } }
+	}`, nil, &result)
+
+	//	Assert
+	if !removed.Remove || missing.Remove {
+		t.Errorf("remove should have returned true and then false: %v / %v", removed.Remove, missing.Remove)
+	}
+
+	if len(result.Applications) != 1 || result.Applications[0].Name != "billing" {
+		t.Errorf("applications should have returned billing: %+v", result.Applications)
+	}
+
+	if len(result.Application.Items) != 3 || result.Application.Index == 0 {
+		t.Errorf("application should have returned 3 db items and an index: %+v", result.Application)
+	}
+
+	resolved := map[string]string{}
+	for _, item := range result.Application.Resolved {
+		resolved[item.Name] = item.Value
+	}
+	if len(resolved) != 2 || resolved["db.host"] != "db02" || resolved["db.timeout"] != "10" {
+		t.Errorf("resolved should have returned the WEB01 config: %+v", resolved)
+	}
+
+	if len(result.Items) != 1 || result.Items[0].Value != "db02" {
+		t.Errorf("items should have returned the WEB01 item: %+v", result.Items)
+	}
+
+	if len(result.Resolve) != 2 {
+		t.Errorf("resolve should have returned 2 items: %+v", result.Resolve)
+	}
+
+	if len(result.Events) != 1 || result.Events[0].Type != "Removed" || result.Events[0].Sequence != 7 || result.Events[0].Item.Name != "ui.theme" {
+		t.Errorf("events should have returned the removed ui.theme: %+v", result.Events)
+	}
+}
+
+//	Reads the next graphql-transport-ws message
+func readTestGraphQLWSMessage(conn *websocket.Conn) (graphqlWSMessage, error) {
+	message := graphqlWSMessage{}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	err := conn.ReadJSON(&message)
+	return message, err
+}
+
+//	Subscriptions should stream matching changes over a WebSocket
+func TestGraphQLHandler_Subscription(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	hub := NewHub()
+	server := httptest.NewServer(NewGraphQLHandler(hub))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphqlWSProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(graphqlWSMessage{Type: "connection_init"})
+	if ack, err := readTestGraphQLWSMessage(conn); err != nil || ack.Type != "connection_ack" {
+		t.Fatalf("Should have gotten connection_ack: %+v / %v", ack, err)
+	}
+
+	payload, _ := json.Marshal(graphqlRequest{Query: `subscription { changes(application: "billing") { type sequence item { name value } } }`})
+	conn.WriteJSON(graphqlWSMessage{ID: "1", Type: "subscribe", Payload: payload})
+	waitForConnections(hub, 1)
+
+	//	Act
+	set := struct{ Set testGraphQLItem }{}
+	runTestGraphQL(t, server.URL, `mutation { set(application: "payroll", name: "Item1", value: "Value1") { id } }`, nil, &set)
+	runTestGraphQL(t, server.URL, `mutation { set(application: "billing", name: "Item2", value: "Value2") { id } }`, nil, &set)
+
+	//	Assert
+	next, err := readTestGraphQLWSMessage(conn)
+	if err != nil || next.Type != "next" || next.ID != "1" {
+		t.Fatalf("Should have gotten next: %+v / %v", next, err)
+	}
+
+	event := struct {
+		Data struct {
+			Changes struct {
+				Type     string
+				Sequence int64
+				Item     testGraphQLItem
+			}
+		}
+	}{}
+	json.Unmarshal(next.Payload, &event)
+	if event.Data.Changes.Type != "Updated" || event.Data.Changes.Sequence != 2 || event.Data.Changes.Item.Name != "Item2" {
+		t.Errorf("Should have only gotten the billing change: %s", next.Payload)
+	}
+
+	//	Completing the subscription should remove it from the hub:
+	conn.WriteJSON(graphqlWSMessage{ID: "1", Type: "complete"})
+	waitForConnections(hub, 0)
+
+	hub.connectionsMx.RLock()
+	connections := len(hub.connections)
+	hub.connectionsMx.RUnlock()
+	if connections != 0 {
+		t.Errorf("The subscription should have been removed from the hub")
+	}
+}
+
+//	Queries without POST (or a WebSocket) should be rejected
+func TestGraphQLHandler_Get_NotAllowed(t *testing.T) {
+	//	Arrange
+	server := httptest.NewServer(NewGraphQLHandler(NewHub()))
+	defer server.Close()
+
+	//	Act
+	resp, err := http.Get(server.URL + "?query={applications{name}}")
+
+	//	Assert
+	if err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET should have returned 405: %v / %v", resp, err)
+	}
+}
+
+//	Errors should have the error code, and unexpected errors shouldn't be sent
+//	to the client
+func TestGraphQLHandler_Errors_Coded(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	server := httptest.NewServer(NewGraphQLHandler(NewHub()))
+	defer server.Close()
+
+	body, _ := json.Marshal(graphqlRequest{Query: `mutation { set(application: "prod-billing", name: "Timeout", value: "60") { id } }`})
+
+	//	Act
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	response := struct {
+		Errors []struct {
+			Message    string
+			Extensions map[string]interface{}
+		}
+	}{}
+	json.NewDecoder(resp.Body).Decode(&response)
+
+	internal := getGraphQLError(errors.New("dial tcp 10.0.0.5:3306: connect: connection refused"))
+
+	//	Assert
+	if len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != datastores.CodeForbidden {
+		t.Errorf("Should have been forbidden: %+v", response.Errors)
+	}
+
+	if internal.Error() != "An unexpected error occurred" {
+		t.Errorf("Shouldn't have sent the unexpected error: %s", internal)
+	}
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/gorilla/websocket"
+)
+
+//	The WebSocket subprotocol used for GraphQL subscriptions.  See
+//	https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const graphqlWSProtocol = "graphql-transport-ws"
+
+//	How long clients have to send connection_init, and the largest message they can send
+const (
+	graphqlInitTimeout    = 10 * time.Second
+	maxGraphQLMessageSize = 64 * 1024
+)
+
+var graphqlUpgrader = &websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024, Subprotocols: []string{graphqlWSProtocol}}
+
+//	A GraphQL request
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+//	A graphql-transport-ws message
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+//	GraphQLHandler serves GraphQL queries and mutations (with HTTP POST) and
+//	subscriptions (with WebSockets, using the graphql-transport-ws protocol)
+type GraphQLHandler struct {
+	h      *Hub
+	schema *graphql.Schema
+}
+
+//	Creates a GraphQL handler that uses the hub for change events
+func NewGraphQLHandler(h *Hub) *GraphQLHandler {
+	return &GraphQLHandler{h: h, schema: newGraphQLSchema(h)}
+}
+
+func (gh *GraphQLHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if websocket.IsWebSocketUpgrade(req) {
+		gh.serveWebSocket(rw, req)
+		return
+	}
+
+	if req.Method != "POST" {
+		sendErrorResponse(rw, fmt.Errorf("Use POST for queries and mutations (or a WebSocket for subscriptions)"), http.StatusMethodNotAllowed)
+		return
+	}
+
+	//	req.Body is a ReadCloser -- we need to remember to close it:
+	defer req.Body.Close()
+
+	//	Decode the request:
+	request := graphqlRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	response := gh.schema.Exec(req.Context(), request.Query, request.OperationName, request.Variables)
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(rw).Encode(response)
+}
+
+//	Runs GraphQL operations (usually subscriptions) over a WebSocket
+func (gh *GraphQLHandler) serveWebSocket(rw http.ResponseWriter, req *http.Request) {
+	wsConn, err := graphqlUpgrader.Upgrade(rw, req, nil)
+	if err != nil {
+		return
+	}
+	defer wsConn.Close()
+	wsConn.SetReadLimit(maxGraphQLMessageSize)
+
+	//	Writes can come from any subscription, so they take turns:
+	var writeMx sync.Mutex
+	write := func(messageType int, data []byte) error {
+		writeMx.Lock()
+		defer writeMx.Unlock()
+		wsConn.SetWriteDeadline(time.Now().Add(writeWait))
+		return wsConn.WriteMessage(messageType, data)
+	}
+	send := func(message graphqlWSMessage) error {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		return write(websocket.TextMessage, data)
+	}
+	closeWith := func(code int, reason string) {
+		write(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	}
+
+	if wsConn.Subprotocol() != graphqlWSProtocol {
+		closeWith(4406, "Subprotocol not acceptable")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	//	Keep the connection alive, like the /ws connections:
+	wsConn.SetReadDeadline(time.Now().Add(graphqlInitTimeout))
+	wsConn.SetPongHandler(func(string) error {
+		return wsConn.SetReadDeadline(time.Now().Add(gh.h.pongWait))
+	})
+	go func() {
+		ping := time.NewTicker(gh.h.pingPeriod)
+		defer ping.Stop()
+		for {
+			select {
+			case <-ping.C:
+				if write(websocket.PingMessage, nil) != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	//	The running operations (by id):
+	var operationsMx sync.Mutex
+	operations := make(map[string]context.CancelFunc)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	initialized := false
+	for {
+		_, data, err := wsConn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		message := graphqlWSMessage{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			closeWith(4400, "Invalid message")
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if initialized {
+				closeWith(4429, "Too many initialisation requests")
+				return
+			}
+			initialized = true
+			wsConn.SetReadDeadline(time.Now().Add(gh.h.pongWait))
+			send(graphqlWSMessage{Type: "connection_ack"})
+
+		case "ping":
+			send(graphqlWSMessage{Type: "pong"})
+
+		case "pong":
+
+		case "subscribe":
+			if !initialized {
+				closeWith(4401, "Unauthorized")
+				return
+			}
+
+			request := graphqlRequest{}
+			if message.ID == "" || json.Unmarshal(message.Payload, &request) != nil {
+				closeWith(4400, "Invalid subscribe message")
+				return
+			}
+
+			operationsMx.Lock()
+			_, exists := operations[message.ID]
+			count := len(operations)
+			operationsMx.Unlock()
+
+			if exists {
+				closeWith(4409, fmt.Sprintf("Subscriber for %s already exists", message.ID))
+				return
+			}
+
+			if count >= maxSubscriptions {
+				payload, _ := json.Marshal([]map[string]string{{"message": fmt.Sprintf("Too many subscriptions (the limit is %d)", maxSubscriptions)}})
+				send(graphqlWSMessage{ID: message.ID, Type: "error", Payload: payload})
+				continue
+			}
+
+			operationCtx, operationCancel := context.WithCancel(ctx)
+			operationsMx.Lock()
+			operations[message.ID] = operationCancel
+			operationsMx.Unlock()
+
+			responses, err := gh.schema.Subscribe(operationCtx, request.Query, request.OperationName, request.Variables)
+			if err != nil {
+				operationCancel()
+				closeWith(4500, err.Error())
+				return
+			}
+
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+
+				failed := false
+				for response := range responses {
+					result, ok := response.(*graphql.Response)
+					if !ok {
+						continue
+					}
+
+					//	Errors without data mean the operation didn't run:
+					if result.Data == nil && len(result.Errors) > 0 {
+						payload, _ := json.Marshal(result.Errors)
+						send(graphqlWSMessage{ID: id, Type: "error", Payload: payload})
+						failed = true
+						continue
+					}
+
+					payload, _ := json.Marshal(result)
+					send(graphqlWSMessage{ID: id, Type: "next", Payload: payload})
+				}
+
+				//	Let the client know we're done (unless it asked us to stop):
+				operationsMx.Lock()
+				_, running := operations[id]
+				delete(operations, id)
+				operationsMx.Unlock()
+
+				if running && !failed && operationCtx.Err() == nil {
+					send(graphqlWSMessage{ID: id, Type: "complete"})
+				}
+				operationCancel()
+			}(message.ID)
+
+		case "complete":
+			operationsMx.Lock()
+			if operationCancel, ok := operations[message.ID]; ok {
+				delete(operations, message.ID)
+				operationCancel()
+			}
+			operationsMx.Unlock()
+
+		default:
+			closeWith(4400, fmt.Sprintf("Unknown message type '%s'", message.Type))
+			return
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//	GrpcServer serves the centralconfig gRPC service (see rpc/centralconfig.proto).
+//	Changes are broadcast through the hub, just like changes made with the HTTP API
+type GrpcServer struct {
+	rpc.UnimplementedConfigServiceServer
+
+	H *Hub
+}
+
+//	Gets a single config item
+func (gs GrpcServer) Get(ctx context.Context, req *rpc.GetRequest) (*rpc.ConfigItem, error) {
+	if req.Application == "" || req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "An application and name are required")
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	response, err := ds.Get(datastores.ConfigItem{Application: req.Application, Machine: req.Machine, Name: req.Name})
+	if err != nil {
+		return nil, getRPCError(err)
+	}
+
+	return getRPCConfigItem(response), nil
+}
+
+//	Gets the effective config for an application (and optional machine)
+func (gs GrpcServer) Resolve(ctx context.Context, req *rpc.ResolveRequest) (*rpc.ConfigItems, error) {
+	if req.Application == "" {
+		return nil, status.Error(codes.InvalidArgument, "An application is required")
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	configItems, err := ds.GetAllForApplication(req.Application)
+	if err != nil {
+		return nil, getRPCError(err)
+	}
+
+	retval := &rpc.ConfigItems{Items: []*rpc.ConfigItem{}}
+	for _, item := range datastores.ResolveConfigItems(configItems, req.Application, req.Machine) {
+		retval.Items = append(retval.Items, getRPCConfigItem(item))
+	}
+
+	return retval, nil
+}
+
+//	Creates or updates a config item
+func (gs GrpcServer) Set(ctx context.Context, req *rpc.SetRequest) (*rpc.ConfigItem, error) {
+	if req.Application == "" || req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "An application and name are required")
+	}
+
+	//	Changes to protected applications have to be proposed with the HTTP API:
+	if err := checkUnprotected(req.Application); err != nil {
+		return nil, getRPCError(err)
+	}
+
+	request := datastores.ConfigItem{Application: req.Application, Machine: req.Machine, Name: req.Name, Value: req.Value}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	See if we're updating an existing item:
+	existing, err := findExactConfigItem(ds, request)
+	if err != nil {
+		return nil, getRPCError(err)
+	}
+	request.Id = existing.Id
+
+	//	Only the value can be set with gRPC, so keep the rest:
+	request.Description = existing.Description
+	request.Labels = existing.Labels
+	request.Expires = existing.Expires
+
+	//	Send the request to the datastore and get a response:
+	response, err := ds.Set(request)
+	if err != nil {
+		return nil, getRPCError(err)
+	}
+
+	gs.H.publish(ds, "Updated", response)
+
+	return getRPCConfigItem(response), nil
+}
+
+//	Removes a config item
+func (gs GrpcServer) Remove(ctx context.Context, req *rpc.RemoveRequest) (*rpc.RemoveResponse, error) {
+	if req.Application == "" || req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "An application and name are required")
+	}
+
+	//	Changes to protected applications have to be proposed with the HTTP API:
+	if err := checkUnprotected(req.Application); err != nil {
+		return nil, getRPCError(err)
+	}
+
+	request := datastores.ConfigItem{Application: req.Application, Machine: req.Machine, Name: req.Name}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Make sure the item exists first:
+	existing, err := findExactConfigItem(ds, request)
+	if err != nil {
+		return nil, getRPCError(err)
+	}
+
+	if existing.Name == "" {
+		return nil, status.Error(codes.NotFound, "No config item found with that application and name")
+	}
+
+	//	Send the request to the datastore:
+	if err := ds.Remove(request); err != nil {
+		return nil, getRPCError(err)
+	}
+
+	gs.H.publish(ds, "Removed", request)
+
+	return &rpc.RemoveResponse{}, nil
+}
+
+//	Lists all applications
+func (gs GrpcServer) ListApplications(ctx context.Context, req *rpc.ListApplicationsRequest) (*rpc.ListApplicationsResponse, error) {
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	applications, err := ds.GetAllApplications()
+	if err != nil {
+		return nil, getRPCError(err)
+	}
+
+	return &rpc.ListApplicationsResponse{Applications: applications}, nil
+}
+
+//	Streams config change events
+func (gs GrpcServer) Watch(req *rpc.WatchRequest, stream rpc.ConfigService_WatchServer) error {
+	//	The patterns work just like a WebSocket subscription:
+	c := newConnection(gs.H)
+	if req.Application != "" || req.Machine != "" || req.Name != "" {
+		sub, err := newSubscription(datastores.WebSocketCommand{
+			Type:        wsSubscribe,
+			Application: req.Application,
+			Machine:     req.Machine,
+			Name:        req.Name})
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid pattern: %s", err)
+		}
+
+		c.subscribed = true
+		c.subscriptions = []subscription{sub}
+	}
+
+	if req.Since != nil && *req.Since < 0 {
+		return status.Error(codes.InvalidArgument, "since can't be negative")
+	}
+
+	defer c.h.removeConnection(c)
+	if req.Since != nil {
+		c.h.resume(c, *req.Since, true)
+	} else {
+		c.h.addConnection(c)
+	}
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			if !ok {
+				return status.Error(codes.Unavailable, "The stream couldn't keep up with events.  Resume from the last sequence number")
+			}
+
+			event := datastores.WebSocketResponse{}
+			if err := json.Unmarshal(message, &event); err != nil {
+				return getRPCError(err)
+			}
+
+			if err := stream.Send(getRPCEvent(event)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+//	Converts a config item to its gRPC message
+func getRPCConfigItem(item datastores.ConfigItem) *rpc.ConfigItem {
+	retval := &rpc.ConfigItem{
+		Id:          item.Id,
+		Application: item.Application,
+		Machine:     item.Machine,
+		Name:        item.Name,
+		Value:       item.Value,
+		Description: item.Description,
+		Labels:      item.Labels}
+
+	if !item.LastUpdated.IsZero() {
+		retval.Updated = timestamppb.New(item.LastUpdated)
+	}
+
+	if item.Expires != nil {
+		retval.Expires = timestamppb.New(*item.Expires)
+	}
+
+	return retval
+}
+
+//	Converts a WebSocket event to its gRPC message
+func getRPCEvent(event datastores.WebSocketResponse) *rpc.Event {
+	retval := &rpc.Event{
+		Type:     event.Type,
+		Sequence: event.Sequence,
+		Message:  event.Message}
+
+	if event.Type == "Updated" || event.Type == "Removed" {
+		retval.Item = getRPCConfigItem(event.Data)
+	}
+
+	return retval
+}
+
+//	Converts a datastore error to a gRPC status error (with the code for its error code)
+func getRPCError(err error) error {
+	_, code, message := getErrorDetails(err, http.StatusInternalServerError)
+
+	switch code {
+	case datastores.CodeNotFound:
+		return status.Error(codes.NotFound, message)
+	case datastores.CodeConflict:
+		return status.Error(codes.AlreadyExists, message)
+	case datastores.CodeValidation:
+		return status.Error(codes.InvalidArgument, message)
+	case datastores.CodeForbidden:
+		return status.Error(codes.PermissionDenied, message)
+	case datastores.CodeUnavailable:
+		return status.Error(codes.Unavailable, message)
+	}
+
+	return status.Error(codes.Internal, message)
+}
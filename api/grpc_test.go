@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/rpc"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+//	Starts a gRPC server with an in-process listener and returns a client for it
+func getTestGrpcClient(t *testing.T, hub *Hub) (rpc.ConfigServiceClient, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	rpc.RegisterConfigServiceServer(server, GrpcServer{H: hub})
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+
+	return rpc.NewConfigServiceClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+//	Items should be set, fetched, resolved and removed
+func TestGrpcServer_SetGetResolveRemove(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	client, stop := getTestGrpcClient(t, NewHub())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	//	Act
+	client.Set(ctx, &rpc.SetRequest{Application: "*", Name: "Timeout", Value: "30"})
+	client.Set(ctx, &rpc.SetRequest{Application: "billing", Name: "Timeout", Value: "10"})
+	first, _ := client.Set(ctx, &rpc.SetRequest{Application: "billing", Name: "Host", Value: "db01"})
+	updated, setErr := client.Set(ctx, &rpc.SetRequest{Application: "billing", Name: "Host", Value: "db02"})
+
+	item, getErr := client.Get(ctx, &rpc.GetRequest{Application: "billing", Name: "Host"})
+	resolved, resolveErr := client.Resolve(ctx, &rpc.ResolveRequest{Application: "billing"})
+	applications, listErr := client.ListApplications(ctx, &rpc.ListApplicationsRequest{})
+
+	_, removeErr := client.Remove(ctx, &rpc.RemoveRequest{Application: "billing", Name: "Host"})
+	_, missingErr := client.Get(ctx, &rpc.GetRequest{Application: "billing", Name: "Host"})
+	_, removeMissingErr := client.Remove(ctx, &rpc.RemoveRequest{Application: "billing", Name: "Host"})
+
+	//	Assert
+	if setErr != nil || updated.Id != first.Id || updated.Updated == nil {
+		t.Errorf("Set should have updated the existing item: %v / %v / %v", first, updated, setErr)
+	}
+
+	if getErr != nil || item.Value != "db02" {
+		t.Errorf("Get should have returned the updated item: %v / %v", item, getErr)
+	}
+
+	values := map[string]string{}
+	for _, resolvedItem := range resolved.GetItems() {
+		values[resolvedItem.Name] = resolvedItem.Value
+	}
+	if resolveErr != nil || len(values) != 2 || values["Timeout"] != "10" || values["Host"] != "db02" {
+		t.Errorf("Resolve should have returned the effective config: %v / %v", values, resolveErr)
+	}
+
+	if listErr != nil || len(applications.Applications) != 2 {
+		t.Errorf("ListApplications should have returned 2 applications: %v / %v", applications, listErr)
+	}
+
+	if removeErr != nil {
+		t.Errorf("Remove failed: %v", removeErr)
+	}
+
+	if status.Code(missingErr) != codes.NotFound || status.Code(removeMissingErr) != codes.NotFound {
+		t.Errorf("Removed items should be NotFound: %v / %v", missingErr, removeMissingErr)
+	}
+}
+
+//	Items should have their description, labels and expiry (and Set should
+//	keep them)
+func TestGrpcServer_Get_ItemDetails(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	ds := datastores.GetConfigDatastore()
+	ds.Set(datastores.ConfigItem{Application: "billing", Name: "Host", Value: "db01", Description: "The database host", Labels: map[string]string{"team": "payments"}, Expires: &expires})
+
+	client, stop := getTestGrpcClient(t, NewHub())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	//	Act
+	item, getErr := client.Get(ctx, &rpc.GetRequest{Application: "billing", Name: "Host"})
+	updated, setErr := client.Set(ctx, &rpc.SetRequest{Application: "billing", Name: "Host", Value: "db02"})
+
+	//	Assert
+	for _, rpcItem := range []*rpc.ConfigItem{item, updated} {
+		if rpcItem.GetDescription() != "The database host" || rpcItem.GetLabels()["team"] != "payments" || !rpcItem.GetExpires().AsTime().Equal(expires) {
+			t.Errorf("Should have had the item details: %v", rpcItem)
+		}
+	}
+
+	if getErr != nil || setErr != nil {
+		t.Errorf("Get and Set failed: %v / %v", getErr, setErr)
+	}
+}
+
+//	Requests without an application or name should be rejected
+func TestGrpcServer_InvalidArgument(t *testing.T) {
+	//	Arrange
+	client, stop := getTestGrpcClient(t, NewHub())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	//	Act
+	_, getErr := client.Get(ctx, &rpc.GetRequest{Application: "billing"})
+	_, setErr := client.Set(ctx, &rpc.SetRequest{Name: "Host"})
+	_, resolveErr := client.Resolve(ctx, &rpc.ResolveRequest{})
+
+	watch, _ := client.Watch(ctx, &rpc.WatchRequest{Name: "db.[a"})
+	_, watchErr := watch.Recv()
+
+	//	Assert
+	for _, err := range []error{getErr, setErr, resolveErr, watchErr} {
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Should have been InvalidArgument: %v", err)
+		}
+	}
+}
+
+//	Watch should stream matching events (and resume)
+func TestGrpcServer_Watch(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	hub := NewHub()
+	client, stop := getTestGrpcClient(t, hub)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client.Set(ctx, &rpc.SetRequest{Application: "billing", Name: "Item1", Value: "Value1"})
+	waitForLog(hub, 1)
+
+	since := int64(0)
+	resumed, err := client.Watch(ctx, &rpc.WatchRequest{Application: "billing", Since: &since})
+	if err != nil {
+		t.Fatalf("Watch failed: %s", err)
+	}
+
+	filtered, err := client.Watch(ctx, &rpc.WatchRequest{Application: "billing"})
+	if err != nil {
+		t.Fatalf("Watch failed: %s", err)
+	}
+	waitForConnections(hub, 2)
+
+	//	Act
+	client.Set(ctx, &rpc.SetRequest{Application: "payroll", Name: "Item2", Value: "Value2"})
+	client.Remove(ctx, &rpc.RemoveRequest{Application: "billing", Name: "Item1"})
+
+	//	Assert
+	event, err := filtered.Recv()
+	if err != nil || event.Type != "Removed" || event.Sequence != 3 || event.Item.GetName() != "Item1" {
+		t.Errorf("Filtered watch should only have gotten the removed Item1: %v / %v", event, err)
+	}
+
+	expected := []struct {
+		eventType string
+		sequence  int64
+	}{
+		{"Updated", 1},
+		{"Resumed", 1},
+		{"Removed", 3},
+	}
+	for _, test := range expected {
+		event, err := resumed.Recv()
+		if err != nil || event.Type != test.eventType || event.Sequence != test.sequence {
+			t.Errorf("Resumed watch should have gotten %s %d: %v / %v", test.eventType, test.sequence, event, err)
+		}
+	}
+}
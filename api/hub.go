@@ -1,8 +1,26 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	The number of events kept for clients that resume by default
+const defaultLogSize = 1000
+
+//	What happens to connections that can't keep up with events
+const (
+	//	Slow connections are disconnected (they can reconnect and resume)
+	DisconnectSlowClients = "disconnect"
+
+	//	Events are dropped for slow connections (they're told how many they missed)
+	DropSlowClients = "drop"
 )
 
 type Hub struct {
@@ -12,37 +30,298 @@ type Hub struct {
 	// Registered connections.
 	connections map[*connection]struct{}
 
+	// What happens to connections that can't keep up
+	slowClientPolicy string
+
+	// How often WebSocket clients are pinged, and how long they have to answer
+	pingPeriod time.Duration
+	pongWait   time.Duration
+
 	// Inbound messages from the connections.
 	Broadcast chan []byte
 
-	logMx sync.RWMutex
-	log   [][]byte
+	// Config item events waiting to be broadcast (publishing never blocks),
+	// and a signal that there are some
+	pendingMx sync.Mutex
+	pending   []pendingEvent
+	published chan struct{}
+
+	// Recent events (oldest first) that clients can resume from
+	logMx   sync.RWMutex
+	log     []loggedEvent
+	logSize int
+
+	// Closed (and replaced) whenever config changes, to wake up watches
+	changedMx sync.Mutex
+	changed   chan struct{}
+}
+
+//	A config item event waiting to be broadcast
+type pendingEvent struct {
+	sequence int64
+	message  []byte
+}
+
+//	An event in the replay log
+type loggedEvent struct {
+	sequence  int64
+	eventType string
+	item      datastores.ConfigItem
+	message   []byte
 }
 
 func NewHub() *Hub {
 	h := &Hub{
-		connectionsMx: sync.RWMutex{},
-		Broadcast:     make(chan []byte),
-		connections:   make(map[*connection]struct{}),
-	}
-
-	go func() {
-		for {
-			msg := <-h.Broadcast
-			h.connectionsMx.RLock()
-			for c := range h.connections {
-				select {
-				case c.send <- msg:
-				// stop trying to send to this connection after trying for 1 second.
-				// if we have to stop, it means that a reader died so remove the connection also.
-				case <-time.After(1 * time.Second):
-					h.removeConnection(c)
-				}
+		connectionsMx:    sync.RWMutex{},
+		Broadcast:        make(chan []byte, sendQueueSize),
+		connections:      make(map[*connection]struct{}),
+		slowClientPolicy: DisconnectSlowClients,
+		pingPeriod:       defaultPingPeriod,
+		pongWait:         defaultPongWait,
+		logSize:          defaultLogSize,
+		changed:          make(chan struct{}),
+		published:        make(chan struct{}, 1),
+	}
+
+	go h.run()
+	return h
+}
+
+//	Broadcasts messages and published events.  Events published at the same
+//	time are sent in sequence order
+func (h *Hub) run() {
+	for {
+		select {
+		case msg := <-h.Broadcast:
+			h.broadcast(msg)
+
+		case <-h.published:
+			h.pendingMx.Lock()
+			events := h.pending
+			h.pending = nil
+			h.pendingMx.Unlock()
+
+			sort.SliceStable(events, func(i, j int) bool {
+				return events[i].sequence < events[j].sequence
+			})
+
+			for _, event := range events {
+				h.broadcast(event.message)
 			}
-			h.connectionsMx.RUnlock()
 		}
-	}()
-	return h
+	}
+}
+
+//	Logs a message (if it's a config item event) and sends it to the
+//	connections that want it
+func (h *Hub) broadcast(msg []byte) {
+	//	Config item events only go to connections that want them:
+	event := datastores.WebSocketResponse{}
+	isEvent := json.Unmarshal(msg, &event) == nil && (event.Type == "Updated" || event.Type == "Removed")
+
+	//	Log the event and send it while holding the log lock, so a
+	//	resuming connection gets each event exactly once:
+	h.logMx.Lock()
+	defer h.logMx.Unlock()
+
+	if isEvent && event.Sequence > 0 {
+		h.log = insertLoggedEvent(h.log, loggedEvent{sequence: event.Sequence, eventType: event.Type, item: event.Data, message: msg})
+		if len(h.log) > h.logSize {
+			h.log = append([]loggedEvent{}, h.log[len(h.log)-h.logSize:]...)
+		}
+	}
+
+	//	Sending never blocks, so one slow connection can't hold up the others:
+	laggards := []*connection{}
+	h.connectionsMx.RLock()
+	for c := range h.connections {
+		if isEvent && !c.wants(event.Data) {
+			continue
+		}
+
+		if !c.queue(msg) && h.slowClientPolicy == DisconnectSlowClients {
+			laggards = append(laggards, c)
+		}
+	}
+	h.connectionsMx.RUnlock()
+
+	//	Remove the slow connections once we're done with the list:
+	for _, c := range laggards {
+		log.Printf("[WARN] Disconnecting a client that isn't keeping up with events\n")
+		h.removeConnection(c)
+	}
+}
+
+//	Adds an event to the log in sequence order.  Events are almost always
+//	newer than the last one, but concurrent changes can be published out of order
+func insertLoggedEvent(events []loggedEvent, event loggedEvent) []loggedEvent {
+	i := len(events)
+	for i > 0 && events[i-1].sequence > event.sequence {
+		i--
+	}
+
+	events = append(events, loggedEvent{})
+	copy(events[i+1:], events[i:])
+	events[i] = event
+	return events
+}
+
+//	SetLogSize sets the number of recent events kept for clients that resume
+func (h *Hub) SetLogSize(size int) {
+	h.logMx.Lock()
+	defer h.logMx.Unlock()
+
+	if size < 1 {
+		size = defaultLogSize
+	}
+	h.logSize = size
+}
+
+//	SetSlowClientPolicy sets what happens to connections that can't keep up with
+//	events: DisconnectSlowClients (the default) or DropSlowClients
+func (h *Hub) SetSlowClientPolicy(policy string) error {
+	if policy != DisconnectSlowClients && policy != DropSlowClients {
+		return fmt.Errorf("Unknown slow client policy '%s'.  Should be one of: %s, %s", policy, DisconnectSlowClients, DropSlowClients)
+	}
+
+	h.connectionsMx.Lock()
+	defer h.connectionsMx.Unlock()
+	h.slowClientPolicy = policy
+	return nil
+}
+
+//	Sends a message to a single connection (like a reply to a command).  If the
+//	connection can't keep up, the slow client policy is applied
+func (h *Hub) reply(c *connection, message []byte) {
+	if c.queue(message) {
+		return
+	}
+
+	h.connectionsMx.RLock()
+	disconnect := h.slowClientPolicy == DisconnectSlowClients
+	h.connectionsMx.RUnlock()
+
+	if disconnect {
+		h.removeConnection(c)
+	}
+}
+
+//	Broadcasts a config item event with the next sequence number from the
+//	datastore.  It doesn't wait for the event to be sent, so changes aren't
+//	held up by each other or by slow connections
+func (h *Hub) publish(store datastores.ConfigService, eventType string, item datastores.ConfigItem) {
+	sequence, err := store.NextEventSequence()
+	if err != nil {
+		//	The event still goes out, but clients can't resume from it
+		log.Printf("[WARN] Can't get the next event sequence number: %v\n", err)
+		sequence = 0
+	}
+
+	h.pendingMx.Lock()
+	h.pending = append(h.pending, pendingEvent{sequence: sequence, message: []byte(getWSEvent(eventType, item, sequence))})
+	h.pendingMx.Unlock()
+
+	//	Wake up the run loop (unless it's already been woken up):
+	select {
+	case h.published <- struct{}{}:
+	default:
+	}
+
+	h.notifyChanged()
+}
+
+//	Returns a channel that's closed the next time config changes
+func (h *Hub) changes() <-chan struct{} {
+	h.changedMx.Lock()
+	defer h.changedMx.Unlock()
+	return h.changed
+}
+
+//	Wakes up everything waiting for config to change
+func (h *Hub) notifyChanged() {
+	h.changedMx.Lock()
+	defer h.changedMx.Unlock()
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+//	Sends the connection the events it missed since the given sequence number,
+//	or tells it to resync if they aren't all in the log anymore.  If register is
+//	true, the connection is added to the hub at the same time so nothing is missed
+func (h *Hub) resume(c *connection, since int64, register bool) {
+	//	If there aren't any logged events, we need the datastore's sequence
+	//	number.  Get it first, so the log isn't locked during the round-trip
+	//	(once there are logged events, there always are):
+	h.logMx.RLock()
+	empty := len(h.log) == 0
+	h.logMx.RUnlock()
+
+	stored, storedErr := int64(0), error(nil)
+	if empty {
+		stored, storedErr = datastores.GetConfigDatastore().GetEventSequence()
+	}
+
+	h.logMx.Lock()
+	defer h.logMx.Unlock()
+
+	if register {
+		h.addConnection(c)
+	}
+
+	//	Find the range of events we can replay:
+	current, oldest := int64(0), int64(0)
+	if len(h.log) > 0 {
+		oldest = h.log[0].sequence
+		current = h.log[len(h.log)-1].sequence
+	} else {
+		if storedErr != nil {
+			h.reply(c, []byte(getWSError("Can't resume: "+storedErr.Error())))
+			return
+		}
+		oldest, current = stored+1, stored
+	}
+
+	//	The client is ahead of us (the datastore was reset) or missed
+	//	events that aren't in the log anymore:
+	if since > current || since < oldest-1 {
+		h.reply(c, []byte(getWSEvent("ResyncRequired", datastores.ConfigItem{}, current)))
+		return
+	}
+
+	replay := [][]byte{}
+	for _, event := range h.log {
+		if event.sequence > since && c.wants(event.item) {
+			replay = append(replay, event.message)
+		}
+	}
+
+	//	If the missed events won't fit in the connection's queue, it's
+	//	quicker for the client to start over:
+	if len(replay)+1 > c.available() {
+		h.reply(c, []byte(getWSEvent("ResyncRequired", datastores.ConfigItem{}, current)))
+		return
+	}
+
+	for _, message := range replay {
+		h.reply(c, message)
+	}
+
+	h.reply(c, []byte(getWSEvent("Resumed", datastores.ConfigItem{}, current)))
+}
+
+//	Gets the logged events after the given sequence number (oldest first)
+func (h *Hub) recentEvents(since int64) []loggedEvent {
+	h.logMx.RLock()
+	defer h.logMx.RUnlock()
+
+	retval := []loggedEvent{}
+	for _, event := range h.log {
+		if event.sequence > since {
+			retval = append(retval, event)
+		}
+	}
+
+	return retval
 }
 
 func (h *Hub) addConnection(conn *connection) {
@@ -56,6 +335,6 @@ func (h *Hub) removeConnection(conn *connection) {
 	defer h.connectionsMx.Unlock()
 	if _, ok := h.connections[conn]; ok {
 		delete(h.connections, conn)
-		close(conn.send)
+		conn.close()
 	}
 }
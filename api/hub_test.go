@@ -0,0 +1,399 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+//	Publishes test events to the hub
+func publishTestEvents(hub *Hub, store datastores.ConfigService, names ...string) {
+	for _, name := range names {
+		hub.publish(store, "Updated", datastores.ConfigItem{Application: "billing", Name: name})
+	}
+}
+
+//	Waits for the hub to log the event with the sequence number
+func waitForLog(hub *Hub, sequence int64) {
+	for i := 0; i < 100; i++ {
+		hub.logMx.RLock()
+		logged := len(hub.log) > 0 && hub.log[len(hub.log)-1].sequence >= sequence
+		hub.logMx.RUnlock()
+		if logged {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//	Connections should be able to resume from a sequence number
+func TestHub_Resume(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+
+	hub := NewHub()
+	hub.SetLogSize(3)
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	publishTestEvents(hub, store, "Item1", "Item2", "Item3", "Item4")
+	waitForLog(hub, 4)
+
+	tests := []struct {
+		since    string
+		expected []string
+		sequence int64
+	}{
+		{"2", []string{"Item3", "Item4", "Resumed"}, 4},
+		{"1", []string{"Item2", "Item3", "Item4", "Resumed"}, 4},
+		{"4", []string{"Resumed"}, 4},
+		{"0", []string{"ResyncRequired"}, 4},
+		{"5", []string{"ResyncRequired"}, 4},
+	}
+
+	for _, test := range tests {
+		//	Act
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?since="+test.since, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %s", err)
+		}
+
+		//	Assert
+		for _, expected := range test.expected {
+			response, err := readWSResponse(conn)
+			if err != nil {
+				t.Errorf("since=%s: Should have gotten %s: %v", test.since, expected, err)
+				break
+			}
+
+			if response.Data.Name != expected && response.Type != expected {
+				t.Errorf("since=%s: Should have gotten %s but got %+v", test.since, expected, response)
+			}
+
+			if (response.Type == "Resumed" || response.Type == "ResyncRequired") && response.Sequence != test.sequence {
+				t.Errorf("since=%s: Should have been at sequence %d but was %d", test.since, test.sequence, response.Sequence)
+			}
+		}
+		conn.Close()
+	}
+}
+
+//	Resuming with the resume command should only replay subscribed events
+func TestHub_ResumeCommand_Subscribed(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+
+	hub := NewHub()
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	hub.publish(store, "Updated", datastores.ConfigItem{Application: "payroll", Name: "Item1"})
+	hub.publish(store, "Updated", datastores.ConfigItem{Application: "billing", Name: "Item2"})
+	waitForLog(hub, 2)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(datastores.WebSocketCommand{Type: wsSubscribe, Application: "billing"})
+	readWSResponse(conn)
+
+	//	Act
+	conn.WriteJSON(datastores.WebSocketCommand{Type: wsResume})
+
+	//	Assert
+	event, err := readWSResponse(conn)
+	if err != nil || event.Data.Name != "Item2" || event.Sequence != 2 {
+		t.Errorf("Resume should have replayed Item2: %+v / %v", event, err)
+	}
+
+	resumed, err := readWSResponse(conn)
+	if err != nil || resumed.Type != "Resumed" || resumed.Sequence != 2 {
+		t.Errorf("Resume should have finished with Resumed: %+v / %v", resumed, err)
+	}
+}
+
+//	After a restart (with an empty log) clients that are up to date can resume
+func TestHub_Resume_EmptyLog(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+	store.NextEventSequence()
+	store.NextEventSequence()
+
+	hub := NewHub()
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	for since, expected := range map[string]string{"2": "Resumed", "1": "ResyncRequired"} {
+		//	Act
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?since="+since, nil)
+		if err != nil {
+			t.Fatalf("Dial failed: %s", err)
+		}
+		response, err := readWSResponse(conn)
+		conn.Close()
+
+		//	Assert
+		if err != nil || response.Type != expected || response.Sequence != 2 {
+			t.Errorf("since=%s: Should have gotten %s: %+v / %v", since, expected, response, err)
+		}
+	}
+}
+
+//	Creates connections that never read and fills their queues
+func addSlowTestConnections(hub *Hub, count int) []*connection {
+	retval := []*connection{}
+	for i := 0; i < count; i++ {
+		c := newConnection(hub)
+		for j := 0; j < sendQueueSize; j++ {
+			c.queue([]byte(getWSResponse("Pong", datastores.ConfigItem{})))
+		}
+		hub.addConnection(c)
+		retval = append(retval, c)
+	}
+
+	return retval
+}
+
+//	Broadcasts test events, failing if the hub doesn't keep up
+func broadcastTestEvents(t *testing.T, hub *Hub, count int) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < count; i++ {
+			hub.Broadcast <- []byte(getWSResponse("Updated", datastores.ConfigItem{Application: "billing", Name: "Item"}))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Broadcasting was blocked by slow connections")
+	}
+}
+
+//	Slow connections should be disconnected without holding up anyone else
+func TestHub_SlowClients_Disconnected(t *testing.T) {
+	//	Arrange
+	hub := NewHub()
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	fast, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer fast.Close()
+	waitForConnections(hub, 1)
+
+	slow := addSlowTestConnections(hub, 50)
+
+	//	Act
+	broadcastTestEvents(t, hub, 10)
+
+	//	Assert
+	for i := 0; i < 10; i++ {
+		if event, err := readWSResponse(fast); err != nil || event.Type != "Updated" {
+			t.Fatalf("Fast connection should have gotten every event: %+v / %v", event, err)
+		}
+	}
+
+	waitForConnections(hub, 1)
+	for _, c := range slow {
+		if c.available() != 0 || c.queue([]byte("{}")) {
+			t.Fatalf("Slow connections should have been closed")
+		}
+	}
+}
+
+//	With the drop policy, slow connections should stay and be told what they missed
+func TestHub_SlowClients_Dropped(t *testing.T) {
+	//	Arrange
+	hub := NewHub()
+	if err := hub.SetSlowClientPolicy(DropSlowClients); err != nil {
+		t.Fatalf("SetSlowClientPolicy failed: %s", err)
+	}
+
+	slow := addSlowTestConnections(hub, 50)
+
+	//	Act
+	broadcastTestEvents(t, hub, 5)
+	for i := 0; i < 100; i++ {
+		slow[len(slow)-1].sendMx.Lock()
+		dropped := slow[len(slow)-1].dropped
+		slow[len(slow)-1].sendMx.Unlock()
+		if dropped == 5 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//	Catch up and get the next event:
+	for i := 0; i < sendQueueSize; i++ {
+		<-slow[0].send
+	}
+	broadcastTestEvents(t, hub, 1)
+
+	//	Assert
+	hub.connectionsMx.RLock()
+	connections := len(hub.connections)
+	hub.connectionsMx.RUnlock()
+	if connections != 50 {
+		t.Errorf("Slow connections shouldn't have been disconnected: %d left", connections)
+	}
+
+	notice := datastores.WebSocketResponse{}
+	select {
+	case message := <-slow[0].send:
+		json.Unmarshal(message, &notice)
+	case <-time.After(5 * time.Second):
+	}
+	if notice.Type != "Dropped" || !strings.HasPrefix(notice.Message, "5 messages") {
+		t.Errorf("Connection should have been told 5 messages were dropped: %+v", notice)
+	}
+
+	event := datastores.WebSocketResponse{}
+	json.Unmarshal(<-slow[0].send, &event)
+	if event.Type != "Updated" {
+		t.Errorf("Connection should have gotten the next event: %+v", event)
+	}
+}
+
+//	Unknown slow client policies should be rejected
+func TestHub_SetSlowClientPolicy_Unknown_ReturnsError(t *testing.T) {
+	if err := NewHub().SetSlowClientPolicy("ignore"); err == nil {
+		t.Errorf("SetSlowClientPolicy failed: Should have returned an error for an unknown policy")
+	}
+}
+
+//	Clients that don't answer pings should be disconnected
+func TestWsHandler_Ping_DisconnectsUnresponsive(t *testing.T) {
+	//	Arrange
+	hub := NewHub()
+	hub.pongWait, hub.pingPeriod = 200*time.Millisecond, 50*time.Millisecond
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	//	Reading answers pings:
+	responsive, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer responsive.Close()
+	go func() {
+		for {
+			if _, _, err := responsive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	unresponsive, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer unresponsive.Close()
+	waitForConnections(hub, 2)
+
+	//	Act
+	time.Sleep(3 * hub.pongWait)
+
+	//	Assert
+	hub.connectionsMx.RLock()
+	connections := len(hub.connections)
+	hub.connectionsMx.RUnlock()
+	if connections != 1 {
+		t.Errorf("Only the responsive connection should be left but there are %d", connections)
+	}
+}
+
+//	A datastore that waits to hand out event sequence numbers
+type blockingSequenceStore struct {
+	datastores.ConfigService
+	release chan struct{}
+}
+
+func (s blockingSequenceStore) NextEventSequence() (int64, error) {
+	<-s.release
+	return s.ConfigService.NextEventSequence()
+}
+
+//	A slow datastore shouldn't hold up changes published through another one
+func TestHub_Publish_NotBlocked(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+	slow := blockingSequenceStore{ConfigService: store, release: make(chan struct{})}
+
+	hub := NewHub()
+	go hub.publish(slow, "Updated", datastores.ConfigItem{Application: "billing", Name: "Slow"})
+
+	//	Act
+	done := make(chan struct{})
+	go func() {
+		publishTestEvents(hub, store, "Item1", "Item2")
+		close(done)
+	}()
+
+	//	Assert
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Errorf("Publishing shouldn't have waited for the slow datastore")
+	}
+
+	close(slow.release)
+	waitForLog(hub, 3)
+
+	hub.logMx.RLock()
+	defer hub.logMx.RUnlock()
+	if len(hub.log) != 3 {
+		t.Errorf("Should have logged all 3 events: %d", len(hub.log))
+	}
+}
+
+//	Events published out of order should be logged in sequence order
+func TestInsertLoggedEvent_Ordered(t *testing.T) {
+	//	Arrange
+	events := []loggedEvent{}
+
+	//	Act
+	for _, sequence := range []int64{1, 2, 5, 3, 4, 6} {
+		events = insertLoggedEvent(events, loggedEvent{sequence: sequence})
+	}
+
+	//	Assert
+	for i, event := range events {
+		if event.sequence != int64(i+1) {
+			t.Errorf("Should have logged the events in sequence order: %+v", events)
+			break
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//	ApprovalAuth is how the users who propose and review changes to protected
+//	applications are identified.  Users are either checked with basic
+//	authentication, or named in a header by an authenticating proxy
+type ApprovalAuth struct {
+	//	User names and their bcrypt password hashes (for basic authentication)
+	Users map[string]string
+
+	//	The header an authenticating proxy puts the user name in (like X-Forwarded-User)
+	UserHeader string
+
+	//	The addresses (or CIDR ranges) of the proxies that can set the header.
+	//	The header is ignored on requests from anywhere else
+	TrustedProxies []string
+}
+
+//	The approval auth settings, ready to use
+type approvalIdentity struct {
+	users      map[string]string
+	userHeader string
+	proxies    []*net.IPNet
+}
+
+var (
+	identity   = approvalIdentity{}
+	identityMx sync.RWMutex
+)
+
+//	SetApprovalAuth sets how the users who propose and review changes are
+//	identified.  Returns an error if the settings aren't valid
+func SetApprovalAuth(auth ApprovalAuth) error {
+	retval := approvalIdentity{users: make(map[string]string), userHeader: http.CanonicalHeaderKey(auth.UserHeader)}
+
+	for user, hash := range auth.Users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("The password hash for '%s' should be a bcrypt hash: %v", user, err)
+		}
+		retval.users[user] = hash
+	}
+
+	if auth.UserHeader != "" && len(auth.TrustedProxies) == 0 {
+		return fmt.Errorf("The user header needs trusted proxies: anyone could set it")
+	}
+
+	for _, proxy := range auth.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy = proxy + "/32"
+			} else {
+				proxy = proxy + "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("The trusted proxy '%s' should be an address or CIDR range", proxy)
+		}
+		retval.proxies = append(retval.proxies, network)
+	}
+
+	identityMx.Lock()
+	defer identityMx.Unlock()
+	identity = retval
+	return nil
+}
+
+//	HasApprovalAuth returns true if users can be identified (so changes to
+//	protected applications can be proposed and reviewed)
+func HasApprovalAuth() bool {
+	identityMx.RLock()
+	defer identityMx.RUnlock()
+	return len(identity.users) > 0 || identity.userHeader != ""
+}
+
+//	Gets the authenticated user making the request: the user name in the
+//	header from a trusted proxy, or the basic authentication user (if the
+//	password is right).  Returns a forbidden error if there isn't one
+func getRequestUser(req *http.Request) (string, error) {
+	identityMx.RLock()
+	defer identityMx.RUnlock()
+
+	if identity.userHeader != "" && identity.isTrustedProxy(req.RemoteAddr) {
+		if user := strings.TrimSpace(req.Header.Get(identity.userHeader)); user != "" {
+			return user, nil
+		}
+	}
+
+	if user, password, ok := req.BasicAuth(); ok {
+		if hash, found := identity.users[user]; found && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return user, nil
+		}
+	}
+
+	return "", &datastores.ConfigError{
+		Code:    datastores.CodeForbidden,
+		Message: "An authenticated user is required to propose or review changes to protected applications"}
+}
+
+//	Returns true if the address (host:port) is one of the trusted proxies
+func (i approvalIdentity) isTrustedProxy(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range i.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
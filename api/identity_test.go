@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Basic authentication should only identify the user with the right password
+func TestGetRequestUser_BasicAuth(t *testing.T) {
+	//	Arrange
+	setTestApprovalUsers(t, "alice")
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	tests := []struct {
+		user, password string
+		want           string
+	}{
+		{"alice", "secret", "alice"},
+		{"alice", "wrong", ""},
+		{"mallory", "secret", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/config/set", nil)
+		req.SetBasicAuth(test.user, test.password)
+
+		//	Act
+		user, err := getRequestUser(req)
+
+		//	Assert
+		if user != test.want {
+			t.Errorf("%s/%s should have been '%s' but was '%s'", test.user, test.password, test.want, user)
+		}
+
+		if test.want == "" && datastores.ErrorCode(err) != datastores.CodeForbidden {
+			t.Errorf("%s/%s should have been forbidden: %v", test.user, test.password, err)
+		}
+	}
+}
+
+//	The user header should only be trusted from a trusted proxy
+func TestGetRequestUser_UserHeader(t *testing.T) {
+	//	Arrange
+	if err := SetApprovalAuth(ApprovalAuth{UserHeader: "X-Forwarded-User", TrustedProxies: []string{"10.0.0.0/8", "192.168.1.5"}}); err != nil {
+		t.Fatalf("SetApprovalAuth failed: %s", err)
+	}
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{"10.1.2.3:4567", "alice"},
+		{"192.168.1.5:4567", "alice"},
+		{"192.168.1.6:4567", ""},
+		{"203.0.113.9:4567", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/config/set", nil)
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set("X-Forwarded-User", "alice")
+
+		//	Act
+		user, _ := getRequestUser(req)
+
+		//	Assert
+		if user != test.want {
+			t.Errorf("%s should have been '%s' but was '%s'", test.remoteAddr, test.want, user)
+		}
+	}
+}
+
+//	Invalid settings should be refused
+func TestSetApprovalAuth_Invalid(t *testing.T) {
+	//	Arrange
+	tests := []ApprovalAuth{
+		{Users: map[string]string{"alice": "secret"}},
+		{UserHeader: "X-Forwarded-User"},
+		{UserHeader: "X-Forwarded-User", TrustedProxies: []string{"proxy.example.com"}},
+	}
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	for _, test := range tests {
+		//	Act
+		err := SetApprovalAuth(test)
+
+		//	Assert
+		if err == nil {
+			t.Errorf("%+v should have been refused", test)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/formats"
+)
+
+//	The largest config file we'll accept
+const maxImportSize = 10 << 20
+
+//	Imports a config file for an application (and optional machine).
+//	The request body is the file itself.  Query parameters:
+//
+//	app: the application name (required)
+//	machine: the machine name
+//	format: one of env, yaml, json, toml, ini, properties (required)
+//	mode: merge (the default) keeps other items, replace removes them
+//	dryrun: if true, return the changes without making them
+func ImportConfig(rw http.ResponseWriter, req *http.Request) {
+	//	req.Body is a ReadCloser -- we need to remember to close it:
+	defer req.Body.Close()
+
+	query := req.URL.Query()
+
+	application := query.Get("app")
+	if application == "" {
+		sendErrorResponse(rw, fmt.Errorf("The app parameter is required"), http.StatusBadRequest)
+		return
+	}
+
+	format, err := formats.ParseFormat(query.Get("format"))
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = datastores.ImportMerge
+	}
+	if mode != datastores.ImportMerge && mode != datastores.ImportReplace {
+		sendErrorResponse(rw, fmt.Errorf("The mode parameter should be merge or replace"), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if query.Get("dryrun") != "" {
+		dryRun, err = strconv.ParseBool(query.Get("dryrun"))
+		if err != nil {
+			sendErrorResponse(rw, fmt.Errorf("The dryrun parameter should be true or false"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	//	Read and decode the file:
+	data, err := ioutil.ReadAll(http.MaxBytesReader(rw, req.Body, maxImportSize))
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	values, err := formats.Decode(data, format)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Figure out what needs to change:
+	changes, err := datastores.PlanImport(ds, application, query.Get("machine"), values, mode)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	result := datastores.ImportResult{DryRun: dryRun, Changes: changes}
+	if dryRun || len(changes) == 0 {
+		sendDataResponse(rw, "Config import planned", result)
+		return
+	}
+
+	//	Imports can't be proposed, so protected applications can't be imported:
+	if err := checkUnprotected(application); err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	Make the changes and let everyone know:
+	updated, removed, err := datastores.ApplyChanges(ds, changes)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, item := range updated {
+		WsHub.publish(ds, "Updated", item)
+	}
+
+	for _, item := range removed {
+		WsHub.publish(ds, "Removed", item)
+	}
+
+	sendDataResponse(rw, "Config imported", result)
+}
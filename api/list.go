@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Gets the list options from the query parameters:
+//
+//	limit: the most items to return (all of them if there isn't a limit)
+//	cursor: the next cursor from the previous page
+//	prefix: only items with names that start with the prefix
+//	contains: only items with values that contain the string
+//	machine: only items for the machine (repeat it for more machines, and leave it blank for items without a machine)
+//	label: only items with the label (like pii or owner:payments -- repeat it for more labels)
+//	sort: application (the default), name or updated, with a - prefix for descending order
+func getListOptions(req *http.Request) (datastores.ListOptions, error) {
+	query := req.URL.Query()
+
+	options := datastores.ListOptions{
+		Cursor:        query.Get("cursor"),
+		NamePrefix:    query.Get("prefix"),
+		ValueContains: query.Get("contains"),
+		Sort:          query.Get("sort"),
+		Labels:        getLabelParameters(req)}
+
+	if machines, ok := query["machine"]; ok {
+		options.Machines = machines
+	}
+
+	if query.Get("limit") != "" {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			return options, fmt.Errorf("The limit parameter should be a number from 1 to %d", datastores.MaxListLimit)
+		}
+		options.Limit = limit
+	}
+
+	return options, nil
+}
+
+//	Gets the label query parameters.  Each one is a label name (like pii) that
+//	matches any value, or a name and value (like owner:payments)
+func getLabelParameters(req *http.Request) map[string]string {
+	parameters := req.URL.Query()["label"]
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]string)
+	for _, parameter := range parameters {
+		name, value := parameter, ""
+		if i := strings.Index(parameter, ":"); i >= 0 {
+			name, value = parameter[:i], parameter[i+1:]
+		}
+		labels[name] = value
+	}
+
+	return labels
+}
+
+//	Lists the config items for the applications (every application if there
+//	aren't any) using the list options in the query parameters
+func sendListResponse(rw http.ResponseWriter, req *http.Request, applications []string, found, notFound string) {
+	options, err := getListOptions(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+	options.Applications = applications
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	page, err := ds.List(options)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	message := found
+	if len(page.Items) == 0 {
+		message = notFound
+	}
+
+	//	Include the cursor for the next page (if there is one):
+	sendResponse(rw, datastores.ConfigResponse{
+		Status:  http.StatusOK,
+		Message: message,
+		Data:    page.Items,
+		Next:    page.Next})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	Lists all config items with the query parameters
+func getTestList(t *testing.T, query url.Values) (*httptest.ResponseRecorder, datastores.ConfigResponse, []datastores.ConfigItem) {
+	req := httptest.NewRequest("GET", "/config/getall?"+query.Encode(), nil)
+	rw := httptest.NewRecorder()
+	GetAllConfig(rw, req)
+
+	items := []datastores.ConfigItem{}
+	response := datastores.ConfigResponse{Data: &items}
+	if err := json.NewDecoder(rw.Body).Decode(&response); err != nil {
+		t.Fatalf("The response isn't valid JSON: %s", err)
+	}
+
+	return rw, response, items
+}
+
+//	Following the next cursor should page through the filtered items
+func TestGetAllConfig_Pages_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "ShowHeader", Value: "true"},
+		{Application: "billing", Name: "ShowFooter", Value: "false"},
+		{Application: "billing", Name: "Timeout", Value: "30"},
+		{Application: "reports", Name: "ShowTotals", Value: "true"},
+		{Application: "reports", Name: "ShowTotals", Machine: "web01", Value: "false"},
+	} {
+		if _, err := ds.Set(item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	//	Act
+	query := url.Values{"limit": {"2"}, "prefix": {"Show"}, "machine": {""}}
+	names := []string{}
+	for pages := 0; pages < 5; pages++ {
+		rw, response, items := getTestList(t, query)
+		if rw.Code != http.StatusOK {
+			t.Fatalf("List failed: %d %+v", rw.Code, response)
+		}
+
+		for _, item := range items {
+			names = append(names, item.Application+"/"+item.Name)
+		}
+
+		if response.Next == "" {
+			break
+		}
+		query.Set("cursor", response.Next)
+	}
+
+	//	Assert
+	expected := []string{"billing/ShowFooter", "billing/ShowHeader", "reports/ShowTotals"}
+	if len(names) != len(expected) {
+		t.Fatalf("Should have listed %v: %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Should have listed %v: %v", expected, names)
+			break
+		}
+	}
+}
+
+//	Bad list parameters should be validation errors
+func TestGetAllConfig_InvalidOptions_Validation(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	for _, query := range []url.Values{
+		{"limit": {"none"}},
+		{"limit": {"5000"}},
+		{"sort": {"value"}},
+		{"cursor": {"not a cursor"}},
+	} {
+		//	Act
+		rw, response, _ := getTestList(t, query)
+
+		//	Assert
+		if rw.Code != http.StatusBadRequest || response.Code != datastores.CodeValidation {
+			t.Errorf("%v should have been a validation error: %d %+v", query, rw.Code, response)
+		}
+	}
+}
+
+//	Listing with label parameters should only return items with all of the labels
+func TestGetAllConfig_Labels_Filtered(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "db.host", Value: "db01.example.com", Labels: map[string]string{"owner": "payments", "pii": ""}},
+		{Application: "billing", Name: "db.user", Value: "billing", Labels: map[string]string{"owner": "payments"}},
+		{Application: "billing", Name: "ui.theme", Value: "dark", Labels: map[string]string{"owner": "web"}},
+	} {
+		if _, err := ds.Set(item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	//	Act
+	rw, response, items := getTestList(t, url.Values{"label": {"owner:payments", "pii"}})
+
+	//	Assert
+	if rw.Code != http.StatusOK || len(items) != 1 || items[0].Name != "db.host" {
+		t.Errorf("Should have only listed db.host: %d %+v / %+v", rw.Code, response, items)
+	}
+}
+
+//	Expired items that haven't been removed yet shouldn't be listed
+func TestGetAllConfig_Expired_Skipped(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	expired := time.Now().Add(-time.Minute)
+	ds := datastores.GetConfigDatastore()
+	ds.Set(datastores.ConfigItem{Application: "billing", Name: "Banner", Value: "Down for maintenance", Expires: &expired})
+	ds.Set(datastores.ConfigItem{Application: "billing", Name: "Timeout", Value: "30"})
+
+	//	Act
+	rw, response, items := getTestList(t, url.Values{})
+
+	req := httptest.NewRequest("GET", "/config/getallforapp", strings.NewReader(`{"application":"billing"}`))
+	appRw := httptest.NewRecorder()
+	GetAllConfigForApp(appRw, req)
+	appItems := []datastores.ConfigItem{}
+	json.NewDecoder(appRw.Body).Decode(&datastores.ConfigResponse{Data: &appItems})
+
+	//	Assert
+	if rw.Code != http.StatusOK || len(items) != 1 || items[0].Name != "Timeout" {
+		t.Errorf("Should have only listed Timeout: %d %+v / %+v", rw.Code, response, items)
+	}
+
+	if appRw.Code != http.StatusOK || len(appItems) != 1 || appItems[0].Name != "Timeout" {
+		t.Errorf("Should have only listed Timeout for the application: %d %+v", appRw.Code, appItems)
+	}
+}
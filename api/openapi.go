@@ -0,0 +1,849 @@
+package api
+
+import (
+	"net/http"
+)
+
+//	The OpenAPI 3 document describing the HTTP API.  Every route registered
+//	by AddRoutes needs to be described here (the router tests check)
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "centralconfig",
+    "description": "A simple REST based service for managing application configuration across a cluster",
+    "version": "2"
+  },
+  "paths": {
+    "/": {
+      "get": {
+        "summary": "Redirects to the web based UI",
+        "tags": ["ui"],
+        "responses": {
+          "301": {"description": "Redirect to /ui/"}
+        }
+      }
+    },
+    "/ui": {
+      "get": {
+        "summary": "The web based UI (static files under /ui/)",
+        "tags": ["ui"],
+        "responses": {
+          "200": {"description": "A UI file", "content": {"text/html": {}}},
+          "404": {"description": "No UI file with that path"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "tags": ["meta"],
+        "responses": {
+          "200": {"description": "The OpenAPI 3 document", "content": {"application/json": {}}}
+        }
+      }
+    },
+    "/config/get": {
+      "post": {
+        "summary": "Gets a config item",
+        "description": "Gets the config item with the application, machine and name in the request",
+        "tags": ["config"],
+        "requestBody": {"$ref": "#/components/requestBodies/ConfigItem"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItem"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/set": {
+      "post": {
+        "summary": "Creates or updates a config item",
+        "description": "Include the id to update an existing item",
+        "tags": ["config"],
+        "requestBody": {"$ref": "#/components/requestBodies/ConfigItem"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItem"},
+          "202": {"$ref": "#/components/responses/Proposal"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/remove": {
+      "post": {
+        "summary": "Removes a config item",
+        "tags": ["config"],
+        "requestBody": {"$ref": "#/components/requestBodies/ConfigItem"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItem"},
+          "202": {"$ref": "#/components/responses/Proposal"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/getall": {
+      "post": {
+        "summary": "Gets all config items",
+        "tags": ["config"],
+        "parameters": [
+          {"$ref": "#/components/parameters/limitQuery"},
+          {"$ref": "#/components/parameters/cursorQuery"},
+          {"$ref": "#/components/parameters/prefixQuery"},
+          {"$ref": "#/components/parameters/containsQuery"},
+          {"$ref": "#/components/parameters/machinesQuery"},
+          {"$ref": "#/components/parameters/labelQuery"},
+          {"$ref": "#/components/parameters/sortQuery"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/getallforapp": {
+      "post": {
+        "summary": "Gets all config items for an application",
+        "description": "Only the application in the request is used.  Items for the default (*) application are included",
+        "tags": ["config"],
+        "parameters": [
+          {"$ref": "#/components/parameters/limitQuery"},
+          {"$ref": "#/components/parameters/cursorQuery"},
+          {"$ref": "#/components/parameters/prefixQuery"},
+          {"$ref": "#/components/parameters/containsQuery"},
+          {"$ref": "#/components/parameters/machinesQuery"},
+          {"$ref": "#/components/parameters/labelQuery"},
+          {"$ref": "#/components/parameters/sortQuery"}
+        ],
+        "requestBody": {"$ref": "#/components/requestBodies/ConfigItem"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/search": {
+      "get": {
+        "summary": "Searches the names and values of config items",
+        "description": "Secret values (items with names like *password* or *token*) aren't searched",
+        "tags": ["config"],
+        "parameters": [
+          {"name": "q", "in": "query", "required": true, "description": "The text (or regular expression) to search for", "schema": {"type": "string"}},
+          {"name": "match", "in": "query", "description": "How the query is matched (defaults to substring)", "schema": {"type": "string", "enum": ["substring", "regex", "exact"]}},
+          {"name": "field", "in": "query", "description": "The fields to match (both by default)", "schema": {"type": "array", "items": {"type": "string", "enum": ["name", "value"]}}, "explode": true},
+          {"name": "ignorecase", "in": "query", "description": "Match without case", "schema": {"type": "boolean"}},
+          {"name": "application", "in": "query", "description": "Only items for these applications", "schema": {"type": "array", "items": {"type": "string"}}, "explode": true},
+          {"$ref": "#/components/parameters/machinesQuery"},
+          {"$ref": "#/components/parameters/limitQuery"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/applications/getall": {
+      "post": {
+        "summary": "Gets all application names",
+        "tags": ["config"],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Applications"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/export": {
+      "get": {
+        "summary": "Exports the resolved config for an application as a config file",
+        "tags": ["import / export"],
+        "parameters": [
+          {"$ref": "#/components/parameters/appQuery"},
+          {"$ref": "#/components/parameters/machineQuery"},
+          {"name": "format", "in": "query", "description": "The file format (defaults to env)", "schema": {"$ref": "#/components/schemas/Format"}},
+          {"name": "prefix", "in": "query", "description": "Only items with the prefix as their name, or under it", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/labelQuery"},
+          {"name": "nest", "in": "query", "description": "Nest dotted names into trees / sections", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The config file",
+            "content": {
+              "text/plain": {"schema": {"type": "string"}},
+              "application/json": {"schema": {"type": "object"}},
+              "application/x-yaml": {"schema": {"type": "string"}},
+              "application/toml": {"schema": {"type": "string"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/import": {
+      "post": {
+        "summary": "Imports a config file for an application",
+        "tags": ["import / export"],
+        "parameters": [
+          {"$ref": "#/components/parameters/appQuery"},
+          {"$ref": "#/components/parameters/machineQuery"},
+          {"name": "format", "in": "query", "required": true, "description": "The file format", "schema": {"$ref": "#/components/schemas/Format"}},
+          {"name": "mode", "in": "query", "description": "merge (the default) keeps other items, replace removes them", "schema": {"type": "string", "enum": ["merge", "replace"]}},
+          {"name": "dryrun", "in": "query", "description": "Only report the changes that would be made", "schema": {"type": "boolean"}}
+        ],
+        "requestBody": {
+          "required": true,
+          "description": "The config file",
+          "content": {"*/*": {"schema": {"type": "string"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "The changes made (or planned)",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "allOf": [
+                    {"$ref": "#/components/schemas/ConfigResponse"},
+                    {"properties": {"data": {"$ref": "#/components/schemas/ImportResult"}}}
+                  ]
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/config/watch": {
+      "get": {
+        "summary": "Waits for an application's config to change (long polling)",
+        "description": "Returns as soon as the application's modification index is greater than index, or when wait runs out",
+        "tags": ["changes"],
+        "parameters": [
+          {"$ref": "#/components/parameters/appQuery"},
+          {"name": "index", "in": "query", "description": "The last modification index seen", "schema": {"type": "integer", "format": "int64"}},
+          {"name": "wait", "in": "query", "description": "How long to wait, like 30s (defaults to 5m, at most 10m)", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The application's config items",
+            "headers": {
+              "X-Config-Index": {"description": "The application's modification index", "schema": {"type": "integer", "format": "int64"}}
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "allOf": [
+                    {"$ref": "#/components/schemas/ConfigResponse"},
+                    {"properties": {"data": {"type": "array", "items": {"$ref": "#/components/schemas/ConfigItem"}}}}
+                  ]
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/apps": {
+      "get": {
+        "summary": "Lists all application names",
+        "tags": ["v2"],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Applications"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/apps/{app}/config": {
+      "get": {
+        "summary": "Lists an application's config items",
+        "tags": ["v2"],
+        "parameters": [
+          {"$ref": "#/components/parameters/appPath"},
+          {"$ref": "#/components/parameters/limitQuery"},
+          {"$ref": "#/components/parameters/cursorQuery"},
+          {"$ref": "#/components/parameters/prefixQuery"},
+          {"$ref": "#/components/parameters/containsQuery"},
+          {"$ref": "#/components/parameters/machinesQuery"},
+          {"$ref": "#/components/parameters/labelQuery"},
+          {"$ref": "#/components/parameters/sortQuery"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/apps/{app}/config/{name}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/appPath"},
+        {"$ref": "#/components/parameters/namePath"},
+        {"$ref": "#/components/parameters/machineQuery"}
+      ],
+      "get": {
+        "summary": "Gets a config item",
+        "tags": ["v2"],
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItem"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "summary": "Creates or updates a config item",
+        "description": "The value, description, labels and expires (or a ttl like 30m instead of expires) in the request body are used.  A missing description, labels or expires keeps the item's current one, and a null expires removes the expiry time.  The application, name and machine come from the path and query",
+        "tags": ["v2"],
+        "requestBody": {"$ref": "#/components/requestBodies/ConfigItem"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItem"},
+          "201": {
+            "description": "The config item was created",
+            "headers": {
+              "Location": {"description": "The new item's path", "schema": {"type": "string"}}
+            },
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ConfigItemResponse"}}}
+          },
+          "202": {"$ref": "#/components/responses/Proposal"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Removes a config item",
+        "tags": ["v2"],
+        "responses": {
+          "204": {"description": "The config item was removed"},
+          "202": {"$ref": "#/components/responses/Proposal"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/apps/{app}/tree/{prefix}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/appPath"},
+        {"$ref": "#/components/parameters/prefixPath"}
+      ],
+      "get": {
+        "summary": "Gets the config items with the prefix as their name, or under it",
+        "tags": ["v2"],
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Removes the config items under the prefix",
+        "tags": ["v2"],
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/apps/{app}/tree/{prefix}/move": {
+      "parameters": [
+        {"$ref": "#/components/parameters/appPath"},
+        {"$ref": "#/components/parameters/prefixPath"}
+      ],
+      "post": {
+        "summary": "Moves the config items under the prefix to a new prefix",
+        "tags": ["v2"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"type": "object", "properties": {"to": {"type": "string", "description": "The new prefix"}}, "required": ["to"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/ConfigItems"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/apps/{app}/resolved": {
+      "get": {
+        "summary": "Gets the effective config items for an application and machine",
+        "description": "With nest, the data is an object nested by the items' dotted names instead of a list",
+        "tags": ["v2"],
+        "parameters": [
+          {"$ref": "#/components/parameters/appPath"},
+          {"$ref": "#/components/parameters/machineQuery"},
+          {"name": "prefix", "in": "query", "description": "Only items with the prefix as their name, or under it", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/labelQuery"},
+          {"name": "nest", "in": "query", "description": "Nest dotted names into objects", "schema": {"type": "boolean"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The effective config items (or an object, with nest)",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "allOf": [
+                    {"$ref": "#/components/schemas/ConfigResponse"},
+                    {"properties": {"data": {"oneOf": [{"type": "array", "items": {"$ref": "#/components/schemas/ConfigItem"}}, {"type": "object"}]}}}
+                  ]
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/schedule": {
+      "get": {
+        "summary": "Lists the scheduled changes that haven't been made yet",
+        "description": "Changes are listed in the order they'll be made",
+        "tags": ["v2"],
+        "parameters": [
+          {"name": "application", "in": "query", "description": "Only changes for the application", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The scheduled changes",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "allOf": [
+                    {"$ref": "#/components/schemas/ConfigResponse"},
+                    {"properties": {"data": {"type": "array", "items": {"$ref": "#/components/schemas/ScheduledChange"}}}}
+                  ]
+                }
+              }
+            }
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Schedules a config value change",
+        "description": "The item's value is changed at the effective time (which should be in the future).  The item keeps its description, labels and expiry time, and an Updated event is sent when the change is made",
+        "tags": ["v2"],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ScheduledChange"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "The change was scheduled",
+            "headers": {
+              "Location": {"description": "The scheduled change's path", "schema": {"type": "string"}}
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "allOf": [
+                    {"$ref": "#/components/schemas/ConfigResponse"},
+                    {"properties": {"data": {"$ref": "#/components/schemas/ScheduledChange"}}}
+                  ]
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/schedule/{id}": {
+      "delete": {
+        "summary": "Cancels a scheduled change",
+        "tags": ["v2"],
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "description": "The scheduled change id", "schema": {"type": "integer", "format": "int64"}}
+        ],
+        "responses": {
+          "204": {"description": "The change was cancelled"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/proposals": {
+      "get": {
+        "summary": "Lists proposed changes to protected applications",
+        "description": "Proposals are never removed (so reviewed proposals are an audit trail).  They're listed oldest first",
+        "tags": ["approval"],
+        "parameters": [
+          {"name": "status", "in": "query", "description": "Only proposals with the status", "schema": {"$ref": "#/components/schemas/ProposalStatus"}},
+          {"name": "application", "in": "query", "description": "Only proposals for the application", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The proposals",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "allOf": [
+                    {"$ref": "#/components/schemas/ConfigResponse"},
+                    {"properties": {"data": {"type": "array", "items": {"$ref": "#/components/schemas/ChangeProposal"}}}}
+                  ]
+                }
+              }
+            }
+          },
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/proposals/{id}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/proposalPath"}
+      ],
+      "get": {
+        "summary": "Gets a proposal",
+        "tags": ["approval"],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Proposal"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/proposals/{id}/approve": {
+      "parameters": [
+        {"$ref": "#/components/parameters/proposalPath"}
+      ],
+      "post": {
+        "summary": "Approves a pending proposal and makes the change",
+        "description": "The reviewer is the authenticated user (basic authentication or the user header from a trusted proxy), and can't be the person who proposed the change.  An Updated (or Removed) event is sent when the change is made",
+        "tags": ["approval"],
+        "requestBody": {"$ref": "#/components/requestBodies/Review"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Proposal"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v2/proposals/{id}/reject": {
+      "parameters": [
+        {"$ref": "#/components/parameters/proposalPath"}
+      ],
+      "post": {
+        "summary": "Rejects a pending proposal",
+        "description": "The reviewer is the authenticated user (basic authentication or the user header from a trusted proxy), and can't be the person who proposed the change",
+        "tags": ["approval"],
+        "requestBody": {"$ref": "#/components/requestBodies/Review"},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Proposal"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/ws": {
+      "get": {
+        "summary": "Streams config change events over a WebSocket",
+        "description": "Send subscribe, unsubscribe, ping and resume commands (see WebSocketCommand).  Events are sent as WebSocketResponse messages",
+        "tags": ["changes"],
+        "responses": {
+          "101": {"description": "Switching to the WebSocket protocol"}
+        }
+      }
+    },
+    "/events": {
+      "get": {
+        "summary": "Streams config change events with Server-Sent Events",
+        "tags": ["changes"],
+        "parameters": [
+          {"name": "application", "in": "query", "description": "Only send events for applications matching this pattern", "schema": {"type": "string"}},
+          {"name": "machine", "in": "query", "description": "Only send events for machines matching this pattern", "schema": {"type": "string"}},
+          {"name": "name", "in": "query", "description": "Only send events for names matching this pattern", "schema": {"type": "string"}},
+          {"name": "since", "in": "query", "description": "Replay the events after this sequence number first", "schema": {"type": "integer", "format": "int64"}},
+          {"name": "Last-Event-ID", "in": "header", "description": "Like since (used by browsers when they reconnect)", "schema": {"type": "integer", "format": "int64"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "The event stream.  Each event's data is a WebSocketResponse",
+            "content": {"text/event-stream": {"schema": {"type": "string"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/graphql": {
+      "get": {
+        "summary": "Runs GraphQL subscriptions over a WebSocket",
+        "description": "Uses the graphql-transport-ws subprotocol",
+        "tags": ["graphql"],
+        "responses": {
+          "101": {"description": "Switching to the WebSocket protocol"},
+          "405": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Runs a GraphQL query or mutation",
+        "tags": ["graphql"],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["query"],
+                "properties": {
+                  "query": {"type": "string"},
+                  "operationName": {"type": "string"},
+                  "variables": {"type": "object"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The GraphQL response",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "properties": {
+                    "data": {"type": "object"},
+                    "errors": {"type": "array", "items": {"type": "object"}}
+                  }
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "ConfigItem": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "application": {"type": "string", "description": "The application name (* for the defaults every application gets)"},
+          "machine": {"type": "string", "description": "The machine name (blank for every machine)"},
+          "name": {"type": "string"},
+          "value": {"type": "string"},
+          "updated": {"type": "string", "format": "date-time"},
+          "description": {"type": "string"},
+          "labels": {"type": "object", "description": "Labels like owner: payments (or pii with a blank value)", "additionalProperties": {"type": "string"}},
+          "expires": {"type": "string", "format": "date-time", "description": "When the item expires (never if it isn't there).  Expired items are ignored, then removed by the expiry sweeper"}
+        }
+      },
+      "ConfigResponse": {
+        "type": "object",
+        "description": "The envelope every JSON response is sent in",
+        "properties": {
+          "status": {"type": "integer", "description": "The HTTP status code"},
+          "code": {"$ref": "#/components/schemas/ErrorCode"},
+          "message": {"type": "string"},
+          "data": {"nullable": true},
+          "next": {"type": "string", "description": "The cursor for the next page of a list (only sent if there is one)"}
+        }
+      },
+      "ConfigItemResponse": {
+        "allOf": [
+          {"$ref": "#/components/schemas/ConfigResponse"},
+          {"properties": {"data": {"$ref": "#/components/schemas/ConfigItem"}}}
+        ]
+      },
+      "ScheduledChange": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer", "format": "int64", "readOnly": true},
+          "application": {"type": "string"},
+          "machine": {"type": "string", "description": "The machine name (blank for every machine)"},
+          "name": {"type": "string"},
+          "value": {"type": "string", "description": "The value the item is set to"},
+          "effective": {"type": "string", "format": "date-time", "description": "When the change is made"},
+          "created": {"type": "string", "format": "date-time", "readOnly": true}
+        }
+      },
+      "ChangeProposal": {
+        "type": "object",
+        "description": "A change to a protected application that needs to be approved by someone other than the person who proposed it",
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "action": {"type": "string", "enum": ["set", "remove"]},
+          "item": {"$ref": "#/components/schemas/ConfigItem"},
+          "status": {"$ref": "#/components/schemas/ProposalStatus"},
+          "proposedBy": {"type": "string"},
+          "proposed": {"type": "string", "format": "date-time"},
+          "reviewedBy": {"type": "string"},
+          "reviewed": {"type": "string", "format": "date-time"},
+          "comment": {"type": "string", "description": "The reviewer's comment"},
+          "previous": {"$ref": "#/components/schemas/ConfigItem"},
+          "error": {"type": "string", "description": "Why an approved change couldn't be made"}
+        }
+      },
+      "ProposalStatus": {
+        "type": "string",
+        "description": "pending proposals are waiting for a review.  failed proposals were approved, but the change couldn't be made",
+        "enum": ["pending", "approved", "rejected", "failed"]
+      },
+      "ConfigChange": {
+        "type": "object",
+        "properties": {
+          "action": {"type": "string"},
+          "application": {"type": "string"},
+          "machine": {"type": "string"},
+          "name": {"type": "string"},
+          "previous": {"type": "string"},
+          "value": {"type": "string"}
+        }
+      },
+      "ImportResult": {
+        "type": "object",
+        "properties": {
+          "dryrun": {"type": "boolean"},
+          "changes": {"type": "array", "items": {"$ref": "#/components/schemas/ConfigChange"}}
+        }
+      },
+      "WebSocketCommand": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "enum": ["subscribe", "unsubscribe", "ping", "resume"]},
+          "application": {"type": "string"},
+          "machine": {"type": "string"},
+          "name": {"type": "string"},
+          "since": {"type": "integer", "format": "int64"}
+        }
+      },
+      "WebSocketResponse": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "description": "Updated, Removed, Resumed, ResyncRequired, Dropped, Pong or Error"},
+          "data": {"$ref": "#/components/schemas/ConfigItem"},
+          "sequence": {"type": "integer", "format": "int64"},
+          "message": {"type": "string"}
+        }
+      },
+      "ErrorCode": {
+        "type": "string",
+        "description": "Identifies the kind of error (only sent with errors)",
+        "enum": ["not_found", "conflict", "validation", "forbidden", "method_not_allowed", "unprocessable", "unavailable", "internal"]
+      },
+      "Format": {
+        "type": "string",
+        "enum": ["env", "yaml", "json", "toml", "ini", "properties"]
+      }
+    },
+    "parameters": {
+      "appPath": {"name": "app", "in": "path", "required": true, "description": "The application name", "schema": {"type": "string"}},
+      "namePath": {"name": "name", "in": "path", "required": true, "description": "The config item name", "schema": {"type": "string"}},
+      "proposalPath": {"name": "id", "in": "path", "required": true, "description": "The proposal id", "schema": {"type": "integer", "format": "int64"}},
+      "prefixPath": {"name": "prefix", "in": "path", "required": true, "description": "A dotted name prefix (like db.primary)", "schema": {"type": "string"}},
+      "appQuery": {"name": "app", "in": "query", "required": true, "description": "The application name", "schema": {"type": "string"}},
+      "machineQuery": {"name": "machine", "in": "query", "description": "The machine name", "schema": {"type": "string"}},
+      "limitQuery": {"name": "limit", "in": "query", "description": "The most items to return (all of them if there isn't a limit)", "schema": {"type": "integer", "minimum": 1, "maximum": 1000}},
+      "cursorQuery": {"name": "cursor", "in": "query", "description": "The next cursor from the previous page", "schema": {"type": "string"}},
+      "prefixQuery": {"name": "prefix", "in": "query", "description": "Only items with names that start with the prefix", "schema": {"type": "string"}},
+      "containsQuery": {"name": "contains", "in": "query", "description": "Only items with values that contain the string", "schema": {"type": "string"}},
+      "machinesQuery": {"name": "machine", "in": "query", "description": "Only items for these machines (leave it blank for items without a machine)", "schema": {"type": "array", "items": {"type": "string"}}, "explode": true},
+      "labelQuery": {"name": "label", "in": "query", "description": "Only items with the label (like pii, or owner:payments for a value)", "schema": {"type": "array", "items": {"type": "string"}}, "explode": true},
+      "sortQuery": {"name": "sort", "in": "query", "description": "The sort order, with a - prefix for descending order (defaults to application)", "schema": {"type": "string", "enum": ["application", "-application", "name", "-name", "updated", "-updated"]}}
+    },
+    "requestBodies": {
+      "Review": {
+        "content": {"application/json": {"schema": {"type": "object", "properties": {"comment": {"type": "string"}}}}}
+      },
+      "ConfigItem": {
+        "required": true,
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ConfigItem"}}}
+      }
+    },
+    "responses": {
+      "ConfigItem": {
+        "description": "A config item",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ConfigItemResponse"}}}
+      },
+      "ConfigItems": {
+        "description": "A list of config items",
+        "content": {
+          "application/json": {
+            "schema": {
+              "allOf": [
+                {"$ref": "#/components/schemas/ConfigResponse"},
+                {"properties": {"data": {"type": "array", "items": {"$ref": "#/components/schemas/ConfigItem"}}}}
+              ]
+            }
+          }
+        }
+      },
+      "Applications": {
+        "description": "A list of application names",
+        "content": {
+          "application/json": {
+            "schema": {
+              "allOf": [
+                {"$ref": "#/components/schemas/ConfigResponse"},
+                {"properties": {"data": {"type": "array", "items": {"type": "string"}}}}
+              ]
+            }
+          }
+        }
+      },
+      "Proposal": {
+        "description": "A proposal (protected applications get one instead of the change)",
+        "headers": {
+          "Location": {"description": "The proposal's path (when it's proposed)", "schema": {"type": "string"}}
+        },
+        "content": {
+          "application/json": {
+            "schema": {
+              "allOf": [
+                {"$ref": "#/components/schemas/ConfigResponse"},
+                {"properties": {"data": {"$ref": "#/components/schemas/ChangeProposal"}}}
+              ]
+            }
+          }
+        }
+      },
+      "Error": {
+        "description": "An error (see ErrorCode)",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ConfigResponse"}}}
+      }
+    }
+  }
+}
+`
+
+//	Gets the OpenAPI 3 document describing the HTTP API
+func GetOpenAPI(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.Write([]byte(openAPIDocument))
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//	Collects every $ref in part of the OpenAPI document
+func collectTestOpenAPIRefs(node interface{}, refs map[string]bool) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				refs[ref] = true
+				continue
+			}
+			collectTestOpenAPIRefs(child, refs)
+		}
+	case []interface{}:
+		for _, child := range value {
+			collectTestOpenAPIRefs(child, refs)
+		}
+	}
+}
+
+//	The OpenAPI document should be valid JSON and every $ref should point at a component
+func TestGetOpenAPI_RefsResolve(t *testing.T) {
+	//	Arrange
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	rw := httptest.NewRecorder()
+
+	//	Act
+	GetOpenAPI(rw, req)
+
+	//	Assert
+	if !strings.HasPrefix(rw.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("The document should be JSON: %s", rw.Header().Get("Content-Type"))
+	}
+
+	document := map[string]interface{}{}
+	if err := json.NewDecoder(rw.Body).Decode(&document); err != nil {
+		t.Fatalf("The document isn't valid JSON: %s", err)
+	}
+
+	refs := map[string]bool{}
+	collectTestOpenAPIRefs(document, refs)
+
+	for ref := range refs {
+		var node interface{} = document
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			parent, ok := node.(map[string]interface{})
+			if !ok {
+				node = nil
+				break
+			}
+			node = parent[part]
+		}
+
+		if node == nil {
+			t.Errorf("%s doesn't point at anything", ref)
+		}
+	}
+}
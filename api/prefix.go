@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/formats"
+	"github.com/gorilla/mux"
+)
+
+//	A request to move a subtree of config items
+type moveRequest struct {
+	To string `json:"to"`
+}
+
+//	Lists the config items for the application in the path with the prefix in
+//	the path as their name, or under it (like db.primary.host under db.primary)
+func GetAppConfigTree(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	items, err := ds.ListPrefix(vars["app"], vars["prefix"])
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	sendDataResponse(rw, "Config items found", items)
+}
+
+//	Removes the config items for the application in the path under the
+//	prefix in the path
+func DeleteAppConfigTree(rw http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+
+	if err := checkUnprotected(vars["app"]); err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	removed, err := ds.RemovePrefix(vars["app"], vars["prefix"])
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	if len(removed) == 0 {
+		sendErrorResponse(rw, fmt.Errorf("No config items found under '%s'", vars["prefix"]), http.StatusNotFound)
+		return
+	}
+
+	for _, item := range removed {
+		WsHub.publish(ds, "Removed", item)
+	}
+
+	sendDataResponse(rw, "Config items removed", removed)
+}
+
+//	Moves the config items for the application in the path under the prefix in
+//	the path to the prefix in the request (like {"to": "db.secondary"})
+func MoveAppConfigTree(rw http.ResponseWriter, req *http.Request) {
+	//	req.Body is a ReadCloser -- we need to remember to close it:
+	defer req.Body.Close()
+
+	vars := mux.Vars(req)
+
+	if err := checkUnprotected(vars["app"]); err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	Decode the request:
+	request := moveRequest{}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	moved, replaced, err := ds.RenamePrefix(vars["app"], vars["prefix"], request.To)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	for _, item := range replaced {
+		WsHub.publish(ds, "Removed", item)
+	}
+
+	for _, item := range moved {
+		WsHub.publish(ds, "Updated", item)
+	}
+
+	sendDataResponse(rw, "Config items moved", moved)
+}
+
+//	Gets the effective config items for the application in the path (one item
+//	per name).  Query parameters:
+//
+//	machine: the machine name
+//	prefix: only items with the prefix as their name, or under it
+//	label: only items with the label (like pii or owner:payments -- repeat it for more labels)
+//	nest: if true, the items are nested into an object by their dotted names
+func ResolveAppConfig(rw http.ResponseWriter, req *http.Request) {
+	application := mux.Vars(req)["app"]
+
+	nest, err := getBoolParameter(req, "nest")
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	resolved, err := getResolvedConfig(ds, application, req.URL.Query().Get("machine"), req.URL.Query().Get("prefix"), getLabelParameters(req))
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !nest {
+		sendDataResponse(rw, "Config items found", resolved)
+		return
+	}
+
+	tree, err := formats.Nest(resolved)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	sendDataResponse(rw, "Config items found", tree)
+}
+
+//	Gets the effective config items for the application and machine, only
+//	keeping the ones under the prefix (if there is one) with all of the labels
+func getResolvedConfig(ds datastores.ConfigService, application, machine, prefix string, labels map[string]string) ([]datastores.ConfigItem, error) {
+	configItems, err := ds.GetAllForApplication(application)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := datastores.ResolveConfigItems(configItems, application, machine)
+	if prefix == "" && len(labels) == 0 {
+		return resolved, nil
+	}
+
+	retval := []datastores.ConfigItem{}
+	for _, item := range resolved {
+		if (prefix == "" || datastores.IsInConfigSubtree(item.Name, prefix)) && datastores.HasConfigLabels(item, labels) {
+			retval = append(retval, item)
+		}
+	}
+
+	return retval, nil
+}
+
+//	Gets a true / false query parameter (false if it isn't there)
+func getBoolParameter(req *http.Request, name string) (bool, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	retval, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("The %s parameter should be true or false", name)
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+//	Sends a request through a router with the prefix routes
+func getTestTreeResponse(t *testing.T, method, target, body string, data interface{}) (*httptest.ResponseRecorder, datastores.ConfigResponse) {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/apps/{app}/tree/{prefix}", GetAppConfigTree).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/tree/{prefix}", DeleteAppConfigTree).Methods("DELETE")
+	router.HandleFunc("/v2/apps/{app}/tree/{prefix}/move", MoveAppConfigTree).Methods("POST")
+	router.HandleFunc("/v2/apps/{app}/resolved", ResolveAppConfig).Methods("GET")
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	response := datastores.ConfigResponse{Data: data}
+	if err := json.NewDecoder(rw.Body).Decode(&response); err != nil {
+		t.Fatalf("The response isn't valid JSON: %s", err)
+	}
+
+	return rw, response
+}
+
+//	Moving a subtree should rename the items, and resolving it should nest them
+func TestMoveAppConfigTree_Resolve_Nested(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "db.primary.host", Value: "db01.example.com"},
+		{Application: "billing", Name: "db.primary.port", Value: "3306"},
+		{Application: "billing", Name: "db.primary.host", Machine: "WEB01", Value: "db02.example.com"},
+		{Application: "billing", Name: "db.primaryhost", Value: "db09.example.com"},
+	} {
+		if _, err := ds.Set(item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	//	Act
+	moved := []datastores.ConfigItem{}
+	rw, response := getTestTreeResponse(t, "POST", "/v2/apps/billing/tree/db.primary/move", `{"to":"db.secondary"}`, &moved)
+	if rw.Code != http.StatusOK || len(moved) != 3 {
+		t.Fatalf("Move should have moved 3 items: %d %+v", rw.Code, response)
+	}
+
+	tree := map[string]interface{}{}
+	rw, response = getTestTreeResponse(t, "GET", "/v2/apps/billing/resolved?machine=WEB01&prefix=db.secondary&nest=true", "", &tree)
+
+	//	Assert
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Resolve failed: %d %+v", rw.Code, response)
+	}
+
+	secondary, _ := tree["db"].(map[string]interface{})["secondary"].(map[string]interface{})
+	if len(tree) != 1 || secondary["host"] != "db02.example.com" || secondary["port"] != "3306" {
+		t.Errorf("Resolve should have nested the moved items: %v", tree)
+	}
+}
+
+//	Resolving should include the default (*) items the application doesn't override
+func TestResolveAppConfig_Defaults_Included(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	for _, item := range []datastores.ConfigItem{
+		{Application: "*", Name: "LogLevel", Value: "info"},
+		{Application: "*", Name: "Timeout", Value: "30"},
+		{Application: "billing", Name: "Timeout", Value: "60"},
+	} {
+		if _, err := ds.Set(item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	//	Act
+	resolved := []datastores.ConfigItem{}
+	rw, response := getTestTreeResponse(t, "GET", "/v2/apps/billing/resolved", "", &resolved)
+
+	//	Assert
+	if rw.Code != http.StatusOK || len(resolved) != 2 {
+		t.Fatalf("Resolve should have returned 2 items: %d %+v", rw.Code, response)
+	}
+
+	if resolved[0].Application != "*" || resolved[0].Value != "info" || resolved[1].Application != "billing" || resolved[1].Value != "60" {
+		t.Errorf("Resolve should have included the default item: %+v", resolved)
+	}
+}
+
+//	Removing a subtree that doesn't exist should be a 404
+func TestDeleteAppConfigTree_NotFound(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	Act
+	rw, response := getTestTreeResponse(t, "DELETE", "/v2/apps/billing/tree/db", "", nil)
+
+	//	Assert
+	if rw.Code != http.StatusNotFound || response.Code != datastores.CodeNotFound {
+		t.Errorf("Should have returned a not_found 404: %d %+v", rw.Code, response)
+	}
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+)
+
+var (
+	//	The application name patterns that need changes approved
+	protectedApplications   []string
+	protectedApplicationsMx sync.RWMutex
+)
+
+//	A review of a proposal
+type reviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+//	SetProtectedApplications sets the application name patterns (like prod-*)
+//	that need a second person to approve changes
+func SetProtectedApplications(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Protected application pattern '%s' isn't valid: %v", pattern, err)
+		}
+	}
+
+	protectedApplicationsMx.Lock()
+	defer protectedApplicationsMx.Unlock()
+	protectedApplications = patterns
+	return nil
+}
+
+//	IsProtectedApplication returns true if changes to the application need to
+//	be approved.  The default (*) application is protected if any application
+//	is, since its items are part of every application's config
+func IsProtectedApplication(application string) bool {
+	protectedApplicationsMx.RLock()
+	defer protectedApplicationsMx.RUnlock()
+
+	if application == "*" && len(protectedApplications) > 0 {
+		return true
+	}
+
+	for _, pattern := range protectedApplications {
+		if matched, _ := path.Match(pattern, application); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+//	Makes sure none of the applications are protected.  Changes that can't be
+//	proposed (like imports and moves) aren't allowed for protected applications
+func checkUnprotected(applications ...string) error {
+	for _, application := range applications {
+		if IsProtectedApplication(application) {
+			return &datastores.ConfigError{
+				Code:    datastores.CodeForbidden,
+				Message: fmt.Sprintf("Changes to '%s' need to be approved: set or remove single items to propose them", application)}
+		}
+	}
+
+	return nil
+}
+
+//	Makes sure a set request with an id doesn't change an item in a protected
+//	application.  Setting an item by its id can move it to another application,
+//	so the stored item's application has to be unprotected too
+func checkStoredUnprotected(ds datastores.ConfigService, request datastores.ConfigItem) error {
+	if request.Id == 0 {
+		return nil
+	}
+
+	items, err := ds.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Id == request.Id {
+			return checkUnprotected(item.Application)
+		}
+	}
+
+	return nil
+}
+
+//	Proposes a change to a protected application instead of making it, and
+//	lets the caller know where to find the proposal
+func sendProposal(rw http.ResponseWriter, req *http.Request, action string, item datastores.ConfigItem) {
+	//	Only an authenticated user can propose a change:
+	user, err := getRequestUser(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	request := datastores.ChangeProposal{
+		Action:     action,
+		Item:       item,
+		ProposedBy: user}
+
+	//	A retried request shouldn't propose the same change again:
+	proposal, found, err := findPendingProposal(ds, request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	if !found {
+		proposal, err = ds.ProposeChange(request)
+		if err != nil {
+			sendErrorResponse(rw, err, http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("[INFO] Proposal %d (%s %s/%s) proposed by %s\n", proposal.Id, proposal.Action, item.Application, item.Name, proposal.ProposedBy)
+	}
+
+	rw.Header().Set("Location", fmt.Sprintf("/v2/proposals/%d", proposal.Id))
+	sendStatusResponse(rw, http.StatusAccepted, "Change proposed for approval", proposal)
+}
+
+//	Finds a pending proposal by the same user for the same change (so setting
+//	or removing an item stays idempotent when it's proposed)
+func findPendingProposal(ds datastores.ConfigService, request datastores.ChangeProposal) (datastores.ChangeProposal, bool, error) {
+	proposals, err := ds.GetProposals()
+	if err != nil {
+		return datastores.ChangeProposal{}, false, err
+	}
+
+	for _, proposal := range proposals {
+		if proposal.Status == datastores.ProposalPending && proposal.ProposedBy == request.ProposedBy && proposal.Action == request.Action && isSameProposedItem(proposal.Item, request.Item) {
+			return proposal, true, nil
+		}
+	}
+
+	return datastores.ChangeProposal{}, false, nil
+}
+
+//	Returns true if the items make the same change (their ids don't matter)
+func isSameProposedItem(a, b datastores.ConfigItem) bool {
+	if a.Application != b.Application || a.Machine != b.Machine || a.Name != b.Name || a.Value != b.Value || a.Description != b.Description {
+		return false
+	}
+
+	if (a.Expires == nil) != (b.Expires == nil) || (a.Expires != nil && !a.Expires.Equal(*b.Expires)) {
+		return false
+	}
+
+	if len(a.Labels) != len(b.Labels) {
+		return false
+	}
+
+	for name, value := range a.Labels {
+		if other, ok := b.Labels[name]; !ok || other != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+//	Lists proposals, oldest first.  Query parameters:
+//
+//	status: only proposals with the status (pending, approved, rejected or failed)
+//	application: only proposals for the application
+func GetProposals(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	proposals, err := ds.GetProposals()
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	retval := []datastores.ChangeProposal{}
+	for _, proposal := range proposals {
+		if query.Get("status") != "" && proposal.Status != query.Get("status") {
+			continue
+		}
+
+		if query.Get("application") != "" && proposal.Item.Application != query.Get("application") {
+			continue
+		}
+
+		retval = append(retval, proposal)
+	}
+
+	sendDataResponse(rw, "Proposals found", retval)
+}
+
+//	Gets the proposal with the id in the path
+func GetProposal(rw http.ResponseWriter, req *http.Request) {
+	id, err := getProposalId(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	proposal, err := ds.GetProposal(id)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	sendDataResponse(rw, "Proposal found", proposal)
+}
+
+//	Approves the proposal with the id in the path and makes the change.  The
+//	body can have a comment:
+//
+//	{ "comment": "Looks good" }
+func ApproveProposal(rw http.ResponseWriter, req *http.Request) {
+	id, review, err := getReviewRequest(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Only an authenticated user can review a proposal:
+	user, err := getRequestUser(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	proposal, item, err := ds.ApproveProposal(id, user, review.Comment)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[INFO] Proposal %d (%s %s/%s) approved by %s\n", proposal.Id, proposal.Action, item.Application, item.Name, proposal.ReviewedBy)
+
+	if proposal.Action == datastores.ProposalRemove {
+		WsHub.publish(ds, "Removed", item)
+	} else {
+		WsHub.publish(ds, "Updated", item)
+	}
+
+	sendDataResponse(rw, "Proposal approved", proposal)
+}
+
+//	Rejects the proposal with the id in the path.  The body can have a comment:
+//
+//	{ "comment": "Not during the sale" }
+func RejectProposal(rw http.ResponseWriter, req *http.Request) {
+	id, review, err := getReviewRequest(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Only an authenticated user can review a proposal:
+	user, err := getRequestUser(req)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	proposal, err := ds.RejectProposal(id, user, review.Comment)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[INFO] Proposal %d (%s %s/%s) rejected by %s\n", proposal.Id, proposal.Action, proposal.Item.Application, proposal.Item.Name, proposal.ReviewedBy)
+
+	sendDataResponse(rw, "Proposal rejected", proposal)
+}
+
+//	Gets the proposal id in the path
+func getProposalId(req *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("The id should be a number")
+	}
+
+	return id, nil
+}
+
+//	Gets the proposal id in the path and the (optional) review in the body
+func getReviewRequest(req *http.Request) (int64, reviewRequest, error) {
+	//	req.Body is a ReadCloser -- we need to remember to close it:
+	defer req.Body.Close()
+
+	review := reviewRequest{}
+	id, err := getProposalId(req)
+	if err != nil {
+		return id, review, err
+	}
+
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil && err != io.EOF {
+		return id, review, err
+	}
+
+	return id, review, nil
+}
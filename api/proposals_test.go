@@ -0,0 +1,319 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//	Lets the users authenticate with the password "secret"
+func setTestApprovalUsers(t *testing.T, users ...string) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %s", err)
+	}
+
+	auth := ApprovalAuth{Users: make(map[string]string)}
+	for _, user := range users {
+		auth.Users[user] = string(hash)
+	}
+
+	if err := SetApprovalAuth(auth); err != nil {
+		t.Fatalf("SetApprovalAuth failed: %s", err)
+	}
+}
+
+//	Sends a request as the user through a router with the proposal routes
+func reviewTestProposal(user, target string) (*httptest.ResponseRecorder, datastores.ChangeProposal) {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/proposals/{id}/approve", ApproveProposal).Methods("POST")
+	router.HandleFunc("/v2/proposals/{id}/reject", RejectProposal).Methods("POST")
+
+	req := httptest.NewRequest("POST", target, strings.NewReader(`{"comment":"Reviewed"}`))
+	req.SetBasicAuth(user, "secret")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	proposal := datastores.ChangeProposal{}
+	response := datastores.ConfigResponse{Data: &proposal}
+	json.NewDecoder(rw.Body).Decode(&response)
+
+	return rw, proposal
+}
+
+//	Setting an item in a protected application should propose the change, and
+//	only make it when someone else approves it
+func TestSetConfig_Protected_Proposed(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	setTestApprovalUsers(t, "alice", "bob")
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	req := httptest.NewRequest("POST", "/config/set", strings.NewReader(`{"application":"prod-billing","name":"Timeout","value":"60"}`))
+	req.SetBasicAuth("bob", "secret")
+	rw := httptest.NewRecorder()
+
+	//	Act
+	SetConfig(rw, req)
+
+	//	Assert
+	proposal := datastores.ChangeProposal{}
+	response := datastores.ConfigResponse{Data: &proposal}
+	json.NewDecoder(rw.Body).Decode(&response)
+	if rw.Code != http.StatusAccepted || proposal.Status != datastores.ProposalPending || proposal.ProposedBy != "bob" || rw.Header().Get("Location") != fmt.Sprintf("/v2/proposals/%d", proposal.Id) {
+		t.Fatalf("Should have proposed the change: %d %+v", rw.Code, response)
+	}
+
+	ds := datastores.GetConfigDatastore()
+	if _, err := ds.Get(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"}); !datastores.IsNotFound(err) {
+		t.Errorf("Shouldn't have made the change before it was approved: %v", err)
+	}
+
+	selfRw, _ := reviewTestProposal("bob", fmt.Sprintf("/v2/proposals/%d/approve", proposal.Id))
+	if selfRw.Code != http.StatusForbidden {
+		t.Errorf("The proposer shouldn't be able to approve the change: %d", selfRw.Code)
+	}
+
+	approveRw, approved := reviewTestProposal("alice", fmt.Sprintf("/v2/proposals/%d/approve", proposal.Id))
+	if approveRw.Code != http.StatusOK || approved.Status != datastores.ProposalApproved || approved.ReviewedBy != "alice" || approved.Comment != "Reviewed" {
+		t.Fatalf("Should have approved the change: %d %+v", approveRw.Code, approved)
+	}
+
+	if item, err := ds.Get(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"}); err != nil || item.Value != "60" {
+		t.Errorf("Should have made the approved change: %+v / %v", item, err)
+	}
+}
+
+//	Rejecting a proposal shouldn't make the change
+func TestRejectProposal_NotApplied(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	setTestApprovalUsers(t, "alice", "bob")
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	ds := datastores.GetConfigDatastore()
+	ds.Set(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "30"})
+	proposal, err := ds.ProposeChange(datastores.ChangeProposal{
+		Action:     datastores.ProposalRemove,
+		Item:       datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"},
+		ProposedBy: "bob"})
+	if err != nil {
+		t.Fatalf("ProposeChange failed: %s", err)
+	}
+
+	//	Act
+	rw, rejected := reviewTestProposal("alice", fmt.Sprintf("/v2/proposals/%d/reject", proposal.Id))
+
+	//	Assert
+	if rw.Code != http.StatusOK || rejected.Status != datastores.ProposalRejected {
+		t.Errorf("Should have rejected the change: %d %+v", rw.Code, rejected)
+	}
+
+	if item, err := ds.Get(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"}); err != nil || item.Value != "30" {
+		t.Errorf("Shouldn't have made the rejected change: %+v / %v", item, err)
+	}
+}
+
+//	Importing into a protected application should be forbidden, since imports
+//	can't be proposed
+func TestImportConfig_Protected_Forbidden(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	req := httptest.NewRequest("POST", "/v2/import?app=prod-billing&format=json", strings.NewReader(`{"Timeout":"60"}`))
+	rw := httptest.NewRecorder()
+
+	//	Act
+	ImportConfig(rw, req)
+
+	//	Assert
+	response := datastores.ConfigResponse{}
+	json.NewDecoder(rw.Body).Decode(&response)
+	if rw.Code != http.StatusForbidden {
+		t.Errorf("Should have been forbidden: %d %+v", rw.Code, response)
+	}
+}
+
+//	Proposing the same change again (like a retried request) should return
+//	the pending proposal instead of making another one
+func TestPutAppConfigItem_Protected_ProposedOnce(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	setTestApprovalUsers(t, "alice", "bob")
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/apps/{app}/config/{name}", PutAppConfigItem).Methods("PUT")
+
+	put := func(body string) datastores.ChangeProposal {
+		req := httptest.NewRequest("PUT", "/v2/apps/prod-billing/config/Timeout", strings.NewReader(body))
+		req.SetBasicAuth("bob", "secret")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+
+		proposal := datastores.ChangeProposal{}
+		response := datastores.ConfigResponse{Data: &proposal}
+		json.NewDecoder(rw.Body).Decode(&response)
+		if rw.Code != http.StatusAccepted {
+			t.Fatalf("Should have proposed the change: %d %+v", rw.Code, response)
+		}
+
+		return proposal
+	}
+
+	//	Act
+	first := put(`{"value":"60"}`)
+	retried := put(`{"value":"60"}`)
+	other := put(`{"value":"90"}`)
+
+	//	Assert
+	if retried.Id != first.Id {
+		t.Errorf("Should have returned the pending proposal: %d / %d", first.Id, retried.Id)
+	}
+
+	if other.Id == first.Id {
+		t.Errorf("A different change should have been proposed separately: %d", other.Id)
+	}
+}
+
+//	Proposing a change without an authenticated user should be forbidden (and
+//	not propose anything)
+func TestSetConfig_Protected_Unauthenticated_Forbidden(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	setTestApprovalUsers(t, "alice", "bob")
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	req := httptest.NewRequest("POST", "/config/set", strings.NewReader(`{"application":"prod-billing","name":"Timeout","value":"60"}`))
+	req.SetBasicAuth("bob", "guessed")
+	rw := httptest.NewRecorder()
+
+	//	Act
+	SetConfig(rw, req)
+
+	//	Assert
+	response := datastores.ConfigResponse{}
+	json.NewDecoder(rw.Body).Decode(&response)
+	if rw.Code != http.StatusForbidden {
+		t.Errorf("Should have been forbidden: %d %+v", rw.Code, response)
+	}
+
+	ds := datastores.GetConfigDatastore()
+	if proposals, err := ds.GetProposals(); err != nil || len(proposals) != 0 {
+		t.Errorf("Shouldn't have proposed the change: %+v / %v", proposals, err)
+	}
+}
+
+//	Setting an item by its id shouldn't move it out of a protected application
+func TestSetConfig_Protected_MovedById_Forbidden(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	stored, err := ds.Set(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "30"})
+	if err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	req := httptest.NewRequest("POST", "/config/set", strings.NewReader(fmt.Sprintf(`{"id":%d,"application":"scratch","name":"Timeout","value":"60"}`, stored.Id)))
+	rw := httptest.NewRecorder()
+
+	//	Act
+	SetConfig(rw, req)
+
+	//	Assert
+	response := datastores.ConfigResponse{}
+	json.NewDecoder(rw.Body).Decode(&response)
+	if rw.Code != http.StatusForbidden {
+		t.Errorf("Should have been forbidden: %d %+v", rw.Code, response)
+	}
+
+	if _, err := ds.Get(datastores.ConfigItem{Application: "scratch", Name: "Timeout"}); !datastores.IsNotFound(err) {
+		t.Errorf("Shouldn't have set the item in the other application: %v", err)
+	}
+}
+
+//	The default (*) application should be protected when any application is,
+//	since its items are part of every application's config
+func TestSetConfig_Protected_Default_Proposed(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	setTestApprovalUsers(t, "bob")
+	defer SetApprovalAuth(ApprovalAuth{})
+
+	SetProtectedApplications([]string{"prod-*"})
+	defer SetProtectedApplications(nil)
+
+	req := httptest.NewRequest("POST", "/config/set", strings.NewReader(`{"application":"*","name":"Timeout","value":"60"}`))
+	req.SetBasicAuth("bob", "secret")
+	rw := httptest.NewRecorder()
+
+	//	Act
+	SetConfig(rw, req)
+
+	//	Assert
+	if rw.Code != http.StatusAccepted {
+		t.Errorf("Should have proposed the change: %d", rw.Code)
+	}
+
+	ds := datastores.GetConfigDatastore()
+	if _, err := ds.Get(datastores.ConfigItem{Application: "*", Name: "Timeout"}); !datastores.IsNotFound(err) {
+		t.Errorf("Shouldn't have made the change before it was approved: %v", err)
+	}
+
+	if IsProtectedApplication("scratch") {
+		t.Errorf("Other applications shouldn't be protected")
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+//	AddRoutes sets up the API routes, and serves the UI with the given handler.
+//	Every route needs to be described in the OpenAPI document (see openapi.go)
+func AddRoutes(router *mux.Router, ui http.Handler) {
+	//	Setup our routes
+	router.HandleFunc("/", ShowUI)
+	router.HandleFunc("/config/get", GetConfig)
+	router.HandleFunc("/config/set", SetConfig)
+	router.HandleFunc("/config/remove", RemoveConfig)
+	router.HandleFunc("/config/getall", GetAllConfig)
+	router.HandleFunc("/config/getallforapp", GetAllConfigForApp)
+	router.HandleFunc("/config/search", SearchConfig).Methods("GET")
+	router.HandleFunc("/applications/getall", GetAllApplications)
+	router.HandleFunc("/config/export", ExportConfig).Methods("GET")
+	router.HandleFunc("/config/import", ImportConfig).Methods("POST")
+	router.Handle("/config/watch", WatchHandler{H: WsHub}).Methods("GET")
+
+	//	RESTful (v2) routes
+	router.HandleFunc("/v2/apps", ListApplications).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config", ListAppConfig).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", GetAppConfigItem).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", PutAppConfigItem).Methods("PUT")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", DeleteAppConfigItem).Methods("DELETE")
+	router.HandleFunc("/v2/apps/{app}/tree/{prefix}", GetAppConfigTree).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/tree/{prefix}", DeleteAppConfigTree).Methods("DELETE")
+	router.HandleFunc("/v2/apps/{app}/tree/{prefix}/move", MoveAppConfigTree).Methods("POST")
+	router.HandleFunc("/v2/apps/{app}/resolved", ResolveAppConfig).Methods("GET")
+	router.HandleFunc("/v2/schedule", GetScheduledChanges).Methods("GET")
+	router.HandleFunc("/v2/schedule", ScheduleChange).Methods("POST")
+	router.HandleFunc("/v2/schedule/{id}", CancelScheduledChange).Methods("DELETE")
+	router.HandleFunc("/v2/proposals", GetProposals).Methods("GET")
+	router.HandleFunc("/v2/proposals/{id}", GetProposal).Methods("GET")
+	router.HandleFunc("/v2/proposals/{id}/approve", ApproveProposal).Methods("POST")
+	router.HandleFunc("/v2/proposals/{id}/reject", RejectProposal).Methods("POST")
+
+	//	Websocket connections
+	router.Handle("/ws", WsHandler{H: WsHub})
+
+	//	Server-Sent Events (for clients that can't use WebSockets)
+	router.Handle("/events", EventsHandler{H: WsHub}).Methods("GET")
+
+	//	GraphQL queries, mutations and subscriptions
+	router.Handle("/graphql", NewGraphQLHandler(WsHub))
+
+	//	The OpenAPI document describing all of these routes
+	router.HandleFunc("/openapi.json", GetOpenAPI).Methods("GET")
+
+	//	Send JSON errors for anything else (like the rest of the API)
+	router.NotFoundHandler = http.HandlerFunc(NotFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(MethodNotAllowed)
+
+	//	The UI (see ShowUI)
+	router.PathPrefix("/ui").Handler(http.StripPrefix("/ui", ui))
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+)
+
+//	Gets the OpenAPI document's paths (and their operations) from the router
+func getTestOpenAPIPaths(t *testing.T, router *mux.Router) map[string]map[string]interface{} {
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("/openapi.json should have returned 200: %v", rw.Code)
+	}
+
+	document := struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}{}
+	if err := json.NewDecoder(rw.Body).Decode(&document); err != nil {
+		t.Fatalf("/openapi.json isn't valid JSON: %s", err)
+	}
+
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Fatalf("/openapi.json should be an OpenAPI 3 document: %s", document.OpenAPI)
+	}
+
+	return document.Paths
+}
+
+//	Every route registered by AddRoutes should be described in the OpenAPI document
+func TestAddRoutes_AllRoutesDescribed(t *testing.T) {
+	//	Arrange
+	router := mux.NewRouter()
+	AddRoutes(router, http.NotFoundHandler())
+	paths := getTestOpenAPIPaths(t, router)
+
+	//	Act
+	registered := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		registered[path] = true
+
+		operations, described := paths[path]
+		if !described {
+			t.Errorf("%s is registered but isn't described in /openapi.json", path)
+			return nil
+		}
+
+		//	Routes without methods need at least one operation described:
+		methods, err := route.GetMethods()
+		if err != nil {
+			if len(operations) == 0 {
+				t.Errorf("%s doesn't have any operations in /openapi.json", path)
+			}
+			return nil
+		}
+
+		for _, method := range methods {
+			if _, ok := operations[strings.ToLower(method)]; !ok {
+				t.Errorf("%s %s is registered but isn't described in /openapi.json", method, path)
+			}
+		}
+
+		return nil
+	})
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Walking the routes failed: %s", err)
+	}
+
+	for path := range paths {
+		if !registered[path] {
+			t.Errorf("%s is described in /openapi.json but isn't registered", path)
+		}
+	}
+}
+
+//	Unknown paths and unsupported methods should get the same JSON errors as
+//	the rest of the API
+func TestAddRoutes_Errors_JSON(t *testing.T) {
+	//	Arrange
+	router := mux.NewRouter()
+	AddRoutes(router, http.NotFoundHandler())
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{"GET", "/config/nothing-here", http.StatusNotFound, "not_found"},
+		{"POST", "/v2/apps", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"PATCH", "/v2/apps/billing/config/Timeout", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		rw := httptest.NewRecorder()
+
+		//	Act
+		router.ServeHTTP(rw, req)
+
+		//	Assert
+		response := datastores.ConfigResponse{}
+		if err := json.NewDecoder(rw.Body).Decode(&response); err != nil {
+			t.Errorf("%s %s should have returned JSON: %s", test.method, test.path, err)
+			continue
+		}
+
+		if rw.Code != test.status || response.Status != test.status || response.Code != test.code || response.Message == "" {
+			t.Errorf("%s %s should have returned %d/%s: %d %+v", test.method, test.path, test.status, test.code, rw.Code, response)
+		}
+
+		if !strings.HasPrefix(rw.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("%s %s should have been JSON: %s", test.method, test.path, rw.Header().Get("Content-Type"))
+		}
+	}
+}
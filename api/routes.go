@@ -32,22 +32,15 @@ func GetConfig(rw http.ResponseWriter, req *http.Request) {
 	//	Get the current datastore:
 	ds := datastores.GetConfigDatastore()
 
-	//	Send the request to the datastore and get a response:
+	//	Send the request to the datastore and get a response
+	//	(if there isn't an item, this is a not found error):
 	response, err := ds.Get(request)
 	if err != nil {
 		sendErrorResponse(rw, err, http.StatusInternalServerError)
 		return
 	}
 
-	//	If we found an item, return it (otherwise, return an empty item):
-	configItem := datastores.ConfigItem{}
-	if response.Name != "" {
-		configItem = response
-		sendDataResponse(rw, "Config item found", configItem)
-		return
-	}
-
-	sendDataResponse(rw, "No config item found with that application and name", configItem)
+	sendDataResponse(rw, "Config item found", response)
 }
 
 //	Set a specific config item
@@ -63,15 +56,27 @@ func SetConfig(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//	Changes to protected applications need to be approved first:
+	if IsProtectedApplication(request.Application) {
+		sendProposal(rw, req, datastores.ProposalSet, request)
+		return
+	}
+
 	//	Get the current datastore:
 	ds := datastores.GetConfigDatastore()
 
+	//	An item can't be moved out of a protected application by its id:
+	if err := checkStoredUnprotected(ds, request); err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
 	//	Send the request to the datastore and get a response:
 	response, err := ds.Set(request)
 	if err != nil {
 		sendErrorResponse(rw, err, http.StatusInternalServerError)
 	} else {
-		WsHub.Broadcast <- []byte(getWSResponse("Updated", response))
+		WsHub.publish(ds, "Updated", response)
 		sendDataResponse(rw, "Config item updated", response)
 	}
 }
@@ -89,6 +94,12 @@ func RemoveConfig(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	//	Changes to protected applications need to be approved first:
+	if IsProtectedApplication(request.Application) {
+		sendProposal(rw, req, datastores.ProposalRemove, request)
+		return
+	}
+
 	//	Get the current datastore:
 	ds := datastores.GetConfigDatastore()
 
@@ -97,12 +108,14 @@ func RemoveConfig(rw http.ResponseWriter, req *http.Request) {
 	if err != nil {
 		sendErrorResponse(rw, err, http.StatusInternalServerError)
 	} else {
-		WsHub.Broadcast <- []byte(getWSResponse("Removed", request))
+		WsHub.publish(ds, "Removed", request)
 		sendDataResponse(rw, "Config item removed", request)
 	}
 }
 
-//	Gets all config information for a given application
+//	Gets all config information for a given application (plus the default
+//	* application).  The list query parameters (see getListOptions) filter,
+//	sort and page the items
 func GetAllConfigForApp(rw http.ResponseWriter, req *http.Request) {
 	//	req.Body is a ReadCloser -- we need to remember to close it:
 	defer req.Body.Close()
@@ -115,47 +128,16 @@ func GetAllConfigForApp(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	//	Get the current datastore:
-	ds := datastores.GetConfigDatastore()
-
-	//	Send the request to the datastore and get a response:
-	configItems, err := ds.GetAllForApplication(request.Application)
-	if err != nil {
-		sendErrorResponse(rw, err, http.StatusInternalServerError)
-		return
-	}
-
-	//	If we found an item, return it (otherwise, return an empty array):
-	if len(configItems) > 0 {
-		sendDataResponse(rw, "Config items found", configItems)
-		return
-	}
-
-	sendDataResponse(rw, "No config items found with that application", configItems)
+	sendListResponse(rw, req, []string{request.Application, "*"}, "Config items found", "No config items found with that application")
 }
 
-//	Gets all config information
+//	Gets all config information.  The list query parameters
+//	(see getListOptions) filter, sort and page the items
 func GetAllConfig(rw http.ResponseWriter, req *http.Request) {
 	//	req.Body is a ReadCloser -- we need to remember to close it:
 	defer req.Body.Close()
 
-	//	Get the current datastore:
-	ds := datastores.GetConfigDatastore()
-
-	//	Send the request to the datastore and get a response:
-	configItems, err := ds.GetAll()
-	if err != nil {
-		sendErrorResponse(rw, err, http.StatusInternalServerError)
-		return
-	}
-
-	//	If we found an item, return it (otherwise, return an empty array):
-	if len(configItems) > 0 {
-		sendDataResponse(rw, "Config items found", configItems)
-		return
-	}
-
-	sendDataResponse(rw, "No config items found", configItems)
+	sendListResponse(rw, req, nil, "Config items found", "No config items found")
 }
 
 //	Gets all applications
@@ -182,41 +164,55 @@ func GetAllApplications(rw http.ResponseWriter, req *http.Request) {
 	sendDataResponse(rw, "No config items found", applications)
 }
 
-//	Used to send back an error:
+//	Used to send back an error.  Datastore errors use the status for their
+//	error code (see getErrorDetails) instead of the given status
 func sendErrorResponse(rw http.ResponseWriter, err error, code int) {
-	//	Our return value
-	response := datastores.ConfigResponse{
-		Status:  code,
-		Message: "Error: " + err.Error()}
+	status, errorCode, message := getErrorDetails(err, code)
 
-	//	Serialize to JSON & return the response:
-	rw.WriteHeader(code)
-	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
-	json.NewEncoder(rw).Encode(response)
+	//	Our return value
+	sendResponse(rw, datastores.ConfigResponse{
+		Status:  status,
+		Code:    errorCode,
+		Message: message})
 }
 
 //	Used to send back a response with data
 func sendDataResponse(rw http.ResponseWriter, message string, dataItems interface{}) {
+	sendStatusResponse(rw, http.StatusOK, message, dataItems)
+}
+
+//	Used to send back a response with data and a specific status code
+func sendStatusResponse(rw http.ResponseWriter, code int, message string, dataItems interface{}) {
 	//	Our return value
-	response := datastores.ConfigResponse{
-		Status:  http.StatusOK,
+	sendResponse(rw, datastores.ConfigResponse{
+		Status:  code,
 		Message: message,
-		Data:    dataItems}
+		Data:    dataItems})
+}
 
+//	Used to send back a response
+func sendResponse(rw http.ResponseWriter, response datastores.ConfigResponse) {
 	//	Serialize to JSON & return the response:
 	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(response.Status)
 	json.NewEncoder(rw).Encode(response)
 }
 
 //	Gets a JSON formatted WebSocket event response
 func getWSResponse(messageType string, item datastores.ConfigItem) string {
+	return getWSEvent(messageType, item, 0)
+}
+
+//	Gets a JSON formatted WebSocket event response with a sequence number
+func getWSEvent(messageType string, item datastores.ConfigItem, sequence int64) string {
 	//	Our default return value:
 	retval := ""
 
 	//	Our WebSocket return value
 	response := datastores.WebSocketResponse{
-		Data: item,
-		Type: messageType}
+		Data:     item,
+		Type:     messageType,
+		Sequence: sequence}
 
 	//	Serialize to JSON and return as a string:
 	responseBytes := new(bytes.Buffer)
@@ -226,3 +222,22 @@ func getWSResponse(messageType string, item datastores.ConfigItem) string {
 
 	return retval
 }
+
+//	Gets a JSON formatted WebSocket error response
+func getWSError(message string) string {
+	return getWSNotice("Error", message)
+}
+
+//	Gets a JSON formatted WebSocket response with a message (and no config item)
+func getWSNotice(messageType, message string) string {
+	response := datastores.WebSocketResponse{
+		Type:    messageType,
+		Message: message}
+
+	responseBytes, err := json.Marshal(&response)
+	if err != nil {
+		return ""
+	}
+
+	return string(responseBytes)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+)
+
+//	The default time between checks for scheduled changes
+const defaultScheduleInterval = 10 * time.Second
+
+//	Lists the scheduled changes that haven't been made yet, in the order
+//	they'll be made.  Use the application query parameter to only list the
+//	changes for one application
+func GetScheduledChanges(rw http.ResponseWriter, req *http.Request) {
+	application := req.URL.Query().Get("application")
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	changes, err := ds.GetScheduledChanges()
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	retval := []datastores.ScheduledChange{}
+	for _, change := range changes {
+		if application == "" || change.Application == application {
+			retval = append(retval, change)
+		}
+	}
+
+	sendDataResponse(rw, "Scheduled changes found", retval)
+}
+
+//	Schedules a config value change.  The body should contain the item and
+//	the time the change should be made:
+//
+//	{ "application": "billing", "name": "Maintenance", "value": "true", "effective": "2016-08-12T02:00:00Z" }
+func ScheduleChange(rw http.ResponseWriter, req *http.Request) {
+	//	req.Body is a ReadCloser -- we need to remember to close it:
+	defer req.Body.Close()
+
+	//	Decode the request:
+	request := datastores.ScheduledChange{}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	Scheduled changes aren't reviewed, so they can't be protected:
+	if err := checkUnprotected(request.Application); err != nil {
+		sendErrorResponse(rw, err, http.StatusForbidden)
+		return
+	}
+
+	//	A time that's already passed is most likely a mistake:
+	if !request.Effective.IsZero() && !request.Effective.After(time.Now()) {
+		sendErrorResponse(rw, fmt.Errorf("The effective time should be in the future"), http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	response, err := ds.ScheduleChange(request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Location", fmt.Sprintf("/v2/schedule/%d", response.Id))
+	sendStatusResponse(rw, http.StatusCreated, "Change scheduled", response)
+}
+
+//	Cancels the scheduled change with the id in the path
+func CancelScheduledChange(rw http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		sendErrorResponse(rw, fmt.Errorf("The id should be a number"), http.StatusBadRequest)
+		return
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response
+	//	(if there isn't a change, this is a not found error):
+	if _, err := ds.CancelScheduledChange(id); err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+//	ApplyScheduledChanges makes the scheduled changes that are due, and sends
+//	an Updated event for each item that changed.  Returns the updated items
+func (h *Hub) ApplyScheduledChanges() ([]datastores.ConfigItem, error) {
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	updated, err := ds.ApplyScheduledChanges(time.Now())
+	for _, item := range updated {
+		h.publish(ds, "Updated", item)
+	}
+
+	return updated, err
+}
+
+//	RunScheduler makes scheduled changes when they're due, checking at the
+//	interval (every 10 seconds if the interval isn't positive).  Changes that
+//	came due while the server was down are made right away.  It doesn't
+//	return, so run it in a goroutine
+func (h *Hub) RunScheduler(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultScheduleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		updated, err := h.ApplyScheduledChanges()
+		if err != nil {
+			log.Printf("[WARN] Can't make scheduled changes: %v\n", err)
+		}
+
+		if len(updated) > 0 {
+			log.Printf("[INFO] Made %d scheduled changes\n", len(updated))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	Scheduling a change in the past should be a validation error
+func TestScheduleChange_Past_BadRequest(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	body := `{"application":"billing","name":"Maintenance","value":"true","effective":"2016-08-12T02:00:00Z"}`
+	req := httptest.NewRequest("POST", "/v2/schedule", strings.NewReader(body))
+	rw := httptest.NewRecorder()
+
+	//	Act
+	ScheduleChange(rw, req)
+
+	//	Assert
+	response := datastores.ConfigResponse{}
+	json.NewDecoder(rw.Body).Decode(&response)
+	if rw.Code != http.StatusBadRequest {
+		t.Errorf("Should have been a bad request: %d %+v", rw.Code, response)
+	}
+}
+
+//	Scheduling a change should return where to find it
+func TestScheduleChange_Future_Created(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	body := `{"application":"billing","name":"Maintenance","value":"true","effective":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`
+	req := httptest.NewRequest("POST", "/v2/schedule", strings.NewReader(body))
+	rw := httptest.NewRecorder()
+
+	//	Act
+	ScheduleChange(rw, req)
+
+	//	Assert
+	change := datastores.ScheduledChange{}
+	response := datastores.ConfigResponse{Data: &change}
+	json.NewDecoder(rw.Body).Decode(&response)
+	if rw.Code != http.StatusCreated || change.Id == 0 || rw.Header().Get("Location") != fmt.Sprintf("/v2/schedule/%d", change.Id) {
+		t.Errorf("Should have scheduled the change: %d %+v %s", rw.Code, response, rw.Header().Get("Location"))
+	}
+}
+
+//	Changes that are due should be made, with an Updated event for each
+func TestHub_ApplyScheduledChanges_Updated(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	The API won't schedule a change in the past, but the datastore will
+	//	(like a change that came due while the server was down):
+	ds := datastores.GetConfigDatastore()
+	ds.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "true", Effective: time.Now().Add(-time.Minute)})
+	ds.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Banner", Value: "Sale", Effective: time.Now().Add(time.Hour)})
+
+	hub := NewHub()
+
+	//	Act
+	updated, err := hub.ApplyScheduledChanges()
+
+	//	Assert
+	if err != nil || len(updated) != 1 || updated[0].Name != "Maintenance" || updated[0].Value != "true" {
+		t.Fatalf("Should have made the due change: %+v / %v", updated, err)
+	}
+
+	waitForLog(hub, 1)
+	hub.logMx.RLock()
+	defer hub.logMx.RUnlock()
+	if len(hub.log) != 1 || hub.log[0].eventType != "Updated" || hub.log[0].item.Name != "Maintenance" {
+		t.Errorf("Should have sent an Updated event: %+v", hub.log)
+	}
+}
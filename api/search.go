@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Searches the names and values of config items.  The query parameters are:
+//
+//	q: the text (or regular expression) to search for
+//	match: substring (the default), regex or exact
+//	field: name or value (repeat it for both, which is the default)
+//	ignorecase: true to match without case
+//	application: only items for the application (repeat it for more applications)
+//	machine: only items for the machine (repeat it for more machines, and leave it blank for items without a machine)
+//	limit: the most items to return (all of them if there isn't a limit)
+//
+//	Secret values (like passwords) aren't searched
+func SearchConfig(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	options := datastores.SearchOptions{
+		Query:        query.Get("q"),
+		Match:        query.Get("match"),
+		Fields:       query["field"],
+		Applications: query["application"]}
+
+	if machines, ok := query["machine"]; ok {
+		options.Machines = machines
+	}
+
+	ignoreCase, err := getBoolParameter(req, "ignorecase")
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+	options.IgnoreCase = ignoreCase
+
+	if query.Get("limit") != "" {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 {
+			sendErrorResponse(rw, fmt.Errorf("The limit parameter should be a number from 1 to %d", datastores.MaxListLimit), http.StatusBadRequest)
+			return
+		}
+		options.Limit = limit
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	items, err := ds.Search(options)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	message := "Config items found"
+	if len(items) == 0 {
+		message = "No config items found"
+	}
+
+	sendDataResponse(rw, message, items)
+}
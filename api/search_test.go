@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	Searching should find items by value, but not secret values
+func TestSearchConfig_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	ds := datastores.GetConfigDatastore()
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "db.host", Value: "db01.example.com"},
+		{Application: "billing", Name: "db.password", Value: "db01.example.com"},
+		{Application: "reports", Name: "db.host", Value: "DB01.example.com"},
+	} {
+		if _, err := ds.Set(item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	query := url.Values{"q": {"db01.example.com"}, "ignorecase": {"true"}}
+	req := httptest.NewRequest("GET", "/config/search?"+query.Encode(), nil)
+	rw := httptest.NewRecorder()
+
+	//	Act
+	SearchConfig(rw, req)
+
+	//	Assert
+	items := []datastores.ConfigItem{}
+	response := datastores.ConfigResponse{Data: &items}
+	if err := json.NewDecoder(rw.Body).Decode(&response); err != nil {
+		t.Fatalf("The response isn't valid JSON: %s", err)
+	}
+
+	if rw.Code != http.StatusOK || len(items) != 2 {
+		t.Fatalf("Should have found 2 items: %d %+v", rw.Code, items)
+	}
+
+	for _, item := range items {
+		if item.Name != "db.host" {
+			t.Errorf("Secret values shouldn't be searched: %+v", item)
+		}
+	}
+}
+
+//	Searching with a bad regular expression should be a validation error
+func TestSearchConfig_BadRegex_Validation(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	Act
+	rw, response := getTestResponse(t, func(rw http.ResponseWriter, req *http.Request) {
+		req.URL.RawQuery = url.Values{"q": {"db("}, "match": {"regex"}}.Encode()
+		SearchConfig(rw, req)
+	}, "GET", "")
+
+	//	Assert
+	if rw.Code != http.StatusBadRequest || response.Code != datastores.CodeValidation {
+		t.Errorf("Should have been a validation error: %d %+v", rw.Code, response)
+	}
+}
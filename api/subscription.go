@@ -0,0 +1,50 @@
+package api
+
+import (
+	"path"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	A subscription to the events for the config items that match its patterns.
+//	Patterns use path.Match syntax and a blank pattern matches everything
+type subscription struct {
+	application string
+	machine     string
+	name        string
+}
+
+//	Creates a subscription from a command (making sure its patterns are valid)
+func newSubscription(command datastores.WebSocketCommand) (subscription, error) {
+	sub := subscription{
+		application: command.Application,
+		machine:     command.Machine,
+		name:        command.Name}
+
+	for _, pattern := range []string{sub.application, sub.machine, sub.name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return sub, err
+		}
+	}
+
+	return sub, nil
+}
+
+//	Returns true if the config item is one the subscriber should see.  Like resolving
+//	config, items for the default (*) application apply to every application and items
+//	without a machine apply to every machine
+func (s subscription) matches(item datastores.ConfigItem) bool {
+	return (item.Application == "*" || matchPattern(s.application, item.Application)) &&
+		(item.Machine == "" || matchPattern(s.machine, item.Machine)) &&
+		matchPattern(s.name, item.Name)
+}
+
+//	Returns true if the value matches the pattern (blank patterns match everything)
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
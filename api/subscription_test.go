@@ -0,0 +1,86 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/websocket"
+)
+
+//	Subscriptions should match items the way config is resolved
+func TestSubscription_Matches(t *testing.T) {
+	sub := subscription{application: "billing", machine: "WEB*", name: "db.*"}
+
+	tests := []struct {
+		item    datastores.ConfigItem
+		matches bool
+	}{
+		{datastores.ConfigItem{Application: "billing", Name: "db.host"}, true},
+		{datastores.ConfigItem{Application: "billing", Machine: "WEB01", Name: "db.host"}, true},
+		{datastores.ConfigItem{Application: "*", Name: "db.host"}, true},
+		{datastores.ConfigItem{Application: "billing", Machine: "APP01", Name: "db.host"}, false},
+		{datastores.ConfigItem{Application: "payroll", Name: "db.host"}, false},
+		{datastores.ConfigItem{Application: "billing", Name: "timeout"}, false},
+	}
+
+	for _, test := range tests {
+		if sub.matches(test.item) != test.matches {
+			t.Errorf("matches failed: %+v should be %v", test.item, test.matches)
+		}
+	}
+}
+
+//	Subscriptions with bad patterns should be rejected
+func TestSubscription_BadPattern_ReturnsError(t *testing.T) {
+	//	Act
+	_, err := newSubscription(datastores.WebSocketCommand{Type: wsSubscribe, Name: "db.[a"})
+
+	//	Assert
+	if err == nil {
+		t.Errorf("newSubscription failed: Should have returned an error for a bad pattern")
+	}
+}
+
+//	Subscribed connections should only get matching events
+func TestWsHandler_Subscribe_OnlyMatchingEvents(t *testing.T) {
+	//	Arrange
+	hub := NewHub()
+	server, url := getTestWsServer(hub)
+	defer server.Close()
+
+	subscriber, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer subscriber.Close()
+
+	everything, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer everything.Close()
+
+	subscriber.WriteJSON(datastores.WebSocketCommand{Type: wsSubscribe, Application: "billing"})
+	ack, err := readWSResponse(subscriber)
+	if err != nil || ack.Type != "Subscribed" || ack.Data.Application != "billing" {
+		t.Fatalf("Subscribe failed: %+v / %v", ack, err)
+	}
+	waitForConnections(hub, 2)
+
+	//	Act
+	hub.Broadcast <- []byte(getWSResponse("Updated", datastores.ConfigItem{Application: "payroll", Name: "Item1"}))
+	hub.Broadcast <- []byte(getWSResponse("Updated", datastores.ConfigItem{Application: "billing", Name: "Item2"}))
+
+	//	Assert
+	event, err := readWSResponse(subscriber)
+	if err != nil || event.Data.Name != "Item2" {
+		t.Errorf("Subscriber should only have gotten Item2: %+v / %v", event, err)
+	}
+
+	for _, name := range []string{"Item1", "Item2"} {
+		event, err := readWSResponse(everything)
+		if err != nil || event.Data.Name != name {
+			t.Errorf("Connection without subscriptions should have gotten %s: %+v / %v", name, event, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+)
+
+//	Lists all applications
+func ListApplications(rw http.ResponseWriter, req *http.Request) {
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response:
+	applications, err := ds.GetAllApplications()
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	//	Always return an array (even if it's empty):
+	if applications == nil {
+		applications = []string{}
+	}
+
+	sendDataResponse(rw, "Applications found", applications)
+}
+
+//	Lists all config items for the application in the path
+//	(plus the default * application).  The list query parameters
+//	(see getListOptions) filter, sort and page the items
+func ListAppConfig(rw http.ResponseWriter, req *http.Request) {
+	app := mux.Vars(req)["app"]
+
+	sendListResponse(rw, req, []string{app, "*"}, "Config items found", "No config items found with that application")
+}
+
+//	Gets a single config item for the application and name in the path
+//	and the (optional) machine query parameter
+func GetAppConfigItem(rw http.ResponseWriter, req *http.Request) {
+	request := getPathConfigItem(req)
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Send the request to the datastore and get a response
+	//	(if there isn't an item, this is a not found error):
+	response, err := ds.Get(request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	sendDataResponse(rw, "Config item found", response)
+}
+
+//	Creates or updates the config item for the application and name in the path
+//	and the (optional) machine query parameter.  The body should contain the value:
+//
+//	{ "value": "true" }
+func PutAppConfigItem(rw http.ResponseWriter, req *http.Request) {
+	//	req.Body is a ReadCloser -- we need to remember to close it:
+	defer req.Body.Close()
+
+	//	Decode the request:
+	body := appConfigItemBody{}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil && err != io.EOF {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	expires, hasExpires, err := getBodyExpires(body, time.Now())
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusBadRequest)
+		return
+	}
+
+	//	The path always wins over anything in the body:
+	request := getPathConfigItem(req)
+	request.Value = body.Value
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	See if we're updating an existing item:
+	existing, err := findExactConfigItem(ds, request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+	request.Id = existing.Id
+
+	//	Keep the description, labels and expiry time unless the body has new ones:
+	request.Description = existing.Description
+	if body.Description != nil {
+		request.Description = *body.Description
+	}
+
+	request.Labels = existing.Labels
+	if body.Labels != nil {
+		request.Labels = body.Labels
+	}
+
+	request.Expires = existing.Expires
+	if hasExpires {
+		request.Expires = expires
+	}
+
+	//	Changes to protected applications need to be approved first:
+	if IsProtectedApplication(request.Application) {
+		sendProposal(rw, req, datastores.ProposalSet, request)
+		return
+	}
+
+	//	Send the request to the datastore and get a response:
+	response, err := ds.Set(request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	WsHub.publish(ds, "Updated", response)
+
+	//	If this is a new item, let the caller know where to find it:
+	if existing.Name == "" {
+		rw.Header().Set("Location", getAppConfigItemPath(response))
+		sendStatusResponse(rw, http.StatusCreated, "Config item created", response)
+		return
+	}
+
+	sendDataResponse(rw, "Config item updated", response)
+}
+
+//	Removes the config item for the application and name in the path
+//	and the (optional) machine query parameter
+func DeleteAppConfigItem(rw http.ResponseWriter, req *http.Request) {
+	request := getPathConfigItem(req)
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	//	Make sure the item exists first:
+	existing, err := findExactConfigItem(ds, request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	if existing.Name == "" {
+		sendErrorResponse(rw, fmt.Errorf("No config item found with that application and name"), http.StatusNotFound)
+		return
+	}
+
+	//	Changes to protected applications need to be approved first:
+	if IsProtectedApplication(request.Application) {
+		sendProposal(rw, req, datastores.ProposalRemove, request)
+		return
+	}
+
+	//	Send the request to the datastore:
+	err = ds.Remove(request)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	WsHub.publish(ds, "Removed", request)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+//	The body of a v2 PUT.  If the description, labels or expiry time are
+//	missing, the item keeps the ones it has (send empty labels to remove them,
+//	or a null expiry time so it doesn't expire).  A ttl (like 30m) sets the
+//	expiry time from now
+type appConfigItemBody struct {
+	Value       string            `json:"value"`
+	Description *string           `json:"description"`
+	Labels      map[string]string `json:"labels"`
+	Expires     json.RawMessage   `json:"expires"`
+	TTL         string            `json:"ttl"`
+}
+
+//	Gets the expiry time from a PUT body.  Returns false if the body doesn't
+//	have an expiry time or a ttl
+func getBodyExpires(body appConfigItemBody, now time.Time) (*time.Time, bool, error) {
+	if body.TTL != "" {
+		ttl, err := time.ParseDuration(body.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, false, fmt.Errorf("The ttl should be a positive duration (like 30m or 2h)")
+		}
+
+		expires := now.Add(ttl)
+		return &expires, true, nil
+	}
+
+	if body.Expires == nil {
+		return nil, false, nil
+	}
+
+	var expires *time.Time
+	if err := json.Unmarshal(body.Expires, &expires); err != nil {
+		return nil, false, fmt.Errorf("The expires time should be an RFC 3339 time (like 2016-08-11T14:50:17Z) or null")
+	}
+
+	return expires, true, nil
+}
+
+//	Builds a config item from the path parameters and machine query parameter
+func getPathConfigItem(req *http.Request) datastores.ConfigItem {
+	vars := mux.Vars(req)
+
+	return datastores.ConfigItem{
+		Application: vars["app"],
+		Name:        vars["name"],
+		Machine:     req.URL.Query().Get("machine")}
+}
+
+//	Gets the v2 resource path for the given config item
+func getAppConfigItemPath(item datastores.ConfigItem) string {
+	path := fmt.Sprintf("/v2/apps/%s/config/%s", url.PathEscape(item.Application), url.PathEscape(item.Name))
+	if item.Machine != "" {
+		path = path + "?machine=" + url.QueryEscape(item.Machine)
+	}
+
+	return path
+}
+
+//	Gets the config item that exactly matches the given application, name and machine.
+//	If there is no exact match an empty item is returned.  An item that has
+//	expired (but hasn't been removed yet) is returned without its expiry time,
+//	so it's replaced instead of being created again
+func findExactConfigItem(ds datastores.ConfigService, c datastores.ConfigItem) (datastores.ConfigItem, error) {
+	response, _, err := datastores.GetExactConfigItem(ds, c.Application, c.Machine, c.Name)
+	if err != nil {
+		return datastores.ConfigItem{}, err
+	}
+
+	if datastores.IsExpired(response, time.Now()) {
+		response.Expires = nil
+	}
+
+	return response, nil
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+//	Puts a config item through a router with the v2 item route
+func putTestAppConfigItem(t *testing.T, target, body string) datastores.ConfigItem {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/apps/{app}/config/{name}", PutAppConfigItem).Methods("PUT")
+
+	req := httptest.NewRequest("PUT", target, strings.NewReader(body))
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	item := datastores.ConfigItem{}
+	response := datastores.ConfigResponse{Data: &item}
+	if err := json.NewDecoder(rw.Body).Decode(&response); err != nil {
+		t.Fatalf("The response isn't valid JSON: %s", err)
+	}
+
+	if rw.Code != http.StatusOK && rw.Code != http.StatusCreated {
+		t.Fatalf("Put failed: %d %+v", rw.Code, response)
+	}
+
+	return item
+}
+
+//	Putting an item that has expired (but hasn't been removed yet) should
+//	replace it, without keeping the expiry time
+func TestPutAppConfigItem_Expired_Replaced(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	past := time.Now().Add(-time.Minute)
+	ds := datastores.GetConfigDatastore()
+	original, err := ds.Set(datastores.ConfigItem{Application: "billing", Name: "Maintenance", Value: "true", Description: "Shows the banner", Expires: &past})
+	if err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+
+	//	Act
+	replaced := putTestAppConfigItem(t, "/v2/apps/billing/config/Maintenance", `{"value":"false"}`)
+
+	//	Assert
+	if replaced.Id != original.Id || replaced.Value != "false" || replaced.Description != "Shows the banner" || replaced.Expires != nil {
+		t.Errorf("Put should have replaced the expired item: %+v", replaced)
+	}
+}
+
+//	Putting just a value should keep the item's description and labels
+func TestPutAppConfigItem_ValueOnly_KeepsLabels(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	putTestAppConfigItem(t, "/v2/apps/billing/config/db.host", `{"value":"db01.example.com","description":"The primary database","labels":{"owner":"payments","pii":""}}`)
+
+	//	Act
+	updated := putTestAppConfigItem(t, "/v2/apps/billing/config/db.host", `{"value":"db02.example.com"}`)
+	cleared := putTestAppConfigItem(t, "/v2/apps/billing/config/db.host", `{"value":"db03.example.com","labels":{}}`)
+
+	//	Assert
+	if updated.Value != "db02.example.com" || updated.Description != "The primary database" || updated.Labels["owner"] != "payments" {
+		t.Errorf("Put should have kept the description and labels: %+v", updated)
+	}
+
+	if cleared.Description != "The primary database" || len(cleared.Labels) != 0 {
+		t.Errorf("Put should have removed the labels: %+v", cleared)
+	}
+}
+
+//	Putting a ttl should set the expiry time, and a null expiry should clear it
+func TestPutAppConfigItem_TTL_SetsExpires(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	before := time.Now()
+
+	//	Act
+	expiring := putTestAppConfigItem(t, "/v2/apps/billing/config/Maintenance", `{"value":"true","ttl":"1h"}`)
+	kept := putTestAppConfigItem(t, "/v2/apps/billing/config/Maintenance", `{"value":"false"}`)
+	cleared := putTestAppConfigItem(t, "/v2/apps/billing/config/Maintenance", `{"value":"false","expires":null}`)
+
+	//	Assert
+	if expiring.Expires == nil || expiring.Expires.Before(before.Add(time.Hour)) || expiring.Expires.After(time.Now().Add(time.Hour)) {
+		t.Errorf("Put should have set the expiry time an hour from now: %+v", expiring)
+	}
+
+	if kept.Expires == nil || !kept.Expires.Equal(*expiring.Expires) {
+		t.Errorf("Put should have kept the expiry time: %+v", kept)
+	}
+
+	if cleared.Expires != nil {
+		t.Errorf("Put should have cleared the expiry time: %+v", cleared)
+	}
+}
+
+//	Sends a request through a router with the v2 item routes
+func serveTestAppConfigRequest(method, target, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/apps/{app}/config", ListAppConfig).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", GetAppConfigItem).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", PutAppConfigItem).Methods("PUT")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", DeleteAppConfigItem).Methods("DELETE")
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	return rw
+}
+
+//	Getting an item that doesn't exist should be a 404
+func TestGetAppConfigItem_Missing_NotFound(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	Act
+	rw := serveTestAppConfigRequest("GET", "/v2/apps/billing/config/db.host", "")
+
+	//	Assert
+	if rw.Code != http.StatusNotFound {
+		t.Errorf("Getting a missing item should be a 404, but got %d: %s", rw.Code, rw.Body.String())
+	}
+}
+
+//	Listing an application without items should say that nothing was found
+func TestListAppConfig_Empty_NotFoundMessage(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	Act
+	rw := serveTestAppConfigRequest("GET", "/v2/apps/billing/config", "")
+
+	//	Assert
+	if rw.Code != http.StatusOK {
+		t.Errorf("Listing an empty application should be a 200, but got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	if !strings.Contains(rw.Body.String(), "No config items found with that application") {
+		t.Errorf("Listing an empty application should say nothing was found: %s", rw.Body.String())
+	}
+}
+
+//	Putting a new item should be a 201 with its location, and putting it
+//	again should be a 200
+func TestPutAppConfigItem_StatusCodes(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	//	Act
+	created := serveTestAppConfigRequest("PUT", "/v2/apps/billing/config/db.host?machine=web01", `{"value":"db01.example.com"}`)
+	updated := serveTestAppConfigRequest("PUT", "/v2/apps/billing/config/db.host?machine=web01", `{"value":"db02.example.com"}`)
+
+	//	Assert
+	if created.Code != http.StatusCreated {
+		t.Errorf("Putting a new item should be a 201, but got %d: %s", created.Code, created.Body.String())
+	}
+
+	if location := created.Header().Get("Location"); location != "/v2/apps/billing/config/db.host?machine=web01" {
+		t.Errorf("Putting a new item should send its location, but got '%s'", location)
+	}
+
+	if updated.Code != http.StatusOK {
+		t.Errorf("Putting an existing item should be a 200, but got %d: %s", updated.Code, updated.Body.String())
+	}
+
+	if location := updated.Header().Get("Location"); location != "" {
+		t.Errorf("Putting an existing item shouldn't send a location, but got '%s'", location)
+	}
+}
+
+//	Deleting an item should be a 204, and deleting it again should be a 404
+func TestDeleteAppConfigItem_StatusCodes(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	putTestAppConfigItem(t, "/v2/apps/billing/config/db.host", `{"value":"db01.example.com"}`)
+
+	//	Act
+	removed := serveTestAppConfigRequest("DELETE", "/v2/apps/billing/config/db.host", "")
+	missing := serveTestAppConfigRequest("DELETE", "/v2/apps/billing/config/db.host", "")
+
+	//	Assert
+	if removed.Code != http.StatusNoContent {
+		t.Errorf("Deleting an item should be a 204, but got %d: %s", removed.Code, removed.Body.String())
+	}
+
+	if missing.Code != http.StatusNotFound {
+		t.Errorf("Deleting a missing item should be a 404, but got %d: %s", missing.Code, missing.Body.String())
+	}
+}
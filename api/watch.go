@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	How long a watch waits for a change by default (and at most)
+const (
+	defaultWatchWait = 5 * time.Minute
+	maxWatchWait     = 10 * time.Minute
+)
+
+//	How often a waiting watch checks the datastore, in case the change
+//	was made by another server using the same database
+var watchRecheck = 5 * time.Second
+
+//	WatchHandler long-polls for changes to an application's config.  It returns
+//	the application's config items as soon as its modification index is greater
+//	than the index passed in (or when the wait is over)
+type WatchHandler struct {
+	H *Hub
+}
+
+func (wh WatchHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	application := query.Get("app")
+	if application == "" {
+		sendErrorResponse(rw, fmt.Errorf("An application is required"), http.StatusBadRequest)
+		return
+	}
+
+	index := int64(0)
+	if query.Get("index") != "" {
+		var err error
+		index, err = strconv.ParseInt(query.Get("index"), 10, 64)
+		if err != nil || index < 0 {
+			sendErrorResponse(rw, fmt.Errorf("index should be a modification index"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	wait := defaultWatchWait
+	if query.Get("wait") != "" {
+		var err error
+		wait, err = time.ParseDuration(query.Get("wait"))
+		if err != nil || wait < 0 {
+			sendErrorResponse(rw, fmt.Errorf("wait should be a duration like 30s or 5m"), http.StatusBadRequest)
+			return
+		}
+	}
+	if wait > maxWatchWait {
+		wait = maxWatchWait
+	}
+
+	//	Get the current datastore:
+	ds := datastores.GetConfigDatastore()
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	recheck := time.NewTicker(watchRecheck)
+	defer recheck.Stop()
+
+	for {
+		//	Get the change notification before the index, so we can't miss a change in between:
+		changed := wh.H.changes()
+
+		current, err := getWatchIndex(ds, application)
+		if err != nil {
+			sendErrorResponse(rw, err, http.StatusInternalServerError)
+			return
+		}
+
+		if current > index {
+			sendWatchResponse(rw, ds, application, current)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-recheck.C:
+		case <-timeout.C:
+			sendWatchResponse(rw, ds, application, current)
+			return
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+//	Gets the modification index for an application's config.  Changes to the
+//	default (*) application change every application's config
+func getWatchIndex(ds datastores.ConfigService, application string) (int64, error) {
+	index, err := ds.GetApplicationIndex(application)
+	if err != nil {
+		return 0, err
+	}
+
+	defaultIndex, err := ds.GetApplicationIndex("*")
+	if err != nil {
+		return 0, err
+	}
+
+	if defaultIndex > index {
+		index = defaultIndex
+	}
+
+	return index, nil
+}
+
+//	Sends the application's config items with their modification index
+func sendWatchResponse(rw http.ResponseWriter, ds datastores.ConfigService, application string, index int64) {
+	configItems, err := ds.GetAllForApplication(application)
+	if err != nil {
+		sendErrorResponse(rw, err, http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("X-Config-Index", strconv.FormatInt(index, 10))
+	sendDataResponse(rw, "Config items found", configItems)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/viper"
+)
+
+//	The result of a watch request
+type testWatchResult struct {
+	index    int64
+	response datastores.ConfigResponse
+	err      error
+}
+
+//	Watches an application's config
+func watchTestConfig(url string) testWatchResult {
+	resp, err := http.Get(url)
+	if err != nil {
+		return testWatchResult{err: err}
+	}
+	defer resp.Body.Close()
+
+	retval := testWatchResult{}
+	retval.index, _ = strconv.ParseInt(resp.Header.Get("X-Config-Index"), 10, 64)
+	retval.err = json.NewDecoder(resp.Body).Decode(&retval.response)
+	return retval
+}
+
+//	Watches should return right away for old indexes and wait for new ones
+func TestWatchHandler_WaitsForChanges(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+
+	hub := NewHub()
+	server := httptest.NewServer(WatchHandler{H: hub})
+	defer server.Close()
+
+	store.Set(datastores.ConfigItem{Application: "billing", Name: "Item1", Value: "Value1"})
+
+	//	Act
+	initial := watchTestConfig(server.URL + "?app=billing")
+
+	results := make(chan testWatchResult)
+	go func() {
+		results <- watchTestConfig(server.URL + "?app=billing&index=" + strconv.FormatInt(initial.index, 10))
+	}()
+
+	//	Changes to other applications shouldn't wake up the watch:
+	time.Sleep(50 * time.Millisecond)
+	other, _ := store.Set(datastores.ConfigItem{Application: "payroll", Name: "Item2", Value: "Value2"})
+	hub.publish(store, "Updated", other)
+
+	select {
+	case result := <-results:
+		t.Fatalf("Watch shouldn't have returned for another application: %+v", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	changed, _ := store.Set(datastores.ConfigItem{Application: "*", Name: "Item3", Value: "Value3"})
+	hub.publish(store, "Updated", changed)
+
+	//	Assert
+	if initial.err != nil || initial.index == 0 {
+		t.Errorf("Watch without an index should have returned right away with the index: %+v", initial)
+	}
+
+	select {
+	case result := <-results:
+		items, _ := result.response.Data.([]interface{})
+		if result.err != nil || result.index <= initial.index || len(items) != 2 {
+			t.Errorf("Watch should have returned both items with a new index: %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Watch should have returned after the change")
+	}
+}
+
+//	Watches should return the current index when the wait is over
+func TestWatchHandler_Timeout(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+	store := datastores.GetConfigDatastore()
+	store.Set(datastores.ConfigItem{Application: "billing", Name: "Item1", Value: "Value1"})
+
+	server := httptest.NewServer(WatchHandler{H: NewHub()})
+	defer server.Close()
+
+	current, _ := store.GetApplicationIndex("billing")
+
+	//	Act
+	result := watchTestConfig(server.URL + "?app=billing&wait=50ms&index=" + strconv.FormatInt(current, 10))
+
+	//	Assert
+	if result.err != nil || result.index != current {
+		t.Errorf("Watch should have returned the current index %d: %+v", current, result)
+	}
+}
+
+//	Bad parameters should be rejected
+func TestWatchHandler_BadRequest(t *testing.T) {
+	//	Arrange
+	server := httptest.NewServer(WatchHandler{H: NewHub()})
+	defer server.Close()
+
+	for _, url := range []string{server.URL, server.URL + "?app=billing&index=abc", server.URL + "?app=billing&wait=soon"} {
+		//	Act
+		resp, err := http.Get(url)
+
+		//	Assert
+		if err != nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s should have returned 400: %v / %v", url, resp, err)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/websocket"
+)
+
+//	Cache keeps the resolved configuration for an application and machine in memory.
+//	It follows changes on the server over the /ws WebSocket, saves a last-known-good
+//	copy to a local file (if one is given) and uses that copy when the server can't
+//	be reached
+type Cache struct {
+	client      *Client
+	application string
+	machine     string
+	backupFile  string
+
+	//	All items for the application (including the default * application)
+	//	and the effective value for each name
+	mu       sync.RWMutex
+	items    map[cacheKey]datastores.ConfigItem
+	resolved map[string]datastores.ConfigItem
+	online   bool
+
+	callbacksMx sync.RWMutex
+	callbacks   []func(datastores.ConfigChange)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+//	Identifies a stored config item
+type cacheKey struct {
+	application string
+	machine     string
+	name        string
+}
+
+//	NewCache creates a cache for the application and machine.  If backupFile isn't
+//	blank, the last-known-good configuration is saved there
+func (c *Client) NewCache(application, machine, backupFile string) *Cache {
+	return &Cache{
+		client:      c,
+		application: application,
+		machine:     machine,
+		backupFile:  backupFile,
+		items:       make(map[cacheKey]datastores.ConfigItem),
+		resolved:    make(map[string]datastores.ConfigItem)}
+}
+
+//	Start loads the configuration and starts following changes.  If the server can't be
+//	reached the backup file is used instead (and the server is retried in the background).
+//	An error is only returned if neither one is available
+func (cache *Cache) Start(ctx context.Context) error {
+	err := cache.refresh(ctx)
+	if err != nil {
+		if backupErr := cache.loadBackup(); backupErr != nil {
+			return err
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	cache.cancel = cancel
+	cache.done = make(chan struct{})
+	go cache.run(runCtx)
+
+	return nil
+}
+
+//	Close stops following changes
+func (cache *Cache) Close() {
+	if cache.cancel != nil {
+		cache.cancel()
+		<-cache.done
+	}
+}
+
+//	OnChange registers a function that's called each time an effective value
+//	changes.  Functions are called in the order they were added
+func (cache *Cache) OnChange(callback func(datastores.ConfigChange)) {
+	cache.callbacksMx.Lock()
+	defer cache.callbacksMx.Unlock()
+	cache.callbacks = append(cache.callbacks, callback)
+}
+
+//	Get gets the effective value for the name
+func (cache *Cache) Get(name string) (string, bool) {
+	item, ok := cache.GetItem(name)
+	return item.Value, ok
+}
+
+//	GetItem gets the effective config item for the name
+func (cache *Cache) GetItem(name string) (datastores.ConfigItem, bool) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	item, ok := cache.resolved[name]
+	return item, ok
+}
+
+//	Items gets all of the effective config items (sorted by name)
+func (cache *Cache) Items() []datastores.ConfigItem {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	items := []datastores.ConfigItem{}
+	for _, item := range cache.items {
+		items = append(items, item)
+	}
+
+	return datastores.ResolveConfigItems(items, cache.application, cache.machine)
+}
+
+//	Online returns true if the cache is currently following the server
+func (cache *Cache) Online() bool {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.online
+}
+
+//	Keeps a WebSocket connection open (reconnecting with a backoff) until the context is done
+func (cache *Cache) run(ctx context.Context) {
+	defer close(cache.done)
+
+	wait := cache.client.backoff
+	for {
+		connected := cache.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		//	Start the backoff over if we were connected for a while:
+		if connected {
+			wait = cache.client.backoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		wait = wait * 2
+		if wait > cache.client.maxBackoff {
+			wait = cache.client.maxBackoff
+		}
+	}
+}
+
+//	Connects to the WebSocket, reloads everything (in case we missed changes while
+//	disconnected) and then applies events until the connection drops.
+//	Returns true if the connection was made
+func (cache *Cache) follow(ctx context.Context) bool {
+	header := http.Header{}
+	cache.client.addCredentials(header)
+
+	dialer := websocket.Dialer{TLSClientConfig: cache.client.tlsConfig, Proxy: http.ProxyFromEnvironment}
+	conn, _, err := dialer.Dial(cache.client.websocketURL(), header)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	//	Close the connection when we're done:
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	//	Only ask for our application's events:
+	subscribe := datastores.WebSocketCommand{Type: "subscribe", Application: cache.application, Machine: cache.machine}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return true
+	}
+
+	if err := cache.refresh(ctx); err != nil {
+		return true
+	}
+
+	for {
+		event := datastores.WebSocketResponse{}
+		if err := conn.ReadJSON(&event); err != nil {
+			break
+		}
+
+		//	If the server dropped events because we fell behind, start over:
+		if event.Type == "Dropped" {
+			if err := cache.refresh(ctx); err != nil {
+				break
+			}
+			continue
+		}
+
+		cache.apply(event)
+	}
+
+	cache.mu.Lock()
+	cache.online = false
+	cache.mu.Unlock()
+
+	return true
+}
+
+//	Loads all items from the server
+func (cache *Cache) refresh(ctx context.Context) error {
+	items, err := cache.client.GetAll(ctx, cache.application)
+	if err != nil {
+		return err
+	}
+
+	cache.replace(items, true)
+	cache.saveBackup()
+
+	return nil
+}
+
+//	Applies a change event from the server
+func (cache *Cache) apply(event datastores.WebSocketResponse) {
+	if event.Type != "Updated" && event.Type != "Removed" {
+		return
+	}
+
+	item := event.Data
+	if item.Application != cache.application && item.Application != "*" {
+		return
+	}
+
+	cache.mu.Lock()
+	items := make([]datastores.ConfigItem, 0, len(cache.items)+1)
+	for key, existing := range cache.items {
+		if key != getCacheKey(item) {
+			items = append(items, existing)
+		}
+	}
+	cache.mu.Unlock()
+
+	if event.Type != "Removed" {
+		items = append(items, item)
+	}
+
+	cache.replace(items, true)
+	cache.saveBackup()
+}
+
+//	Replaces the cached items and calls the change callbacks for any effective
+//	values that changed
+func (cache *Cache) replace(items []datastores.ConfigItem, online bool) {
+	resolved := make(map[string]datastores.ConfigItem)
+	for _, item := range datastores.ResolveConfigItems(items, cache.application, cache.machine) {
+		resolved[item.Name] = item
+	}
+
+	stored := make(map[cacheKey]datastores.ConfigItem)
+	for _, item := range items {
+		stored[getCacheKey(item)] = item
+	}
+
+	cache.mu.Lock()
+	previous := cache.resolved
+	cache.items = stored
+	cache.resolved = resolved
+	cache.online = online
+	cache.mu.Unlock()
+
+	//	Figure out what changed:
+	changes := []datastores.ConfigChange{}
+	for name, item := range resolved {
+		old, existed := previous[name]
+		switch {
+		case !existed:
+			changes = append(changes, newCacheChange("add", item, ""))
+		case old.Value != item.Value:
+			changes = append(changes, newCacheChange("update", item, old.Value))
+		}
+	}
+
+	for name, old := range previous {
+		if _, exists := resolved[name]; !exists {
+			change := newCacheChange("remove", old, old.Value)
+			change.Value = ""
+			changes = append(changes, change)
+		}
+	}
+
+	cache.callbacksMx.RLock()
+	defer cache.callbacksMx.RUnlock()
+	for _, change := range changes {
+		for _, callback := range cache.callbacks {
+			callback(change)
+		}
+	}
+}
+
+//	Saves the current items to the backup file (if we have one).  The file is written
+//	to a temporary file first, so a crash can't leave a partial backup behind
+func (cache *Cache) saveBackup() error {
+	if cache.backupFile == "" {
+		return nil
+	}
+
+	cache.mu.RLock()
+	items := []datastores.ConfigItem{}
+	for _, item := range cache.items {
+		items = append(items, item)
+	}
+	cache.mu.RUnlock()
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(cache.backupFile), filepath.Base(cache.backupFile))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+
+	return os.Rename(tmp.Name(), cache.backupFile)
+}
+
+//	Loads the items from the backup file
+func (cache *Cache) loadBackup() error {
+	if cache.backupFile == "" {
+		return os.ErrNotExist
+	}
+
+	data, err := ioutil.ReadFile(cache.backupFile)
+	if err != nil {
+		return err
+	}
+
+	items := []datastores.ConfigItem{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	cache.replace(items, false)
+
+	return nil
+}
+
+//	Gets the cache key for a config item
+func getCacheKey(item datastores.ConfigItem) cacheKey {
+	return cacheKey{application: item.Application, machine: item.Machine, name: item.Name}
+}
+
+//	Creates a change for a callback
+func newCacheChange(action string, item datastores.ConfigItem, previous string) datastores.ConfigChange {
+	return datastores.ConfigChange{
+		Action:      action,
+		Application: item.Application,
+		Machine:     item.Machine,
+		Name:        item.Name,
+		Previous:    previous,
+		Value:       item.Value}
+}
+
+//	Gets the WebSocket URL for the server
+func (c *Client) websocketURL() string {
+	address := c.address
+	switch {
+	case strings.HasPrefix(address, "https://"):
+		address = "wss://" + strings.TrimPrefix(address, "https://")
+	case strings.HasPrefix(address, "http://"):
+		address = "ws://" + strings.TrimPrefix(address, "http://")
+	}
+
+	return address + "/ws"
+}
@@ -0,0 +1,135 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Waits for a change to show up in the channel
+func waitForChange(t *testing.T, changes chan datastores.ConfigChange, name string) datastores.ConfigChange {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case change := <-changes:
+			if change.Name == name {
+				return change
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for a change to %s", name)
+		}
+	}
+}
+
+//	Cache should follow changes made on the server
+func TestCache_FollowsChanges_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	c.Set(ctx, datastores.ConfigItem{Application: "*", Name: "TestItem1", Value: "global"})
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Machine: "WEB01", Value: "machine"})
+
+	cache := c.NewCache("MyTestAppName", "WEB01", "")
+	changes := make(chan datastores.ConfigChange, 10)
+	cache.OnChange(func(change datastores.ConfigChange) {
+		changes <- change
+	})
+
+	if err := cache.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %s", err)
+	}
+	defer cache.Close()
+	waitForChange(t, changes, "TestItem1")
+
+	//	Wait for the WebSocket to connect:
+	for i := 0; i < 100 && !cache.Online(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	//	Act
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem2", Value: "added"})
+	added := waitForChange(t, changes, "TestItem2")
+
+	c.Remove(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Machine: "WEB01"})
+	fallback := waitForChange(t, changes, "TestItem1")
+
+	//	Assert
+	if added.Action != "add" || added.Value != "added" {
+		t.Errorf("Cache failed: Should have added TestItem2: %+v", added)
+	}
+
+	if fallback.Action != "update" || fallback.Previous != "machine" || fallback.Value != "global" {
+		t.Errorf("Cache failed: Removing the machine item should fall back to the global item: %+v", fallback)
+	}
+
+	if value, _ := cache.Get("TestItem1"); value != "global" {
+		t.Errorf("Cache failed: TestItem1 should be global but was %s", value)
+	}
+}
+
+//	Cache should use the backup file if the server is down
+func TestCache_ServerDown_UsesBackup(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	backupFile := "testing-backup.json"
+	defer os.Remove(filename)
+	defer os.Remove(backupFile)
+
+	server := getTestServer(filename)
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Value: "Value1"})
+
+	online := c.NewCache("MyTestAppName", "", backupFile)
+	if err := online.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %s", err)
+	}
+	online.Close()
+	server.Close()
+
+	//	Act
+	offlineClient, _ := client.New(server.URL, client.WithRetries(0, time.Millisecond, time.Millisecond))
+	offline := offlineClient.NewCache("MyTestAppName", "", backupFile)
+	err := offline.Start(ctx)
+	defer offline.Close()
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Start failed: Should have used the backup file: %s", err)
+	}
+
+	if value, _ := offline.Get("TestItem1"); value != "Value1" {
+		t.Errorf("Cache failed: TestItem1 should be Value1 but was '%s'", value)
+	}
+
+	if offline.Online() {
+		t.Errorf("Cache failed: Shouldn't be online")
+	}
+}
+
+//	Cache should fail to start without a server or backup file
+func TestCache_ServerDown_NoBackup_ReturnsError(t *testing.T) {
+	//	Arrange
+	c, _ := client.New("http://127.0.0.1:1", client.WithRetries(0, time.Millisecond, time.Millisecond))
+	cache := c.NewCache("MyTestAppName", "", "")
+
+	//	Act
+	err := cache.Start(context.Background())
+
+	//	Assert
+	if err == nil {
+		cache.Close()
+		t.Errorf("Start failed: Should have returned an error")
+	}
+}
@@ -0,0 +1,499 @@
+//	Package client is a Go client for the centralconfig API
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	ErrNotFound is returned when a config item doesn't exist
+var ErrNotFound = errors.New("config item not found")
+
+//	Error is returned when the server responds with an error.  Code is the
+//	server's error code (like conflict or unavailable), if it sent one
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("centralconfig: server returned %d: %s", e.StatusCode, e.Message)
+}
+
+//	ProposalError is returned when a change to a protected application was
+//	proposed instead of being made.  The change is made when someone else
+//	approves the proposal
+type ProposalError struct {
+	Proposal datastores.ChangeProposal
+}
+
+func (e *ProposalError) Error() string {
+	return fmt.Sprintf("the change needs to be approved (proposal %d)", e.Proposal.Id)
+}
+
+//	Client talks to a centralconfig server
+type Client struct {
+	address    string
+	httpClient *http.Client
+	tlsConfig  *tls.Config
+
+	//	The request timeout (if WithTimeout set one)
+	timeout    time.Duration
+	hasTimeout bool
+
+	//	Credentials
+	token    string
+	user     string
+	password string
+
+	//	Retry settings
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+//	Option configures a Client
+type Option func(*Client)
+
+//	WithHTTPClient uses a copy of the given HTTP client for all requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+//	WithTLSConfig uses the given TLS configuration to connect to the server
+//	(for requests and the cache's WebSocket).  It's applied to a copy of the
+//	HTTP client's transport, so it can be used with WithHTTPClient (as long as
+//	the client's transport is an *http.Transport)
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+//	WithTimeout sets the timeout for each request
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+		c.hasTimeout = true
+	}
+}
+
+//	WithToken sends a bearer token with each request.  The server doesn't check
+//	it -- it's for a proxy in front of the server that authenticates requests
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+//	WithBasicAuth sends a user name and password with each request
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) {
+		c.user = user
+		c.password = password
+	}
+}
+
+//	WithRetries sets the number of times a failed request is retried.  The wait
+//	between attempts starts at backoff and doubles each time (up to maxBackoff).
+//	Only GET, PUT and DELETE requests are retried: a POST (like scheduling a
+//	change) that failed may still have been made
+func WithRetries(retries int, backoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+//	New creates a client for the centralconfig server at the given address
+//	(for example: https://config.example.com:3000).  Options can be given in
+//	any order
+func New(address string, opts ...Option) (*Client, error) {
+	if _, err := url.Parse(address); err != nil || address == "" {
+		return nil, fmt.Errorf("centralconfig: invalid server address '%s'", address)
+	}
+
+	c := &Client{
+		address:    strings.TrimSuffix(address, "/"),
+		retries:    3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	httpClient, err := c.getHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	c.httpClient = httpClient
+
+	return c, nil
+}
+
+//	Gets the HTTP client to use, once all the options are set: a copy of the
+//	WithHTTPClient client (so the caller's client isn't changed) with the
+//	timeout and TLS configuration
+func (c *Client) getHTTPClient() (*http.Client, error) {
+	httpClient := http.Client{Timeout: 30 * time.Second}
+	if c.httpClient != nil {
+		httpClient = *c.httpClient
+	}
+
+	if c.hasTimeout {
+		httpClient.Timeout = c.timeout
+	}
+
+	//	Without a TLS configuration, the cache's WebSocket uses the transport's:
+	if c.tlsConfig == nil {
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			c.tlsConfig = transport.TLSClientConfig
+		}
+		return &httpClient, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport)
+	if httpClient.Transport != nil {
+		configured, ok := httpClient.Transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("centralconfig: can't use a TLS configuration with a %T transport", httpClient.Transport)
+		}
+		transport = configured
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = c.tlsConfig
+	httpClient.Transport = transport
+
+	return &httpClient, nil
+}
+
+//	Get gets a single config item.  If the item doesn't exist for the machine or
+//	application the default (*) application item is returned.  Returns ErrNotFound
+//	if there isn't an item at all
+func (c *Client) Get(ctx context.Context, application, name, machine string) (datastores.ConfigItem, error) {
+	item := datastores.ConfigItem{}
+	err := c.call(ctx, "GET", configItemPath(application, name, machine), nil, &item)
+
+	return item, err
+}
+
+//	GetAll gets all config items for the application (plus the default * application).
+//	If the application is blank, all config items are returned
+func (c *Client) GetAll(ctx context.Context, application string) ([]datastores.ConfigItem, error) {
+	items := []datastores.ConfigItem{}
+
+	path := "/config/getall"
+	if application != "" {
+		path = fmt.Sprintf("/v2/apps/%s/config", url.PathEscape(application))
+	}
+
+	err := c.call(ctx, "GET", path, nil, &items)
+
+	return items, err
+}
+
+//	GetResolved gets the effective config items for the application and machine
+//	(one item per name)
+func (c *Client) GetResolved(ctx context.Context, application, machine string) ([]datastores.ConfigItem, error) {
+	items, err := c.GetAll(ctx, application)
+	if err != nil {
+		return nil, err
+	}
+
+	return datastores.ResolveConfigItems(items, application, machine), nil
+}
+
+//	ExportOptions are the optional settings for an export
+type ExportOptions struct {
+	//	The machine to resolve the config for
+	Machine string
+
+	//	One of env, yaml, json, toml, ini or properties (defaults to env)
+	Format string
+
+	//	Only items with the prefix as their name, or under it (like db.primary)
+	Prefix string
+
+	//	Only items with all of these labels.  A blank value matches any value
+	Labels map[string]string
+
+	//	Nest dotted names into trees (yaml, json, toml) or sections (ini)
+	Nest bool
+}
+
+//	Export gets the resolved config for the application as a config file
+func (c *Client) Export(ctx context.Context, application string, options ExportOptions) ([]byte, error) {
+	query := url.Values{}
+	query.Set("app", application)
+	if options.Machine != "" {
+		query.Set("machine", options.Machine)
+	}
+	if options.Format != "" {
+		query.Set("format", options.Format)
+	}
+	if options.Prefix != "" {
+		query.Set("prefix", options.Prefix)
+	}
+	for name, value := range options.Labels {
+		if value != "" {
+			name = name + ":" + value
+		}
+		query.Add("label", name)
+	}
+	if options.Nest {
+		query.Set("nest", "true")
+	}
+
+	output := []byte{}
+	err := c.call(ctx, "GET", "/config/export?"+query.Encode(), nil, &output)
+
+	return output, err
+}
+
+//	Set creates or updates a config item.  If the item doesn't have a description,
+//	labels or expiry time, an existing item keeps the ones it has.  Returns a
+//	*ProposalError if the application is protected
+func (c *Client) Set(ctx context.Context, item datastores.ConfigItem) (datastores.ConfigItem, error) {
+	response := datastores.ConfigItem{}
+	err := c.call(ctx, "PUT", configItemPath(item.Application, item.Name, item.Machine), datastores.ConfigItem{Value: item.Value, Description: item.Description, Labels: item.Labels, Expires: item.Expires}, &response)
+
+	return response, err
+}
+
+//	Remove removes a config item.  Returns ErrNotFound if it doesn't exist, or
+//	a *ProposalError if the application is protected
+func (c *Client) Remove(ctx context.Context, item datastores.ConfigItem) error {
+	return c.call(ctx, "DELETE", configItemPath(item.Application, item.Name, item.Machine), nil, nil)
+}
+
+//	Applications gets all applications
+func (c *Client) Applications(ctx context.Context) ([]string, error) {
+	applications := []string{}
+	err := c.call(ctx, "GET", "/v2/apps", nil, &applications)
+
+	return applications, err
+}
+
+//	ScheduleChange schedules a config value change for the change's effective
+//	time.  Returns the change with its id
+func (c *Client) ScheduleChange(ctx context.Context, change datastores.ScheduledChange) (datastores.ScheduledChange, error) {
+	response := datastores.ScheduledChange{}
+	err := c.call(ctx, "POST", "/v2/schedule", change, &response)
+
+	return response, err
+}
+
+//	ScheduledChanges gets the scheduled changes that haven't been made yet, in
+//	the order they'll be made.  If the application is blank, all of them are returned
+func (c *Client) ScheduledChanges(ctx context.Context, application string) ([]datastores.ScheduledChange, error) {
+	changes := []datastores.ScheduledChange{}
+
+	path := "/v2/schedule"
+	if application != "" {
+		path = path + "?application=" + url.QueryEscape(application)
+	}
+
+	err := c.call(ctx, "GET", path, nil, &changes)
+
+	return changes, err
+}
+
+//	CancelScheduledChange cancels a scheduled change.  Returns ErrNotFound if
+//	it doesn't exist (or has already been made)
+func (c *Client) CancelScheduledChange(ctx context.Context, id int64) error {
+	return c.call(ctx, "DELETE", fmt.Sprintf("/v2/schedule/%d", id), nil, nil)
+}
+
+//	Proposals gets the proposed changes to protected applications, oldest
+//	first.  The status and application are optional filters
+func (c *Client) Proposals(ctx context.Context, status, application string) ([]datastores.ChangeProposal, error) {
+	proposals := []datastores.ChangeProposal{}
+
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if application != "" {
+		query.Set("application", application)
+	}
+
+	path := "/v2/proposals"
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+
+	err := c.call(ctx, "GET", path, nil, &proposals)
+
+	return proposals, err
+}
+
+//	Proposal gets a proposal.  Returns ErrNotFound if it doesn't exist
+func (c *Client) Proposal(ctx context.Context, id int64) (datastores.ChangeProposal, error) {
+	proposal := datastores.ChangeProposal{}
+	err := c.call(ctx, "GET", fmt.Sprintf("/v2/proposals/%d", id), nil, &proposal)
+
+	return proposal, err
+}
+
+//	ApproveProposal approves a pending proposal, and the server makes the
+//	change.  The reviewer is the basic authentication user
+func (c *Client) ApproveProposal(ctx context.Context, id int64, comment string) (datastores.ChangeProposal, error) {
+	proposal := datastores.ChangeProposal{}
+	err := c.call(ctx, "POST", fmt.Sprintf("/v2/proposals/%d/approve", id), map[string]string{"comment": comment}, &proposal)
+
+	return proposal, err
+}
+
+//	RejectProposal rejects a pending proposal.  The reviewer is the basic
+//	authentication user
+func (c *Client) RejectProposal(ctx context.Context, id int64, comment string) (datastores.ChangeProposal, error) {
+	proposal := datastores.ChangeProposal{}
+	err := c.call(ctx, "POST", fmt.Sprintf("/v2/proposals/%d/reject", id), map[string]string{"comment": comment}, &proposal)
+
+	return proposal, err
+}
+
+//	Calls the server (retrying if necessary) and decodes the response data
+func (c *Client) call(ctx context.Context, method, path string, body interface{}, data interface{}) error {
+	var requestBody []byte
+	if body != nil {
+		var err error
+		if requestBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := c.do(ctx, method, path, requestBody, data)
+		if err == nil || !retry || !isIdempotent(method) || attempt >= c.retries {
+			return err
+		}
+
+		//	Wait (with some jitter) before trying again:
+		jitter := time.Duration(0)
+		if wait > 0 {
+			jitter = time.Duration(rand.Int63n(int64(wait)/2 + 1))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait + jitter):
+		}
+
+		wait = wait * 2
+		if wait > c.maxBackoff {
+			wait = c.maxBackoff
+		}
+	}
+}
+
+//	Makes a single request.  Returns true if the request can be retried
+func (c *Client) do(ctx context.Context, method, path string, body []byte, data interface{}) (bool, error) {
+	req, err := http.NewRequest(method, c.address+path, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	c.addCredentials(req.Header)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		//	Don't retry if we gave up on purpose
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	//	Changes to protected applications are proposed instead:
+	proposal := datastores.ChangeProposal{}
+	if resp.StatusCode == http.StatusAccepted {
+		data = &proposal
+	}
+
+	//	Files (like exports) aren't wrapped in a response:
+	if file, ok := data.(*[]byte); ok && resp.StatusCode < 300 {
+		*file, err = ioutil.ReadAll(resp.Body)
+		return err != nil, err
+	}
+
+	//	Some responses (like a 204) don't have a body:
+	response := datastores.ConfigResponse{Data: data}
+	if resp.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		if err != nil && err != io.EOF {
+			return resp.StatusCode >= 500, &Error{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected response: %s", err)}
+		}
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, &Error{StatusCode: resp.StatusCode, Code: response.Code, Message: strings.TrimPrefix(response.Message, "Error: ")}
+	case resp.StatusCode >= 300:
+		return false, &Error{StatusCode: resp.StatusCode, Code: response.Code, Message: strings.TrimPrefix(response.Message, "Error: ")}
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		return false, &ProposalError{Proposal: proposal}
+	}
+
+	return false, nil
+}
+
+//	Returns true if making the request more than once has the same effect as
+//	making it once, so it's safe to retry
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "PUT", "DELETE":
+		return true
+	}
+
+	return false
+}
+
+//	Adds credentials to the request headers (if we have them)
+func (c *Client) addCredentials(header http.Header) {
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	} else if c.user != "" {
+		req := http.Request{Header: header}
+		req.SetBasicAuth(c.user, c.password)
+	}
+}
+
+//	Gets the v2 API path for a config item
+func configItemPath(application, name, machine string) string {
+	path := fmt.Sprintf("/v2/apps/%s/config/%s", url.PathEscape(application), url.PathEscape(name))
+	if machine != "" {
+		path = path + "?machine=" + url.QueryEscape(machine)
+	}
+
+	return path
+}
@@ -0,0 +1,504 @@
+package client_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/api"
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//	Starts a test server using the real API handlers and a BoltDB datastore
+func getTestServer(filename string) *httptest.Server {
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/config/getall", api.GetAllConfig)
+	router.HandleFunc("/config/export", api.ExportConfig).Methods("GET")
+	router.HandleFunc("/v2/apps", api.ListApplications).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config", api.ListAppConfig).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", api.GetAppConfigItem).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", api.PutAppConfigItem).Methods("PUT")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", api.DeleteAppConfigItem).Methods("DELETE")
+	router.HandleFunc("/v2/schedule", api.GetScheduledChanges).Methods("GET")
+	router.HandleFunc("/v2/schedule", api.ScheduleChange).Methods("POST")
+	router.HandleFunc("/v2/schedule/{id}", api.CancelScheduledChange).Methods("DELETE")
+	router.HandleFunc("/v2/proposals", api.GetProposals).Methods("GET")
+	router.HandleFunc("/v2/proposals/{id}", api.GetProposal).Methods("GET")
+	router.HandleFunc("/v2/proposals/{id}/approve", api.ApproveProposal).Methods("POST")
+	router.HandleFunc("/v2/proposals/{id}/reject", api.RejectProposal).Methods("POST")
+	router.Handle("/ws", api.WsHandler{H: api.WsHub})
+
+	return httptest.NewServer(router)
+}
+
+//	Client set then get should work
+func TestClient_Set_ThenGet_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+
+	//	Act
+	_, err := c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Value: "Value1"})
+	if err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+
+	item, err := c.Get(ctx, "MyTestAppName", "TestItem1", "")
+
+	//	Assert
+	if err != nil {
+		t.Errorf("Get failed: %s", err)
+	}
+
+	if item.Value != "Value1" {
+		t.Errorf("Get failed: Should have returned Value1 but returned %s", item.Value)
+	}
+}
+
+//	Client get should return ErrNotFound for missing items
+func TestClient_Get_ItemDoesntExist_NotFound(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+
+	//	Act
+	_, err := c.Get(context.Background(), "MyTestAppName", "Missing", "")
+
+	//	Assert
+	if err != client.ErrNotFound {
+		t.Errorf("Get failed: Should have returned ErrNotFound but returned %v", err)
+	}
+}
+
+//	Client remove should remove the item
+func TestClient_Remove_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	item := datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Machine: "WEB01", Value: "Value1"}
+	c.Set(ctx, item)
+
+	//	Act
+	err := c.Remove(ctx, item)
+
+	//	Assert
+	if err != nil {
+		t.Errorf("Remove failed: %s", err)
+	}
+
+	if err := c.Remove(ctx, item); err != client.ErrNotFound {
+		t.Errorf("Remove failed: Removing twice should return ErrNotFound but returned %v", err)
+	}
+}
+
+//	Client GetAll and Applications should list everything that was set
+func TestClient_GetAll_Applications_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	c.Set(ctx, datastores.ConfigItem{Application: "*", Name: "Global", Value: "global"})
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Value: "Value1"})
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "TestItem1", Machine: "WEB01", Value: "machine"})
+	c.Set(ctx, datastores.ConfigItem{Application: "OtherAppName", Name: "TestItem2", Value: "Value2"})
+
+	//	Act
+	items, err := c.GetAll(ctx, "MyTestAppName")
+	resolved, resolveErr := c.GetResolved(ctx, "MyTestAppName", "WEB01")
+	applications, appErr := c.Applications(ctx)
+
+	//	Assert
+	if err != nil || resolveErr != nil || appErr != nil {
+		t.Fatalf("GetAll failed: %v / %v / %v", err, resolveErr, appErr)
+	}
+
+	if len(items) != 3 {
+		t.Errorf("GetAll failed: Should have returned 3 items but returned %d", len(items))
+	}
+
+	if len(resolved) != 2 || resolved[1].Value != "machine" {
+		t.Errorf("GetResolved failed: Should have resolved the machine item: %+v", resolved)
+	}
+
+	if len(applications) != 3 {
+		t.Errorf("Applications failed: Should have returned 3 applications but returned %v", applications)
+	}
+}
+
+//	Typed accessors should convert values
+func TestClient_TypedAccessors_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "Count", Value: "42"})
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "Enabled", Value: "true"})
+	c.Set(ctx, datastores.ConfigItem{Application: "MyTestAppName", Name: "Timeout", Value: "1m30s"})
+
+	//	Act
+	count, countErr := c.GetInt(ctx, "MyTestAppName", "Count", "")
+	enabled, enabledErr := c.GetBool(ctx, "MyTestAppName", "Enabled", "")
+	timeout, timeoutErr := c.GetDuration(ctx, "MyTestAppName", "Timeout", "")
+	_, badErr := c.GetInt(ctx, "MyTestAppName", "Enabled", "")
+
+	//	Assert
+	if countErr != nil || count != 42 {
+		t.Errorf("GetInt failed: %d / %v", count, countErr)
+	}
+
+	if enabledErr != nil || !enabled {
+		t.Errorf("GetBool failed: %v / %v", enabled, enabledErr)
+	}
+
+	if timeoutErr != nil || timeout != 90*time.Second {
+		t.Errorf("GetDuration failed: %s / %v", timeout, timeoutErr)
+	}
+
+	if badErr == nil {
+		t.Errorf("GetInt failed: Should have returned an error for a non-integer value")
+	}
+}
+
+//	Server errors should be retried
+func TestClient_ServerError_Retried(t *testing.T) {
+	//	Arrange
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte(`{"status":200,"message":"Applications found","data":["MyTestAppName"]}`))
+	}))
+	defer server.Close()
+
+	c, _ := client.New(server.URL, client.WithRetries(3, time.Millisecond, 10*time.Millisecond))
+
+	//	Act
+	applications, err := c.Applications(context.Background())
+
+	//	Assert
+	if err != nil {
+		t.Errorf("Applications failed: Should have succeeded after retrying: %s", err)
+	}
+
+	if len(applications) != 1 || atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Applications failed: Should have tried 3 times: %d attempts, %v", attempts, applications)
+	}
+}
+
+//	A POST that failed shouldn't be retried, since it may have been made
+func TestClient_ServerError_PostNotRetried(t *testing.T) {
+	//	Arrange
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c, _ := client.New(server.URL, client.WithRetries(3, time.Millisecond, 10*time.Millisecond))
+
+	//	Act
+	_, err := c.ScheduleChange(context.Background(), datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "true", Effective: time.Now().Add(time.Hour)})
+
+	//	Assert
+	if err == nil || atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("ScheduleChange failed: Should have tried once: %d attempts, %v", attempts, err)
+	}
+}
+
+//	A TLS configuration should be applied to the configured HTTP client
+//	instead of replacing it
+func TestClient_TLSConfig_KeepsHTTPClient(t *testing.T) {
+	//	Arrange
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"status":200,"message":"Applications found","data":["MyTestAppName"]}`))
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	var proxied int32
+	httpClient := &http.Client{Transport: &http.Transport{Proxy: func(req *http.Request) (*url.URL, error) {
+		atomic.AddInt32(&proxied, 1)
+		return nil, nil
+	}}}
+
+	//	The options shouldn't depend on their order:
+	tlsOption := client.WithTLSConfig(&tls.Config{RootCAs: roots})
+	clientOption := client.WithHTTPClient(httpClient)
+	timeoutOption := client.WithTimeout(5 * time.Second)
+	orders := [][]client.Option{
+		{clientOption, tlsOption, timeoutOption},
+		{timeoutOption, tlsOption, clientOption},
+	}
+
+	for i, opts := range orders {
+		c, err := client.New(server.URL, opts...)
+		if err != nil {
+			t.Fatalf("New failed: %s", err)
+		}
+
+		//	Act
+		applications, err := c.Applications(context.Background())
+
+		//	Assert
+		if err != nil || len(applications) != 1 {
+			t.Errorf("Applications failed: Should have trusted the server (order %d): %v / %v", i, applications, err)
+		}
+
+		if atomic.LoadInt32(&proxied) != int32(i+1) {
+			t.Errorf("Applications failed: Should have used the configured HTTP client's transport (order %d)", i)
+		}
+	}
+
+	if httpClient.Timeout != 0 {
+		t.Errorf("New failed: Shouldn't have changed the configured HTTP client's timeout: %v", httpClient.Timeout)
+	}
+}
+
+//	A TLS configuration can't be applied to a custom transport
+func TestClient_TLSConfig_CustomTransport_Error(t *testing.T) {
+	//	Arrange
+	httpClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, nil
+	})}
+
+	//	Act
+	_, err := client.New("https://config.example.com", client.WithHTTPClient(httpClient), client.WithTLSConfig(&tls.Config{}))
+
+	//	Assert
+	if err == nil {
+		t.Errorf("New failed: Should have returned an error for the custom transport")
+	}
+}
+
+//	An http.RoundTripper made from a function
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+//	Cancelling the context should stop retries
+func TestClient_ContextCancelled_StopsRetrying(t *testing.T) {
+	//	Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c, _ := client.New(server.URL, client.WithRetries(100, 50*time.Millisecond, time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	//	Act
+	start := time.Now()
+	_, err := c.Applications(ctx)
+
+	//	Assert
+	if err != context.DeadlineExceeded {
+		t.Errorf("Applications failed: Should have returned the context error but returned %v", err)
+	}
+
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Applications failed: Should have stopped retrying when the context was done")
+	}
+}
+
+//	Server errors should include the server's error code
+func TestClient_Conflict_ErrorCode(t *testing.T) {
+	//	Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusConflict)
+		rw.Write([]byte(`{"status":409,"code":"conflict","message":"A config item with that application, machine and name already exists","data":null}`))
+	}))
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+
+	//	Act
+	_, err := c.Applications(context.Background())
+
+	//	Assert
+	clientErr, ok := err.(*client.Error)
+	if !ok || clientErr.StatusCode != http.StatusConflict || clientErr.Code != datastores.CodeConflict {
+		t.Errorf("Applications failed: Should have returned the conflict error: %v", err)
+	}
+}
+
+//	Client should be able to schedule, list and cancel changes
+func TestClient_ScheduleChange_ThenCancel_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	effective := time.Now().Add(time.Hour)
+
+	//	Act
+	scheduled, err := c.ScheduleChange(ctx, datastores.ScheduledChange{Application: "MyTestAppName", Name: "Maintenance", Value: "true", Effective: effective})
+	if err != nil {
+		t.Fatalf("ScheduleChange failed: %s", err)
+	}
+
+	changes, listErr := c.ScheduledChanges(ctx, "MyTestAppName")
+	cancelErr := c.CancelScheduledChange(ctx, scheduled.Id)
+
+	//	Assert
+	if listErr != nil || len(changes) != 1 || changes[0].Id != scheduled.Id || !changes[0].Effective.Equal(effective) {
+		t.Errorf("ScheduledChanges failed: Should have listed the change: %+v / %v", changes, listErr)
+	}
+
+	if cancelErr != nil {
+		t.Errorf("CancelScheduledChange failed: %s", cancelErr)
+	}
+
+	if err := c.CancelScheduledChange(ctx, scheduled.Id); err != client.ErrNotFound {
+		t.Errorf("CancelScheduledChange failed: Cancelling twice should return ErrNotFound but returned %v", err)
+	}
+}
+
+//	Client changes to a protected application should be proposed, and made
+//	once someone else approves them
+func TestClient_Set_Protected_ThenApprove_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	api.SetProtectedApplications([]string{"prod-*"})
+	defer api.SetProtectedApplications(nil)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err := api.SetApprovalAuth(api.ApprovalAuth{Users: map[string]string{"alice": string(hash), "bob": string(hash)}}); err != nil {
+		t.Fatalf("SetApprovalAuth failed: %s", err)
+	}
+	defer api.SetApprovalAuth(api.ApprovalAuth{})
+
+	proposer, _ := client.New(server.URL, client.WithBasicAuth("bob", "secret"))
+	reviewer, _ := client.New(server.URL, client.WithBasicAuth("alice", "secret"))
+	ctx := context.Background()
+
+	//	Act
+	_, err := proposer.Set(ctx, datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "60"})
+
+	//	Assert
+	var proposed *client.ProposalError
+	if !errors.As(err, &proposed) || proposed.Proposal.Status != datastores.ProposalPending {
+		t.Fatalf("Set failed: Should have returned a ProposalError but returned %v", err)
+	}
+
+	if _, err := proposer.Get(ctx, "prod-billing", "Timeout", ""); err != client.ErrNotFound {
+		t.Errorf("Get failed: The change shouldn't have been made yet: %v", err)
+	}
+
+	pending, err := reviewer.Proposals(ctx, datastores.ProposalPending, "prod-billing")
+	if err != nil || len(pending) != 1 || pending[0].Id != proposed.Proposal.Id {
+		t.Errorf("Proposals failed: Should have listed the proposal: %+v / %v", pending, err)
+	}
+
+	approved, err := reviewer.ApproveProposal(ctx, proposed.Proposal.Id, "Looks good")
+	if err != nil || approved.Status != datastores.ProposalApproved || approved.ReviewedBy != "alice" {
+		t.Fatalf("ApproveProposal failed: %+v / %v", approved, err)
+	}
+
+	if item, err := proposer.Get(ctx, "prod-billing", "Timeout", ""); err != nil || item.Value != "60" {
+		t.Errorf("Get failed: Should have made the approved change: %+v / %v", item, err)
+	}
+
+	if _, err := reviewer.Proposal(ctx, proposed.Proposal.Id+1); err != client.ErrNotFound {
+		t.Errorf("Proposal failed: Should have returned ErrNotFound but returned %v", err)
+	}
+}
+
+//	Client export should render the filtered config as a file
+func TestClient_Export_Filtered_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+
+	for _, item := range []datastores.ConfigItem{
+		{Application: "*", Name: "db.primary.port", Value: "3306", Labels: map[string]string{"owner": "payments"}},
+		{Application: "billing", Name: "db.primary.host", Value: "db01.example.com", Labels: map[string]string{"owner": "payments"}},
+		{Application: "billing", Name: "db.primary.user", Value: "billing", Labels: map[string]string{"owner": "dba"}},
+		{Application: "billing", Name: "Timeout", Value: "30", Labels: map[string]string{"owner": "payments"}},
+	} {
+		if _, err := c.Set(ctx, item); err != nil {
+			t.Fatalf("Set failed: %s", err)
+		}
+	}
+
+	//	Act
+	output, err := c.Export(ctx, "billing", client.ExportOptions{Format: "env", Prefix: "db.primary", Labels: map[string]string{"owner": "payments"}})
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+
+	if exported := string(output); !strings.Contains(exported, "db01.example.com") || !strings.Contains(exported, "3306") || strings.Contains(exported, "dba") || strings.Contains(exported, "30\n") {
+		t.Errorf("Export failed: Should have exported the filtered items: %s", exported)
+	}
+
+	if _, err := c.Export(ctx, "billing", client.ExportOptions{Format: "xml"}); err == nil {
+		t.Errorf("Export failed: Should have returned an error for an unknown format")
+	}
+}
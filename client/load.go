@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	LoadError reports the config items that couldn't be loaded into a struct.
+//	Everything else is still loaded
+type LoadError struct {
+	//	Names of required config items that weren't found
+	Missing []string
+
+	//	Config items with values that couldn't be converted
+	Invalid []InvalidValue
+}
+
+//	InvalidValue is a config item value that couldn't be converted to its field's type
+type InvalidValue struct {
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e *LoadError) Error() string {
+	problems := []string{}
+	if len(e.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing required items: %s", strings.Join(e.Missing, ", ")))
+	}
+
+	for _, invalid := range e.Invalid {
+		problems = append(problems, fmt.Sprintf("invalid value '%s' for %s: %s", invalid.Value, invalid.Name, invalid.Err))
+	}
+
+	return "centralconfig: " + strings.Join(problems, "; ")
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+//	Load gets the effective config items for the application and loads them into
+//	the struct that target points to.  See Unmarshal for how fields are matched
+func (c *Client) Load(ctx context.Context, application string, target interface{}) error {
+	return c.LoadForMachine(ctx, application, "", target)
+}
+
+//	LoadForMachine is like Load, but uses the config items for the machine where they exist
+func (c *Client) LoadForMachine(ctx context.Context, application, machine string, target interface{}) error {
+	items, err := c.GetResolved(ctx, application, machine)
+	if err != nil {
+		return err
+	}
+
+	return Unmarshal(items, target)
+}
+
+//	Load loads the cached config items into the struct that target points to.
+//	See Unmarshal for how fields are matched
+func (cache *Cache) Load(target interface{}) error {
+	return Unmarshal(cache.Items(), target)
+}
+
+//	Unmarshal loads config items into the struct that target points to.
+//
+//	Each exported field is matched with the config item named in its config tag
+//	(or the field name if there isn't one).  Nested structs use their name as a
+//	prefix, so the Host field of a Database struct is loaded from Database.Host.
+//	Tag options:
+//
+//		Host    string        `config:"database.host,required"`
+//		Timeout time.Duration `config:"timeout" default:"30s"`
+//		Hosts   []string      `config:"hosts"`  // comma separated
+//		Ignored string        `config:"-"`
+//
+//	Strings, bools, ints, uints, floats, durations, RFC 3339 times, slices of any
+//	of these, pointers and types that implement encoding.TextUnmarshaler are supported.
+//	Fields without a matching item (or a default) are left alone.  If any required
+//	items are missing or any values can't be converted, a *LoadError is returned
+func Unmarshal(items []datastores.ConfigItem, target interface{}) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("centralconfig: target must be a pointer to a struct, not %T", target)
+	}
+
+	values := make(map[string]string)
+	for _, item := range items {
+		values[item.Name] = item.Value
+	}
+
+	loadErr := &LoadError{}
+	if err := unmarshalStruct(values, "", value.Elem(), loadErr); err != nil {
+		return err
+	}
+
+	if len(loadErr.Missing) > 0 || len(loadErr.Invalid) > 0 {
+		return loadErr
+	}
+
+	return nil
+}
+
+//	Loads the values into each field of a struct
+func unmarshalStruct(values map[string]string, prefix string, value reflect.Value, loadErr *LoadError) error {
+	valueType := value.Type()
+
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("config")
+		if tag == "-" {
+			continue
+		}
+
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma+1:]
+		}
+
+		required := false
+		for _, option := range strings.Split(options, ",") {
+			switch strings.TrimSpace(option) {
+			case "":
+			case "required":
+				required = true
+			default:
+				return fmt.Errorf("centralconfig: unknown config tag option '%s' on field %s", option, field.Name)
+			}
+		}
+
+		fieldValue := value.Field(i)
+
+		//	Nested structs (that don't convert from a single value) load their own fields.
+		//	Embedded structs without a name share our prefix:
+		if isNestedStruct(field.Type) {
+			nestedPrefix := joinConfigName(prefix, name)
+			if name == "" && !field.Anonymous {
+				nestedPrefix = joinConfigName(prefix, field.Name)
+			}
+
+			if field.Type.Kind() == reflect.Ptr {
+				//	Only create missing structs if there's something to put in them:
+				if fieldValue.IsNil() && (!fieldValue.CanSet() || !hasConfigPrefix(values, nestedPrefix)) {
+					continue
+				}
+				if fieldValue.IsNil() {
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+
+			if err := unmarshalStruct(values, nestedPrefix, fieldValue, loadErr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		name = joinConfigName(prefix, name)
+
+		raw, ok := values[name]
+		if !ok {
+			raw, ok = field.Tag.Lookup("default")
+		}
+
+		if !ok {
+			if required {
+				loadErr.Missing = append(loadErr.Missing, name)
+			}
+			continue
+		}
+
+		if err := setFieldValue(fieldValue, raw); err != nil {
+			loadErr.Invalid = append(loadErr.Invalid, InvalidValue{Name: name, Value: raw, Err: err})
+		}
+	}
+
+	return nil
+}
+
+//	Returns true if the type is a struct that's loaded field by field
+func isNestedStruct(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	return fieldType.Kind() == reflect.Struct && !reflect.PtrTo(fieldType).Implements(textUnmarshalerType)
+}
+
+//	Returns true if there are any values under the prefix
+func hasConfigPrefix(values map[string]string, prefix string) bool {
+	for name := range values {
+		if prefix == "" || strings.HasPrefix(name, prefix+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+//	Joins a config name to its prefix
+func joinConfigName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	if name == "" {
+		return prefix
+	}
+
+	return prefix + "." + name
+}
+
+//	Converts the raw value and sets the field to it
+func setFieldValue(field reflect.Value, raw string) error {
+	//	Types that know how to convert themselves:
+	if field.CanAddr() && field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(raw), 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(strings.TrimSpace(raw), 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+
+	case reflect.Slice:
+		//	Slices come from comma separated lists:
+		slice := reflect.MakeSlice(field.Type(), 0, 0)
+		if strings.TrimSpace(raw) != "" {
+			for _, part := range strings.Split(raw, ",") {
+				element := reflect.New(field.Type().Elem()).Elem()
+				if err := setFieldValue(element, strings.TrimSpace(part)); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, element)
+			}
+		}
+		field.Set(slice)
+
+	case reflect.Ptr:
+		element := reflect.New(field.Type().Elem())
+		if err := setFieldValue(element.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(element)
+
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
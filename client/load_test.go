@@ -0,0 +1,166 @@
+package client_test
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+type testDatabaseConfig struct {
+	Host string `config:"host,required"`
+	Port int    `config:"port" default:"5432"`
+}
+
+type testBillingConfig struct {
+	Name     string
+	Timeout  time.Duration      `config:"request.timeout"`
+	Retries  uint8              `config:"retries"`
+	Rate     float64            `config:"rate"`
+	Enabled  bool               `config:"enabled"`
+	Hosts    []string           `config:"hosts"`
+	Ports    []int              `config:"ports"`
+	Started  time.Time          `config:"started"`
+	Limit    *int               `config:"limit"`
+	Database testDatabaseConfig `config:"database"`
+	Replica  *testDatabaseConfig
+	Ignored  string `config:"-"`
+}
+
+//	Unmarshal should convert values into each field type
+func TestUnmarshal_AllTypes_Successful(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Name: "Name", Value: "billing"},
+		{Name: "request.timeout", Value: "1m30s"},
+		{Name: "retries", Value: "5"},
+		{Name: "rate", Value: "0.25"},
+		{Name: "enabled", Value: "true"},
+		{Name: "hosts", Value: "a.example.com, b.example.com"},
+		{Name: "ports", Value: "80,443"},
+		{Name: "started", Value: "2017-03-01T10:00:00Z"},
+		{Name: "limit", Value: "10"},
+		{Name: "database.host", Value: "db01"},
+		{Name: "Replica.host", Value: "db02"},
+		{Name: "Replica.port", Value: "6543"},
+		{Name: "Ignored", Value: "shouldn't be set"}}
+	cfg := testBillingConfig{}
+
+	//	Act
+	err := client.Unmarshal(items, &cfg)
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %s", err)
+	}
+
+	if cfg.Name != "billing" || cfg.Timeout != 90*time.Second || cfg.Retries != 5 || cfg.Rate != 0.25 || !cfg.Enabled {
+		t.Errorf("Unmarshal failed: Scalar values weren't converted: %+v", cfg)
+	}
+
+	if !reflect.DeepEqual(cfg.Hosts, []string{"a.example.com", "b.example.com"}) || !reflect.DeepEqual(cfg.Ports, []int{80, 443}) {
+		t.Errorf("Unmarshal failed: Slices weren't converted: %v / %v", cfg.Hosts, cfg.Ports)
+	}
+
+	if cfg.Started.Year() != 2017 || cfg.Limit == nil || *cfg.Limit != 10 {
+		t.Errorf("Unmarshal failed: Time or pointer weren't converted: %s / %v", cfg.Started, cfg.Limit)
+	}
+
+	if cfg.Database.Host != "db01" || cfg.Database.Port != 5432 {
+		t.Errorf("Unmarshal failed: Nested struct should use the value and default: %+v", cfg.Database)
+	}
+
+	if cfg.Replica == nil || cfg.Replica.Host != "db02" || cfg.Replica.Port != 6543 {
+		t.Errorf("Unmarshal failed: Nested struct pointer should be loaded: %+v", cfg.Replica)
+	}
+
+	if cfg.Ignored != "" {
+		t.Errorf("Unmarshal failed: Ignored field shouldn't be set")
+	}
+}
+
+//	Unmarshal should report missing and invalid items
+func TestUnmarshal_MissingAndInvalid_ReturnsLoadError(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Name: "Name", Value: "billing"},
+		{Name: "retries", Value: "lots"},
+		{Name: "ports", Value: "80,https"},
+		{Name: "Replica.host", Value: "db02"}}
+	cfg := testBillingConfig{}
+
+	//	Act
+	err := client.Unmarshal(items, &cfg)
+
+	//	Assert
+	loadErr, ok := err.(*client.LoadError)
+	if !ok {
+		t.Fatalf("Unmarshal failed: Should have returned a LoadError but returned %v", err)
+	}
+
+	if !reflect.DeepEqual(loadErr.Missing, []string{"database.host"}) {
+		t.Errorf("Unmarshal failed: database.host should be missing: %v", loadErr.Missing)
+	}
+
+	if len(loadErr.Invalid) != 2 || loadErr.Invalid[0].Name != "retries" || loadErr.Invalid[1].Name != "ports" {
+		t.Errorf("Unmarshal failed: retries and ports should be invalid: %+v", loadErr.Invalid)
+	}
+
+	if cfg.Name != "billing" || cfg.Replica.Host != "db02" {
+		t.Errorf("Unmarshal failed: Valid items should still be loaded: %+v", cfg)
+	}
+}
+
+//	Unmarshal should only accept a pointer to a struct
+func TestUnmarshal_NotStructPointer_ReturnsError(t *testing.T) {
+	//	Arrange
+	cfg := testBillingConfig{}
+
+	//	Act
+	err := client.Unmarshal(nil, cfg)
+
+	//	Assert
+	if err == nil {
+		t.Errorf("Unmarshal failed: Should have returned an error for a non-pointer")
+	}
+}
+
+//	Load should use the resolved config items for the application
+func TestClient_Load_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	c.Set(ctx, datastores.ConfigItem{Application: "*", Name: "database.host", Value: "global"})
+	c.Set(ctx, datastores.ConfigItem{Application: "billing", Name: "database.host", Value: "billing"})
+	c.Set(ctx, datastores.ConfigItem{Application: "billing", Name: "database.host", Machine: "WEB01", Value: "machine"})
+
+	//	Act
+	cfg := testBillingConfig{}
+	err := c.Load(ctx, "billing", &cfg)
+
+	machineCfg := testBillingConfig{}
+	machineErr := c.LoadForMachine(ctx, "billing", "WEB01", &machineCfg)
+
+	//	Assert
+	if err != nil || machineErr != nil {
+		t.Fatalf("Load failed: %v / %v", err, machineErr)
+	}
+
+	if cfg.Database.Host != "billing" {
+		t.Errorf("Load failed: Should have used the application value but used %s", cfg.Database.Host)
+	}
+
+	if machineCfg.Database.Host != "machine" {
+		t.Errorf("LoadForMachine failed: Should have used the machine value but used %s", machineCfg.Database.Host)
+	}
+}
@@ -0,0 +1,288 @@
+//	Package remote makes centralconfig available as a viper remote provider.
+//	Import it and add the provider with the server address and application
+//	(or application/machine) as the path:
+//
+//		import _ "github.com/cagedtornado/centralconfig/client/remote"
+//
+//		viper.AddRemoteProvider("centralconfig", "http://localhost:3000", "billing/WEB01")
+//		viper.SetConfigType("json")
+//		err := viper.ReadRemoteConfig()
+//
+//	WatchRemoteConfig blocks until the configuration changes on the server
+//	(changes are followed over the server's WebSocket) and then reloads it
+package remote
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/formats"
+	"github.com/spf13/viper"
+)
+
+//	Provider is the name used with viper.AddRemoteProvider
+const Provider = "centralconfig"
+
+//	The viper remote config interface (viper doesn't export it)
+type remoteConfigFactory interface {
+	Get(rp viper.RemoteProvider) (io.Reader, error)
+	Watch(rp viper.RemoteProvider) (io.Reader, error)
+	WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool)
+}
+
+//	Reads config from centralconfig and passes other providers on to the
+//	remote config support that was registered before us (if any)
+type remoteConfigProvider struct {
+	next remoteConfigFactory
+
+	mu       sync.Mutex
+	opts     []client.Option
+	watchers map[string]*watcher
+}
+
+//	Follows the config for one endpoint and path
+type watcher struct {
+	cache *client.Cache
+
+	mu       sync.Mutex
+	version  int64
+	returned int64
+	changed  chan struct{}
+}
+
+var provider = &remoteConfigProvider{watchers: make(map[string]*watcher)}
+
+func init() {
+	Register()
+}
+
+//	Register sets the client options (credentials, TLS and so on) used to talk to
+//	the server and makes sure the provider is registered with viper.  It's called
+//	with no options when the package is imported.  Call it again after importing
+//	github.com/spf13/viper/remote if you use both, since that package replaces
+//	viper's remote config support when it's imported
+func Register(opts ...client.Option) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if len(opts) > 0 {
+		provider.opts = opts
+	}
+
+	if current, ok := viper.RemoteConfig.(*remoteConfigProvider); !ok || current != provider {
+		provider.next = viper.RemoteConfig
+		viper.RemoteConfig = provider
+	}
+
+	for _, name := range viper.SupportedRemoteProviders {
+		if name == Provider {
+			return
+		}
+	}
+	viper.SupportedRemoteProviders = append(viper.SupportedRemoteProviders, Provider)
+}
+
+//	Get reads the resolved config for the application
+func (p *remoteConfigProvider) Get(rp viper.RemoteProvider) (io.Reader, error) {
+	if rp.Provider() != Provider {
+		return p.nextProvider().Get(rp)
+	}
+
+	c, err := p.getClient(rp)
+	if err != nil {
+		return nil, err
+	}
+
+	application, machine := parsePath(rp.Path())
+	items, err := c.GetResolved(context.Background(), application, machine)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encodeItems(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+//	Watch waits for the config to change and then reads it.  The first call
+//	returns right away (so nothing is missed between reading and watching)
+func (p *remoteConfigProvider) Watch(rp viper.RemoteProvider) (io.Reader, error) {
+	if rp.Provider() != Provider {
+		return p.nextProvider().Watch(rp)
+	}
+
+	w, err := p.getWatcher(rp)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		w.mu.Lock()
+		version, changed := w.version, w.changed
+		if version != w.returned {
+			w.returned = version
+			w.mu.Unlock()
+
+			data, err := encodeItems(w.cache.Items())
+			if err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(data), nil
+		}
+		w.mu.Unlock()
+
+		<-changed
+	}
+}
+
+//	WatchChannel sends the config each time it changes, until something is
+//	sent to (or the caller closes) the quit channel
+func (p *remoteConfigProvider) WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool) {
+	if rp.Provider() != Provider {
+		return p.nextProvider().WatchChannel(rp)
+	}
+
+	responses := make(chan *viper.RemoteResponse)
+	quit := make(chan bool)
+
+	go func() {
+		w, err := p.getWatcher(rp)
+		if err != nil {
+			select {
+			case responses <- &viper.RemoteResponse{Error: err}:
+			case <-quit:
+			}
+			return
+		}
+
+		version := int64(-1)
+		for {
+			w.mu.Lock()
+			current, changed := w.version, w.changed
+			w.mu.Unlock()
+
+			if current != version {
+				version = current
+				data, err := encodeItems(w.cache.Items())
+				response := &viper.RemoteResponse{Value: data, Error: err}
+
+				select {
+				case responses <- response:
+				case <-quit:
+					return
+				}
+				continue
+			}
+
+			select {
+			case <-changed:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return responses, quit
+}
+
+//	Gets the remote config support for other providers
+func (p *remoteConfigProvider) nextProvider() remoteConfigFactory {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next == nil {
+		return unsupportedProvider{}
+	}
+
+	return p.next
+}
+
+//	Gets a client for the provider endpoint
+func (p *remoteConfigProvider) getClient(rp viper.RemoteProvider) (*client.Client, error) {
+	p.mu.Lock()
+	opts := p.opts
+	p.mu.Unlock()
+
+	return client.New(rp.Endpoint(), opts...)
+}
+
+//	Gets the watcher for the provider endpoint and path (starting it if it isn't running yet)
+func (p *remoteConfigProvider) getWatcher(rp viper.RemoteProvider) (*watcher, error) {
+	key := rp.Endpoint() + "|" + rp.Path()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if w, ok := p.watchers[key]; ok {
+		return w, nil
+	}
+
+	c, err := client.New(rp.Endpoint(), p.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	application, machine := parsePath(rp.Path())
+	w := &watcher{cache: c.NewCache(application, machine, ""), returned: -1, changed: make(chan struct{})}
+	w.cache.OnChange(func(change datastores.ConfigChange) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		w.version++
+		close(w.changed)
+		w.changed = make(chan struct{})
+	})
+
+	if err := w.cache.Start(context.Background()); err != nil {
+		return nil, err
+	}
+
+	p.watchers[key] = w
+	return w, nil
+}
+
+//	Splits the provider path into the application and machine
+func parsePath(path string) (string, string) {
+	path = strings.Trim(path, "/")
+	if slash := strings.LastIndex(path, "/"); slash >= 0 {
+		return path[:slash], path[slash+1:]
+	}
+
+	return path, ""
+}
+
+//	Encodes config items as JSON.  Dotted names are nested (so viper.Sub works)
+//	unless a name is both a value and a parent, in which case they're left flat
+func encodeItems(items []datastores.ConfigItem) ([]byte, error) {
+	data, err := formats.Encode(items, formats.JSON, formats.Options{Nest: true})
+	if err != nil {
+		return formats.Encode(items, formats.JSON, formats.Options{})
+	}
+
+	return data, nil
+}
+
+//	Used for other providers when viper's own remote support isn't imported
+type unsupportedProvider struct{}
+
+func (unsupportedProvider) Get(rp viper.RemoteProvider) (io.Reader, error) {
+	return nil, viper.UnsupportedRemoteProviderError(rp.Provider())
+}
+
+func (unsupportedProvider) Watch(rp viper.RemoteProvider) (io.Reader, error) {
+	return nil, viper.UnsupportedRemoteProviderError(rp.Provider())
+}
+
+func (unsupportedProvider) WatchChannel(rp viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool) {
+	responses := make(chan *viper.RemoteResponse, 1)
+	responses <- &viper.RemoteResponse{Error: viper.UnsupportedRemoteProviderError(rp.Provider())}
+	return responses, make(chan bool)
+}
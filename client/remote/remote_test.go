@@ -0,0 +1,106 @@
+package remote_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/api"
+	"github.com/cagedtornado/centralconfig/client"
+	_ "github.com/cagedtornado/centralconfig/client/remote"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+//	Starts a test server using the real API handlers and a BoltDB datastore
+func getTestServer(filename string) *httptest.Server {
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v2/apps/{app}/config", api.ListAppConfig).Methods("GET")
+	router.HandleFunc("/v2/apps/{app}/config/{name}", api.PutAppConfigItem).Methods("PUT")
+	router.Handle("/ws", api.WsHandler{H: api.WsHub})
+
+	return httptest.NewServer(router)
+}
+
+//	Viper should read the resolved config and follow changes
+func TestRemoteProvider_ReadAndWatch_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	c, _ := client.New(server.URL)
+	ctx := context.Background()
+	c.Set(ctx, datastores.ConfigItem{Application: "*", Name: "database.port", Value: "5432"})
+	c.Set(ctx, datastores.ConfigItem{Application: "billing", Name: "database.host", Value: "db01"})
+	c.Set(ctx, datastores.ConfigItem{Application: "billing", Name: "database.host", Machine: "WEB01", Value: "db02"})
+
+	v := viper.New()
+	v.SetConfigType("json")
+	if err := v.AddRemoteProvider("centralconfig", server.URL, "billing/WEB01"); err != nil {
+		t.Fatalf("AddRemoteProvider failed: %s", err)
+	}
+
+	//	Act
+	err := v.ReadRemoteConfig()
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("ReadRemoteConfig failed: %s", err)
+	}
+
+	if v.GetString("database.host") != "db02" || v.GetInt("database.port") != 5432 {
+		t.Errorf("ReadRemoteConfig failed: Should have read the resolved config: %v", v.AllSettings())
+	}
+
+	//	The first watch returns right away:
+	if err := v.WatchRemoteConfig(); err != nil {
+		t.Fatalf("WatchRemoteConfig failed: %s", err)
+	}
+
+	//	The next one waits for a change:
+	watched := make(chan error)
+	go func() {
+		watched <- v.WatchRemoteConfig()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	c.Set(ctx, datastores.ConfigItem{Application: "billing", Name: "database.host", Machine: "WEB01", Value: "db03"})
+
+	select {
+	case err := <-watched:
+		if err != nil {
+			t.Fatalf("WatchRemoteConfig failed: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("WatchRemoteConfig failed: Timed out waiting for the change")
+	}
+
+	if v.GetString("database.host") != "db03" {
+		t.Errorf("WatchRemoteConfig failed: Should have followed the change but was %s", v.GetString("database.host"))
+	}
+}
+
+//	Other providers shouldn't be handled by centralconfig
+func TestRemoteProvider_OtherProvider_ReturnsError(t *testing.T) {
+	//	Arrange
+	v := viper.New()
+	v.SetConfigType("json")
+	v.AddRemoteProvider("consul", "localhost:8500", "billing")
+
+	//	Act
+	err := v.ReadRemoteConfig()
+
+	//	Assert
+	if err == nil {
+		t.Errorf("ReadRemoteConfig failed: Should have returned an error for an unsupported provider")
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//	GetString gets the value of a config item
+func (c *Client) GetString(ctx context.Context, application, name, machine string) (string, error) {
+	item, err := c.Get(ctx, application, name, machine)
+	if err != nil {
+		return "", err
+	}
+
+	return item.Value, nil
+}
+
+//	GetInt gets the value of a config item as an integer
+func (c *Client) GetInt(ctx context.Context, application, name, machine string) (int, error) {
+	value, err := c.GetString(ctx, application, name, machine)
+	if err != nil {
+		return 0, err
+	}
+
+	retval, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("centralconfig: %s is not an integer: %q", name, value)
+	}
+
+	return retval, nil
+}
+
+//	GetBool gets the value of a config item as a boolean
+//	(1, t, true, 0, f, false and so on)
+func (c *Client) GetBool(ctx context.Context, application, name, machine string) (bool, error) {
+	value, err := c.GetString(ctx, application, name, machine)
+	if err != nil {
+		return false, err
+	}
+
+	retval, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("centralconfig: %s is not a boolean: %q", name, value)
+	}
+
+	return retval, nil
+}
+
+//	GetDuration gets the value of a config item as a duration (like 1m30s)
+func (c *Client) GetDuration(ctx context.Context, application, name, machine string) (time.Duration, error) {
+	value, err := c.GetString(ctx, application, name, machine)
+	if err != nil {
+		return 0, err
+	}
+
+	retval, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("centralconfig: %s is not a duration: %q", name, value)
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// appsCmd represents the apps command
+var appsCmd = &cobra.Command{
+	Use:   "apps",
+	Short: "Lists applications on a centralconfig server",
+	Long: `Lists all applications on a running centralconfig server.
+
+Example:
+
+centralconfig apps --server https://config.example.com
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		applications, err := c.Applications(context.Background())
+		if err != nil {
+			return getClientError(err)
+		}
+
+		switch clientOutput {
+		case outputJSON:
+			return printJSON(applications)
+
+		case outputPlain:
+			for _, application := range applications {
+				fmt.Println(application)
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "APPLICATION")
+		for _, application := range applications {
+			fmt.Fprintln(w, application)
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(appsCmd)
+
+	addClientFlags(appsCmd)
+}
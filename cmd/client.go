@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+//	Exit codes for the client commands
+const (
+	exitCodeFailed   = 1
+	exitCodeNotFound = 2
+)
+
+//	Client output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputPlain = "plain"
+)
+
+var (
+	clientMachine string
+	clientOutput  string
+)
+
+//	An error that should end the process with a specific exit code
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+//	Adds the flags shared by all of the client commands
+func addClientFlags(cmd *cobra.Command) {
+	addServerFlags(cmd)
+	cmd.Flags().StringVarP(&clientOutput, "output", "o", outputTable, "output format: table, json or plain")
+	cmd.PreRunE = bindClientFlags
+}
+
+//	Adds the flags for connecting to the server (for commands that have their
+//	own output, like export)
+func addServerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("server", "s", "", "address of the centralconfig server (default is http://localhost:3000)")
+	cmd.Flags().String("token", "", "bearer token to send with each request (for a proxy in front of the server)")
+	cmd.Flags().String("user", "", "user name for basic authentication")
+	cmd.Flags().String("password", "", "password for basic authentication")
+	cmd.Flags().String("ca-cert", "", "CA certificate file used to verify the server")
+	cmd.Flags().Bool("insecure", false, "don't verify the server's TLS certificate")
+
+	//	Client commands report their own errors and exit codes:
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.PreRunE = bindServerFlags
+}
+
+//	Binds the running command's server flags to the client config settings.
+//	This happens at run time because each client command has its own copy of the flags
+func bindServerFlags(cmd *cobra.Command, args []string) error {
+	viper.SetDefault("client.server", "http://localhost:3000")
+
+	for _, name := range []string{"server", "token", "user", "password", "ca-cert", "insecure"} {
+		if err := viper.BindPFlag("client."+name, cmd.Flags().Lookup(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//	Binds the running command's flags to the client config settings, and
+//	checks the output format
+func bindClientFlags(cmd *cobra.Command, args []string) error {
+	if err := bindServerFlags(cmd, args); err != nil {
+		return err
+	}
+
+	switch clientOutput {
+	case outputTable, outputJSON, outputPlain:
+		return nil
+	}
+
+	return fmt.Errorf("Unknown output format '%s'.  Should be one of: table, json, plain", clientOutput)
+}
+
+//	Gets a client for the configured centralconfig server
+func getClient() (*client.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: viper.GetBool("client.insecure")}
+
+	if caFile := viper.GetString("client.ca-cert"); caFile != "" {
+		caCert, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, exitCodeError{exitCodeFailed, err}
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, exitCodeError{exitCodeFailed, fmt.Errorf("No certificates found in %s", caFile)}
+		}
+	}
+
+	opts := []client.Option{client.WithTLSConfig(tlsConfig)}
+	if token := viper.GetString("client.token"); token != "" {
+		opts = append(opts, client.WithToken(token))
+	} else if user := viper.GetString("client.user"); user != "" {
+		opts = append(opts, client.WithBasicAuth(user, viper.GetString("client.password")))
+	}
+
+	c, err := client.New(viper.GetString("client.server"), opts...)
+	if err != nil {
+		return nil, exitCodeError{exitCodeFailed, err}
+	}
+
+	return c, nil
+}
+
+//	Gets the exit code error for a client error
+func getClientError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case client.ErrNotFound:
+		return exitCodeError{exitCodeNotFound, fmt.Errorf("No config item found with that application and name")}
+	}
+
+	return exitCodeError{exitCodeFailed, err}
+}
+
+//	Prints config items in the selected output format
+func printConfigItems(items []datastores.ConfigItem) error {
+	switch clientOutput {
+	case outputJSON:
+		return printJSON(items)
+
+	case outputPlain:
+		for _, item := range items {
+			fmt.Printf("%s=%s\n", item.Name, item.Value)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APPLICATION\tMACHINE\tNAME\tVALUE\tUPDATED")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.Application, item.Machine, item.Name, item.Value, item.LastUpdated.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+//	Prints the value as indented JSON
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/api"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+//	Starts a server with the API routes, using a bolt database
+func getTestServer(filename string) *httptest.Server {
+	viper.Set("datastore.type", "boltdb")
+	viper.Set("datastore.database", filename)
+
+	router := mux.NewRouter()
+	api.AddRoutes(router, http.NotFoundHandler())
+
+	return httptest.NewServer(router)
+}
+
+//	Runs a command (with the server flag) and returns what it printed.  The
+//	flags are shared between commands, so they're reset first
+func runTestCommand(t *testing.T, server string, args ...string) (string, error) {
+	clientMachine = ""
+	clientOutput = outputTable
+	lsResolve = false
+	setTTL = 0
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Can't capture the output: %s", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	RootCmd.SetArgs(append(args, "--server", server))
+	err = RootCmd.Execute()
+
+	writer.Close()
+	output, _ := ioutil.ReadAll(reader)
+
+	return string(output), err
+}
+
+//	Gets the exit code a command's error would end the process with
+func getTestExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if coded, ok := err.(exitCodeError); ok {
+		return coded.code
+	}
+
+	return -1
+}
+
+//	Client commands should print items in each of the output formats
+func TestClientCommands_OutputFormats(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	if _, err := runTestCommand(t, server.URL, "set", "billing", "db.host", "db01.example.com", "--output", "plain"); err != nil {
+		t.Fatalf("set failed: %s", err)
+	}
+
+	tests := []struct {
+		args     []string
+		contains string
+	}{
+		{[]string{"get", "billing", "db.host", "--output", "table"}, "APPLICATION"},
+		{[]string{"get", "billing", "db.host", "--output", "json"}, `"value": "db01.example.com"`},
+		{[]string{"get", "billing", "db.host", "--output", "plain"}, "db01.example.com\n"},
+		{[]string{"set", "billing", "db.port", "5432", "--output", "table"}, "db.port"},
+		{[]string{"set", "billing", "db.port", "5433", "--output", "json"}, `"value": "5433"`},
+		{[]string{"ls", "billing", "--output", "table"}, "APPLICATION"},
+		{[]string{"ls", "billing", "--output", "json"}, `"name": "db.port"`},
+		{[]string{"ls", "billing", "--output", "plain"}, "db.host=db01.example.com\n"},
+		{[]string{"apps", "--output", "table"}, "APPLICATION\nbilling\n"},
+		{[]string{"apps", "--output", "json"}, `"billing"`},
+		{[]string{"apps", "--output", "plain"}, "billing\n"},
+	}
+
+	for _, test := range tests {
+		//	Act
+		output, err := runTestCommand(t, server.URL, test.args...)
+
+		//	Assert
+		if err != nil {
+			t.Errorf("%s failed: %s", strings.Join(test.args, " "), err)
+			continue
+		}
+
+		if !strings.Contains(output, test.contains) {
+			t.Errorf("%s should have printed '%s', but printed: %s", strings.Join(test.args, " "), test.contains, output)
+		}
+	}
+}
+
+//	JSON output should be the item itself (so it can be read by scripts)
+func TestClientCommands_Get_JSON(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	runTestCommand(t, server.URL, "set", "billing", "db.host", "db01.example.com", "--machine", "web01")
+
+	//	Act
+	output, err := runTestCommand(t, server.URL, "get", "billing", "db.host", "--machine", "web01", "--output", "json")
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("get failed: %s", err)
+	}
+
+	item := datastores.ConfigItem{}
+	if err := json.Unmarshal([]byte(output), &item); err != nil {
+		t.Fatalf("get should have printed JSON: %s (%s)", err, output)
+	}
+
+	if item.Application != "billing" || item.Name != "db.host" || item.Machine != "web01" || item.Value != "db01.example.com" {
+		t.Errorf("get should have printed the item: %+v", item)
+	}
+}
+
+//	Set with plain output and rm shouldn't print anything
+func TestClientCommands_SetAndRemove_Quiet(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	//	Act
+	setOutput, setErr := runTestCommand(t, server.URL, "set", "billing", "db.host", "db01.example.com", "--output", "plain")
+	rmOutput, rmErr := runTestCommand(t, server.URL, "rm", "billing", "db.host")
+	_, getErr := runTestCommand(t, server.URL, "get", "billing", "db.host")
+
+	//	Assert
+	if setErr != nil || rmErr != nil {
+		t.Fatalf("set and rm should have completed without error: %v / %v", setErr, rmErr)
+	}
+
+	if setOutput != "" || rmOutput != "" {
+		t.Errorf("set and rm shouldn't have printed anything: '%s' / '%s'", setOutput, rmOutput)
+	}
+
+	if code := getTestExitCode(getErr); code != exitCodeNotFound {
+		t.Errorf("The item should have been removed, but get exited with %d: %v", code, getErr)
+	}
+}
+
+//	Client commands should exit with 2 when the item isn't found, and 1 when
+//	they fail
+func TestClientCommands_ExitCodes(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	server := getTestServer(filename)
+	defer server.Close()
+
+	//	A server that isn't running any more:
+	stopped := httptest.NewServer(http.NotFoundHandler())
+	stopped.Close()
+
+	tests := []struct {
+		server string
+		args   []string
+		code   int
+	}{
+		{server.URL, []string{"get", "billing", "db.host"}, exitCodeNotFound},
+		{server.URL, []string{"rm", "billing", "db.host"}, exitCodeNotFound},
+		{server.URL, []string{"ls", "--resolve"}, exitCodeFailed},
+		{stopped.URL, []string{"get", "billing", "db.host"}, exitCodeFailed},
+		{stopped.URL, []string{"set", "billing", "db.host", "db01.example.com"}, exitCodeFailed},
+		{stopped.URL, []string{"rm", "billing", "db.host"}, exitCodeFailed},
+		{stopped.URL, []string{"ls", "billing"}, exitCodeFailed},
+		{stopped.URL, []string{"apps"}, exitCodeFailed},
+	}
+
+	for _, test := range tests {
+		//	Act
+		_, err := runTestCommand(t, test.server, test.args...)
+
+		//	Assert
+		if code := getTestExitCode(err); code != test.code {
+			t.Errorf("%s should have exited with %d, but exited with %d: %v", strings.Join(test.args, " "), test.code, code, err)
+		}
+	}
+}
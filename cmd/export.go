@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/formats"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportApplication string
+	exportMachine     string
+	exportFormat      string
+	exportPrefix      string
+	exportLabels      []string
+	exportNest        bool
+	exportOutput      string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports an application's configuration as a config file",
+	Long: `Renders the resolved configuration for an application (and optional machine)
+from a running centralconfig server as a config file.  Supported formats are: 
+env, yaml, json, toml, ini and properties.  Use --prefix and --label to only 
+export some of the items.
+
+Example:
+
+centralconfig export --app billing --machine WEB01 --format yaml --nest > billing.yaml
+centralconfig export --app billing --prefix db.primary --label owner:payments --server https://config.example.com
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportApplication == "" {
+			return exitCodeError{exitCodeFailed, fmt.Errorf("The --app flag is required")}
+		}
+
+		format, err := formats.ParseFormat(exportFormat)
+		if err != nil {
+			return exitCodeError{exitCodeFailed, err}
+		}
+
+		labels, err := getExportLabels()
+		if err != nil {
+			return exitCodeError{exitCodeFailed, err}
+		}
+
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		//	Have the server render the config file:
+		output, err := c.Export(context.Background(), exportApplication, client.ExportOptions{
+			Machine: exportMachine,
+			Format:  string(format),
+			Prefix:  exportPrefix,
+			Labels:  labels,
+			Nest:    exportNest})
+		if err != nil {
+			return getClientError(err)
+		}
+
+		if exportOutput != "" {
+			return ioutil.WriteFile(exportOutput, output, 0644)
+		}
+
+		_, err = os.Stdout.Write(output)
+		return err
+	},
+}
+
+//	Gets the labels from the --label flags (like pii or owner:payments)
+func getExportLabels() (map[string]string, error) {
+	if len(exportLabels) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string)
+	for _, label := range exportLabels {
+		name, value := label, ""
+		if i := strings.Index(label, ":"); i >= 0 {
+			name, value = label[:i], label[i+1:]
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("The label '%s' needs a name (like pii or owner:payments)", label)
+		}
+		labels[name] = value
+	}
+
+	return labels, nil
+}
+
+func init() {
+	RootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportApplication, "app", "a", "", "application to export")
+	exportCmd.Flags().StringVarP(&exportMachine, "machine", "m", "", "machine to resolve the configuration for")
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "env", "output format: env, yaml, json, toml, ini or properties")
+	exportCmd.Flags().StringVar(&exportPrefix, "prefix", "", "only export items with the prefix as their name, or under it (like db.primary)")
+	exportCmd.Flags().StringArrayVar(&exportLabels, "label", nil, "only export items with the label (like pii or owner:payments -- repeat it for more labels)")
+	exportCmd.Flags().BoolVarP(&exportNest, "nest", "n", false, "nest dotted names into trees (yaml, json, toml) or sections (ini)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "file to write to (defaults to stdout)")
+	addServerFlags(exportCmd)
+}
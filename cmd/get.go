@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+)
+
+// getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get [application] [name]",
+	Short: "Gets a config item from a centralconfig server",
+	Long: `Gets a single config item from a running centralconfig server.  If the item 
+doesn't exist for the machine or application, the default (*) application is used.
+
+Exits with 2 if the item doesn't exist.
+
+Example:
+
+centralconfig get AccountingReports ShowFooterDates --output plain
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		item, err := c.Get(context.Background(), args[0], args[1], clientMachine)
+		if err != nil {
+			return getClientError(err)
+		}
+
+		//	Plain output is just the value (so it can be used in scripts)
+		if clientOutput == outputPlain {
+			fmt.Println(item.Value)
+			return nil
+		}
+
+		if clientOutput == outputJSON {
+			return printJSON(item)
+		}
+
+		return printConfigItems([]datastores.ConfigItem{item})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(getCmd)
+
+	getCmd.Flags().StringVarP(&clientMachine, "machine", "m", "", "machine name")
+	addClientFlags(getCmd)
+}
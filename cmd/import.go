@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/formats"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importApplication string
+	importMachine     string
+	importFormat      string
+	importReplace     bool
+	importDryRun      bool
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Imports a config file into an application's configuration",
+	Long: `Loads the values from a config file into the configured datastore for 
+an application (and optional machine).  Supported formats are: 
+env, yaml, json, toml, ini and properties.  Nested documents are flattened 
+into dotted names.
+
+By default, existing items that aren't in the file are left alone.  Use 
+--replace to remove them.  Use --dry-run to see what would change without 
+changing anything.
+
+Example:
+
+centralconfig import --app billing --dry-run billing.yaml
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if importApplication == "" {
+			return fmt.Errorf("The --app flag is required")
+		}
+
+		//	Use the file extension if we don't have a format:
+		formatName := importFormat
+		if formatName == "" {
+			formatName = filepath.Ext(args[0])
+		}
+
+		format, err := formats.ParseFormat(formatName)
+		if err != nil {
+			return err
+		}
+
+		//	Read the file (or stdin):
+		var data []byte
+		if args[0] == "-" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		values, err := formats.Decode(data, format)
+		if err != nil {
+			return err
+		}
+
+		mode := datastores.ImportMerge
+		if importReplace {
+			mode = datastores.ImportReplace
+		}
+
+		//	Figure out what needs to change:
+		ds := datastores.GetConfigDatastore()
+		changes, err := datastores.PlanImport(ds, importApplication, importMachine, values, mode)
+		if err != nil {
+			return err
+		}
+
+		printConfigChanges(changes)
+
+		if importDryRun || len(changes) == 0 {
+			return nil
+		}
+
+		_, _, err = datastores.ApplyChanges(ds, changes)
+		return err
+	},
+}
+
+//	Prints a line for each change (or a note that there aren't any)
+func printConfigChanges(changes []datastores.ConfigChange) {
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	for _, change := range changes {
+		switch change.Action {
+		case "add":
+			fmt.Printf("+ %s = %s\n", change.Name, change.Value)
+		case "update":
+			fmt.Printf("~ %s = %s (was %s)\n", change.Name, change.Value, change.Previous)
+		case "remove":
+			fmt.Printf("- %s (was %s)\n", change.Name, change.Previous)
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importApplication, "app", "a", "", "application to import into")
+	importCmd.Flags().StringVarP(&importMachine, "machine", "m", "", "machine to import into")
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", "", "file format: env, yaml, json, toml, ini or properties (defaults to the file extension)")
+	importCmd.Flags().BoolVarP(&importReplace, "replace", "r", false, "remove existing items that aren't in the file")
+	importCmd.Flags().BoolVarP(&importDryRun, "dry-run", "d", false, "show what would change without changing anything")
+}
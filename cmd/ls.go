@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+)
+
+var lsResolve bool
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls [application]",
+	Short: "Lists config items on a centralconfig server",
+	Long: `Lists the config items for an application (plus the default * application) 
+on a running centralconfig server.  Without an application, all config items are listed.
+
+Use --resolve to list only the effective value of each item for the machine.
+
+Example:
+
+centralconfig ls AccountingReports --resolve --machine WEB01 --output plain
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && lsResolve {
+			return exitCodeError{exitCodeFailed, fmt.Errorf("An application is required to resolve config items")}
+		}
+
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		//	Without an application, this gets everything:
+		application := ""
+		if len(args) > 0 {
+			application = args[0]
+		}
+
+		items, err := c.GetAll(context.Background(), application)
+		if err != nil {
+			return getClientError(err)
+		}
+
+		if lsResolve {
+			items = datastores.ResolveConfigItems(items, application, clientMachine)
+		}
+
+		return printConfigItems(items)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(lsCmd)
+
+	lsCmd.Flags().StringVarP(&clientMachine, "machine", "m", "", "machine name (used with --resolve)")
+	lsCmd.Flags().BoolVarP(&lsResolve, "resolve", "r", false, "only list the effective value of each item")
+	addClientFlags(lsCmd)
+}
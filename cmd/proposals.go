@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+)
+
+var (
+	proposalStatus  string
+	proposalComment string
+)
+
+// proposalsCmd represents the proposals command
+var proposalsCmd = &cobra.Command{
+	Use:   "proposals",
+	Short: "Reviews proposed changes on a centralconfig server",
+	Long: `Lists, approves and rejects proposed changes to protected applications on
+a running centralconfig server.  Changes to protected applications (with set
+or rm) are proposed instead of being made, and someone else has to approve them.
+
+Reviewers are identified by their --user name and --password (or by an
+authenticating proxy in front of the server).
+
+Example:
+
+centralconfig proposals ls --status pending
+centralconfig proposals approve 7 --user alice --comment "Looks good"
+centralconfig proposals reject 8 --user alice --comment "Not during the sale"
+`,
+}
+
+// proposalsLsCmd represents the proposals ls command
+var proposalsLsCmd = &cobra.Command{
+	Use:   "ls [application]",
+	Short: "Lists proposals",
+	Long: `Lists the proposed changes (oldest first), including the ones that have
+been reviewed.  Without an application, proposals for every application are listed.
+
+Example:
+
+centralconfig proposals ls Payroll --status pending
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		application := ""
+		if len(args) > 0 {
+			application = args[0]
+		}
+
+		proposals, err := c.Proposals(context.Background(), proposalStatus, application)
+		if err != nil {
+			return getClientError(err)
+		}
+
+		return printProposals(proposals)
+	},
+}
+
+// proposalsApproveCmd represents the proposals approve command
+var proposalsApproveCmd = &cobra.Command{
+	Use:   "approve [id]",
+	Short: "Approves a proposal and makes the change",
+	Long: `Approves a pending proposal, and the server makes the change.  You can't
+approve your own proposals.
+
+Exits with 2 if the proposal doesn't exist.
+
+Example:
+
+centralconfig proposals approve 7 --user alice --comment "Looks good"
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reviewProposal(args[0], func(c *client.Client, id int64) (datastores.ChangeProposal, error) {
+			return c.ApproveProposal(context.Background(), id, proposalComment)
+		})
+	},
+}
+
+// proposalsRejectCmd represents the proposals reject command
+var proposalsRejectCmd = &cobra.Command{
+	Use:   "reject [id]",
+	Short: "Rejects a proposal",
+	Long: `Rejects a pending proposal, so the change isn't made.  You can't reject
+your own proposals.
+
+Exits with 2 if the proposal doesn't exist.
+
+Example:
+
+centralconfig proposals reject 8 --user alice --comment "Not during the sale"
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reviewProposal(args[0], func(c *client.Client, id int64) (datastores.ChangeProposal, error) {
+			return c.RejectProposal(context.Background(), id, proposalComment)
+		})
+	},
+}
+
+//	Approves or rejects the proposal with the id, and prints it
+func reviewProposal(arg string, review func(c *client.Client, id int64) (datastores.ChangeProposal, error)) error {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return exitCodeError{exitCodeFailed, fmt.Errorf("The id should be a number")}
+	}
+
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+
+	proposal, err := review(c, id)
+	if err == client.ErrNotFound {
+		return exitCodeError{exitCodeNotFound, fmt.Errorf("No proposal found with the id %d", id)}
+	}
+	if err != nil {
+		return getClientError(err)
+	}
+
+	return printProposals([]datastores.ChangeProposal{proposal})
+}
+
+//	Prints the proposal if a change was proposed instead of being made.
+//	Returns false if the error isn't a proposal
+func printProposedChange(err error) (bool, error) {
+	var proposed *client.ProposalError
+	if !errors.As(err, &proposed) {
+		return false, nil
+	}
+
+	switch clientOutput {
+	case outputJSON:
+		return true, printJSON(proposed.Proposal)
+	case outputPlain:
+		fmt.Println(proposed.Proposal.Id)
+		return true, nil
+	}
+
+	fmt.Printf("The change needs to be approved: proposal %d\n", proposed.Proposal.Id)
+	return true, nil
+}
+
+//	Prints proposals in the selected output format
+func printProposals(proposals []datastores.ChangeProposal) error {
+	switch clientOutput {
+	case outputJSON:
+		return printJSON(proposals)
+
+	case outputPlain:
+		for _, proposal := range proposals {
+			fmt.Printf("%d %s %s %s=%s\n", proposal.Id, proposal.Status, proposal.Action, proposal.Item.Name, proposal.Item.Value)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tACTION\tAPPLICATION\tMACHINE\tNAME\tVALUE\tPROPOSED BY\tPROPOSED\tREVIEWED BY")
+	for _, proposal := range proposals {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", proposal.Id, proposal.Status, proposal.Action, proposal.Item.Application, proposal.Item.Machine, proposal.Item.Name, proposal.Item.Value, proposal.ProposedBy, proposal.Proposed.Format(time.RFC3339), proposal.ReviewedBy)
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	RootCmd.AddCommand(proposalsCmd)
+	proposalsCmd.AddCommand(proposalsLsCmd, proposalsApproveCmd, proposalsRejectCmd)
+
+	proposalsLsCmd.Flags().StringVar(&proposalStatus, "status", "", "only list proposals with the status: pending, approved, rejected or failed")
+	proposalsApproveCmd.Flags().StringVar(&proposalComment, "comment", "", "a comment for the audit trail")
+	proposalsRejectCmd.Flags().StringVar(&proposalComment, "comment", "", "a comment for the audit trail")
+
+	for _, cmd := range []*cobra.Command{proposalsLsCmd, proposalsApproveCmd, proposalsRejectCmd} {
+		addClientFlags(cmd)
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+)
+
+// rmCmd represents the rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm [application] [name]",
+	Short: "Removes a config item from a centralconfig server",
+	Long: `Removes a single config item from a running centralconfig server.  
+Removing items from protected applications is proposed, and someone else 
+has to approve it.
+
+Exits with 2 if the item doesn't exist.
+
+Example:
+
+centralconfig rm AccountingReports ShowFooterDates --machine WEB01
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		err = c.Remove(context.Background(), datastores.ConfigItem{
+			Application: args[0],
+			Name:        args[1],
+			Machine:     clientMachine})
+		if proposed, printErr := printProposedChange(err); proposed {
+			return printErr
+		}
+
+		return getClientError(err)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rmCmd)
+
+	rmCmd.Flags().StringVarP(&clientMachine, "machine", "m", "", "machine name")
+	addClientFlags(rmCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/cagedtornado/centralconfig/datastores"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -24,6 +25,12 @@ use both an API and a web UI to manage configuration information.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
+		//	Client commands exit with a specific code (so they can be scripted)
+		if coded, ok := err.(exitCodeError); ok {
+			fmt.Fprintln(os.Stderr, coded)
+			os.Exit(coded.code)
+		}
+
 		fmt.Println(err)
 		os.Exit(-1)
 	}
@@ -46,8 +53,16 @@ func initConfig() {
 	viper.SetDefault("server.port", "3000")
 	viper.SetDefault("server.bind", "")
 	viper.SetDefault("server.allowed-origins", "*")
+	viper.SetDefault("server.event-log-size", 1000)
+	viper.SetDefault("server.slow-client-policy", "disconnect")
+	viper.SetDefault("server.expiry-sweep-interval", "1m")
+	viper.SetDefault("server.schedule-interval", "10s")
 	viper.SetDefault("datastore.type", "boltdb")
 	viper.SetDefault("datastore.database", "config.db")
+	viper.SetDefault("search.secret-names", datastores.DefaultSecretNames)
+	viper.SetDefault("approval.protected-applications", []string{})
+	viper.SetDefault("approval.user-header", "")
+	viper.SetDefault("approval.trusted-proxies", []string{})
 
 	viper.SetConfigName("centralconfig") // name of config file (without extension)
 	viper.AddConfigPath("$HOME")         // adding home directory as first search path
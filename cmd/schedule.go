@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/client"
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleAt string
+	scheduleIn time.Duration
+)
+
+// scheduleCmd represents the schedule command
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Schedules config changes on a centralconfig server",
+	Long: `Schedules config value changes on a running centralconfig server, lists
+the changes that haven't been made yet, and cancels them.  The server makes
+each change at its effective time (even if it restarted in between).
+
+Example:
+
+centralconfig schedule set AccountingReports Maintenance true --at 2016-08-12T02:00:00-04:00
+centralconfig schedule ls AccountingReports
+centralconfig schedule cancel 12
+`,
+}
+
+// scheduleSetCmd represents the schedule set command
+var scheduleSetCmd = &cobra.Command{
+	Use:   "set [application] [name] [value]",
+	Short: "Schedules a config value change",
+	Long: `Schedules a config value change for a time (--at, in RFC 3339 format) or
+after a while (--in).  The item keeps its description, labels and expiry time.
+
+Example:
+
+centralconfig schedule set AccountingReports Maintenance true --at 2016-08-12T02:00:00-04:00
+centralconfig schedule set AccountingReports LogLevel info --in 2h --machine WEB01
+`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		effective, err := getScheduleEffective()
+		if err != nil {
+			return exitCodeError{exitCodeFailed, err}
+		}
+
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		change, err := c.ScheduleChange(context.Background(), datastores.ScheduledChange{
+			Application: args[0],
+			Name:        args[1],
+			Machine:     clientMachine,
+			Value:       args[2],
+			Effective:   effective})
+		if err != nil {
+			return getClientError(err)
+		}
+
+		switch clientOutput {
+		case outputPlain:
+			fmt.Println(change.Id)
+			return nil
+		case outputJSON:
+			return printJSON(change)
+		}
+
+		return printScheduledChanges([]datastores.ScheduledChange{change})
+	},
+}
+
+// scheduleLsCmd represents the schedule ls command
+var scheduleLsCmd = &cobra.Command{
+	Use:   "ls [application]",
+	Short: "Lists scheduled changes",
+	Long: `Lists the scheduled changes that haven't been made yet (in the order
+they'll be made).  Without an application, all scheduled changes are listed.
+
+Example:
+
+centralconfig schedule ls AccountingReports --output json
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		application := ""
+		if len(args) > 0 {
+			application = args[0]
+		}
+
+		changes, err := c.ScheduledChanges(context.Background(), application)
+		if err != nil {
+			return getClientError(err)
+		}
+
+		return printScheduledChanges(changes)
+	},
+}
+
+// scheduleCancelCmd represents the schedule cancel command
+var scheduleCancelCmd = &cobra.Command{
+	Use:   "cancel [id]",
+	Short: "Cancels a scheduled change",
+	Long: `Cancels a scheduled change that hasn't been made yet.
+
+Exits with 2 if the change doesn't exist (or has already been made).
+
+Example:
+
+centralconfig schedule cancel 12
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return exitCodeError{exitCodeFailed, fmt.Errorf("The id should be a number")}
+		}
+
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		err = c.CancelScheduledChange(context.Background(), id)
+		if err == client.ErrNotFound {
+			return exitCodeError{exitCodeNotFound, fmt.Errorf("No scheduled change found with the id %d", id)}
+		}
+
+		return getClientError(err)
+	},
+}
+
+//	Gets the effective time from the --at or --in flag
+func getScheduleEffective() (time.Time, error) {
+	switch {
+	case scheduleAt != "" && scheduleIn != 0:
+		return time.Time{}, fmt.Errorf("Use either --at or --in, not both")
+
+	case scheduleAt != "":
+		effective, err := time.Parse(time.RFC3339, scheduleAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("The --at time should be in RFC 3339 format (like 2016-08-12T02:00:00-04:00)")
+		}
+		return effective, nil
+
+	case scheduleIn > 0:
+		return time.Now().Add(scheduleIn), nil
+	}
+
+	return time.Time{}, fmt.Errorf("A time is required: use --at or --in")
+}
+
+//	Prints scheduled changes in the selected output format
+func printScheduledChanges(changes []datastores.ScheduledChange) error {
+	switch clientOutput {
+	case outputJSON:
+		return printJSON(changes)
+
+	case outputPlain:
+		for _, change := range changes {
+			fmt.Printf("%d %s %s=%s\n", change.Id, change.Effective.Format(time.RFC3339), change.Name, change.Value)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAPPLICATION\tMACHINE\tNAME\tVALUE\tEFFECTIVE")
+	for _, change := range changes {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", change.Id, change.Application, change.Machine, change.Name, change.Value, change.Effective.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	RootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleSetCmd, scheduleLsCmd, scheduleCancelCmd)
+
+	scheduleSetCmd.Flags().StringVarP(&clientMachine, "machine", "m", "", "machine name")
+	scheduleSetCmd.Flags().StringVar(&scheduleAt, "at", "", "when to make the change (like 2016-08-12T02:00:00-04:00)")
+	scheduleSetCmd.Flags().DurationVar(&scheduleIn, "in", 0, "make the change after this long (like 30m or 2h)")
+
+	for _, cmd := range []*cobra.Command{scheduleSetCmd, scheduleLsCmd, scheduleCancelCmd} {
+		addClientFlags(cmd)
+	}
+}
@@ -2,21 +2,26 @@ package cmd
 
 import (
 	"log"
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/cagedtornado/centralconfig/api"
 	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/rpc"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
 	serverInterface   string
 	serverPort        int
+	serverGrpcPort    int
 	serverUIDirectory string
 	allowedOrigins    string
 )
@@ -40,43 +45,55 @@ func serve(cmd *cobra.Command, args []string) {
 	//	Log the datastore information we have:
 	logDatastoreInfo()
 
-	//	Create a router and setup our REST endpoints...
-	var Router = mux.NewRouter()
+	//	Create the datastore's tables (or upgrade them from an earlier release):
+	if err := datastores.GetConfigDatastore().InitStore(false); err != nil {
+		log.Printf("[ERROR] Can't initialize the datastore: %v\n", err)
+	}
 
-	//	Setup our routes
-	Router.HandleFunc("/", api.ShowUI)
-	Router.HandleFunc("/config/get", api.GetConfig)
-	Router.HandleFunc("/config/set", api.SetConfig)
-	Router.HandleFunc("/config/remove", api.RemoveConfig)
-	Router.HandleFunc("/config/getall", api.GetAllConfig)
-	Router.HandleFunc("/config/getallforapp", api.GetAllConfigForApp)
-	Router.HandleFunc("/applications/getall", api.GetAllApplications)
+	//	Setup the hub used for change events:
+	api.WsHub.SetLogSize(viper.GetInt("server.event-log-size"))
+	if err := api.WsHub.SetSlowClientPolicy(viper.GetString("server.slow-client-policy")); err != nil {
+		log.Printf("[WARN] %v -- disconnecting slow clients\n", err)
+	}
 
-	//	Websocket connections
-	Router.Handle("/ws", api.WsHandler{H: api.WsHub})
+	//	Remove expired config items in the background:
+	go api.WsHub.RunExpirySweeper(viper.GetDuration("server.expiry-sweep-interval"))
 
-	//	If we don't have a UI directory specified...
-	if viper.GetString("server.ui-dir") == "" {
-		//	Use the static assets file generated with
-		//	https://github.com/elazarl/go-bindata-assetfs using the centralconfig-ui from
-		//	https://github.com/danesparza/centralconfig-ui.
-		//
-		//	To generate this file, place the 'ui'
-		//	directory under the main centralconfig directory and run the commands:
-		//	go-bindata-assetfs.exe -pkg cmd ./ui/...
-		//	mv bindata_assetfs.go cmd
-		//	go install ./...
-		Router.PathPrefix("/ui").Handler(http.StripPrefix("/ui", http.FileServer(assetFS())))
-	} else {
-		//	Use the supplied directory:
-		log.Printf("[INFO] Using UI directory: %s\n", viper.GetString("server.ui-dir"))
-		Router.PathPrefix("/ui").Handler(http.StripPrefix("/ui", http.FileServer(http.Dir(viper.GetString("server.ui-dir")))))
+	//	Make scheduled changes when they're due:
+	go api.WsHub.RunScheduler(viper.GetDuration("server.schedule-interval"))
+
+	//	Setup the names of items with secret values (that searches skip):
+	if err := datastores.SetSecretNames(viper.GetStringSlice("search.secret-names")); err != nil {
+		log.Printf("[WARN] %v -- using the default secret names\n", err)
+	}
+
+	//	Setup the applications that need changes approved (and don't start
+	//	without them -- unprotected changes can't be taken back):
+	if err := api.SetProtectedApplications(viper.GetStringSlice("approval.protected-applications")); err != nil {
+		log.Fatalf("[ERROR] %v\n", err)
 	}
 
+	//	Setup how the users who propose and review changes are identified:
+	if err := api.SetApprovalAuth(api.ApprovalAuth{
+		Users:          viper.GetStringMapString("approval.users"),
+		UserHeader:     viper.GetString("approval.user-header"),
+		TrustedProxies: viper.GetStringSlice("approval.trusted-proxies")}); err != nil {
+		log.Fatalf("[ERROR] %v\n", err)
+	}
+
+	if len(viper.GetStringSlice("approval.protected-applications")) > 0 && !api.HasApprovalAuth() {
+		log.Printf("[WARN] No approval users or user header are set -- changes to protected applications can't be proposed or reviewed\n")
+	}
+
+	//	Create a router and setup our REST endpoints...
+	var Router = mux.NewRouter()
+	api.AddRoutes(Router, getUIHandler())
+
 	//	Setup the CORS options:
 	log.Printf("[INFO] Allowed CORS origins: %s\n", viper.GetString("server.allowed-origins"))
 	c := cors.New(cors.Options{
 		AllowedOrigins:   strings.Split(allowedOrigins, ","),
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "HEAD"},
 		AllowCredentials: true,
 	})
 	corsHandler := c.Handler(Router)
@@ -87,6 +104,11 @@ func serve(cmd *cobra.Command, args []string) {
 		formattedInterface = "127.0.0.1"
 	}
 
+	//	If we have a gRPC port, serve gRPC on it too:
+	if viper.GetInt("server.grpc-port") > 0 {
+		go serveGrpc(formattedInterface)
+	}
+
 	//	If we have an SSL cert specified, use it:
 	if viper.GetString("server.sslcert") != "" {
 		log.Printf("[INFO] Using SSL cert: %s\n", viper.GetString("server.sslcert"))
@@ -105,17 +127,65 @@ func init() {
 
 	//	Setup our flags
 	serveCmd.Flags().IntVarP(&serverPort, "port", "p", 1313, "port on which the server will listen")
+	serveCmd.Flags().IntVarP(&serverGrpcPort, "grpc-port", "g", 0, "port on which the gRPC server will listen (0 for no gRPC server)")
 	serveCmd.Flags().StringVarP(&serverInterface, "bind", "i", "", "interface to which the server will bind")
 	serveCmd.Flags().StringVarP(&serverUIDirectory, "ui-dir", "u", "", "directory for the UI")
 	serveCmd.Flags().StringVarP(&allowedOrigins, "allowed-origins", "o", "", "comma seperated list of allowed CORS origins")
 
 	//	Bind config flags for optional config file override:
 	viper.BindPFlag("server.port", serveCmd.Flags().Lookup("port"))
+	viper.BindPFlag("server.grpc-port", serveCmd.Flags().Lookup("grpc-port"))
 	viper.BindPFlag("server.bind", serveCmd.Flags().Lookup("bind"))
 	viper.BindPFlag("server.ui-dir", serveCmd.Flags().Lookup("ui-dir"))
 	viper.BindPFlag("server.allowed-origins", serveCmd.Flags().Lookup("allowed-origins"))
 }
 
+//	Gets the handler that serves the UI files
+func getUIHandler() http.Handler {
+	//	If we don't have a UI directory specified...
+	if viper.GetString("server.ui-dir") == "" {
+		//	Use the static assets file generated with
+		//	https://github.com/elazarl/go-bindata-assetfs using the centralconfig-ui from
+		//	https://github.com/danesparza/centralconfig-ui.
+		//
+		//	To generate this file, place the 'ui'
+		//	directory under the main centralconfig directory and run the commands:
+		//	go-bindata-assetfs.exe -pkg cmd ./ui/...
+		//	mv bindata_assetfs.go cmd
+		//	go install ./...
+		return http.FileServer(assetFS())
+	} else {
+		//	Use the supplied directory:
+		log.Printf("[INFO] Using UI directory: %s\n", viper.GetString("server.ui-dir"))
+		return http.FileServer(http.Dir(viper.GetString("server.ui-dir")))
+	}
+}
+
+//	Serves the gRPC service (with the same SSL cert as the HTTP server, if there is one)
+func serveGrpc(formattedInterface string) {
+	options := []grpc.ServerOption{}
+	if viper.GetString("server.sslcert") != "" {
+		creds, err := credentials.NewServerTLSFromFile(viper.GetString("server.sslcert"), viper.GetString("server.sslkey"))
+		if err != nil {
+			log.Printf("[ERROR] Can't start the gRPC server: %v\n", err)
+			return
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", viper.GetString("server.bind")+":"+viper.GetString("server.grpc-port"))
+	if err != nil {
+		log.Printf("[ERROR] Can't start the gRPC server: %v\n", err)
+		return
+	}
+
+	server := grpc.NewServer(options...)
+	rpc.RegisterConfigServiceServer(server, api.GrpcServer{H: api.WsHub})
+
+	log.Printf("[INFO] Starting gRPC server: %s:%s\n", formattedInterface, viper.GetString("server.grpc-port"))
+	log.Printf("[ERROR] %v\n", server.Serve(listener))
+}
+
 func logDatastoreInfo() {
 	//	Get configuration information
 	ds := datastores.GetConfigDatastore()
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/spf13/cobra"
+)
+
+var setTTL time.Duration
+
+// setCmd represents the set command
+var setCmd = &cobra.Command{
+	Use:   "set [application] [name] [value]",
+	Short: "Sets a config item on a centralconfig server",
+	Long: `Creates or updates a single config item on a running centralconfig server.  
+Use - as the value to read it from stdin.  Use --ttl for a temporary 
+override that's removed when it expires.  Changes to protected applications 
+are proposed, and someone else has to approve them.
+
+Example:
+
+centralconfig set AccountingReports ShowFooterDates true --machine WEB01
+centralconfig set AccountingReports LogLevel debug --ttl 2h
+`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value := args[2]
+		if value == "-" {
+			input, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				return exitCodeError{exitCodeFailed, err}
+			}
+			value = strings.TrimSuffix(string(input), "\n")
+		}
+
+		c, err := getClient()
+		if err != nil {
+			return err
+		}
+
+		request := datastores.ConfigItem{
+			Application: args[0],
+			Name:        args[1],
+			Machine:     clientMachine,
+			Value:       value}
+
+		if setTTL > 0 {
+			expires := time.Now().Add(setTTL)
+			request.Expires = &expires
+		}
+
+		item, err := c.Set(context.Background(), request)
+		if proposed, printErr := printProposedChange(err); proposed {
+			return printErr
+		}
+		if err != nil {
+			return getClientError(err)
+		}
+
+		switch clientOutput {
+		case outputPlain:
+			return nil
+		case outputJSON:
+			return printJSON(item)
+		}
+
+		return printConfigItems([]datastores.ConfigItem{item})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(setCmd)
+
+	setCmd.Flags().StringVarP(&clientMachine, "machine", "m", "", "machine name")
+	setCmd.Flags().DurationVar(&setTTL, "ttl", 0, "remove the item after this long (like 30m or 2h)")
+	addClientFlags(setCmd)
+}
@@ -1,6 +1,8 @@
 package datastores
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"time"
 
@@ -15,13 +17,37 @@ type BoltDB struct {
 }
 
 const system_ids string = "system_ids"
+const system_events string = "system_events"
+const system_indexes string = "system_indexes"
+const system_schedule string = "system_schedule"
+const system_proposals string = "system_proposals"
+
+//	Returns true if the bucket is used by centralconfig (and isn't an application)
+func isBoltSystemBucket(name []byte) bool {
+	switch string(name) {
+	case system_ids, system_events, system_indexes, system_schedule, system_proposals:
+		return true
+	}
+	return false
+}
+
+//	Opens the Bolt database.  If it can't be opened, the datastore is unavailable
+//	(and an unopened database is returned, so deferred Close calls are still safe)
+func (store BoltDB) open() (*bolt.DB, error) {
+	db, err := bolt.Open(store.Database, 0600, nil)
+	if err != nil {
+		return &bolt.DB{}, unavailableError(err)
+	}
+
+	return db, nil
+}
 
 //	If we need to list applications, we can do so by listing buckets:
 //	https://github.com/boltdb/bolt/issues/295
 
 func (store BoltDB) InitStore(overwrite bool) error {
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 
 	return err
@@ -32,7 +58,7 @@ func (store BoltDB) Get(configItem ConfigItem) (ConfigItem, error) {
 	retval := ConfigItem{}
 
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 	if err != nil {
 		return retval, err
@@ -56,9 +82,15 @@ func (store BoltDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			//	Need to make sure we got something back here before we try to unmarshal?
 			if len(configBytes) > 0 {
 				//	Unmarshal data into our config item
-				if err := json.Unmarshal(configBytes, &retval); err != nil {
+				item := ConfigItem{}
+				if err := json.Unmarshal(configBytes, &item); err != nil {
 					return err
 				}
+
+				//	Expired items are skipped (until they're swept)
+				if !IsExpired(item, time.Now()) {
+					retval = item
+				}
 			}
 		}
 
@@ -77,16 +109,30 @@ func (store BoltDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			//	Need to make sure we got something back here before we try to unmarshal?
 			if len(configBytes) > 0 {
 				//	Unmarshal data into our config item
-				if err := json.Unmarshal(configBytes, &retval); err != nil {
+				item := ConfigItem{}
+				if err := json.Unmarshal(configBytes, &item); err != nil {
 					return err
 				}
+
+				//	Expired items are skipped (until they're swept)
+				if !IsExpired(item, time.Now()) {
+					retval = item
+				}
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return retval, err
+	}
 
-	return retval, err
+	//	If we didn't find it, say so:
+	if retval.Id == 0 {
+		return retval, notFoundError()
+	}
+
+	return retval, nil
 }
 
 func (store BoltDB) GetAllForApplication(application string) ([]ConfigItem, error) {
@@ -94,7 +140,7 @@ func (store BoltDB) GetAllForApplication(application string) ([]ConfigItem, erro
 	retval := []ConfigItem{}
 
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 	if err != nil {
 		return retval, err
@@ -162,7 +208,7 @@ func (store BoltDB) GetAll() ([]ConfigItem, error) {
 	var bucketList []string
 
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 	if err != nil {
 		return retval, err
@@ -171,7 +217,9 @@ func (store BoltDB) GetAll() ([]ConfigItem, error) {
 	//	Get a list of all buckets
 	err = db.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
-			bucketList = append(bucketList, string(name))
+			if !isBoltSystemBucket(name) {
+				bucketList = append(bucketList, string(name))
+			}
 			return nil
 		})
 	})
@@ -207,13 +255,73 @@ func (store BoltDB) GetAll() ([]ConfigItem, error) {
 	return retval, err
 }
 
+//	Gets a page of config items (see ListOptions).  In the default (application)
+//	order, the application buckets are read one at a time, so only the buckets
+//	up to the end of the page are read.  The other orders have to read every
+//	matching item to sort them
+func (store BoltDB) List(options ListOptions) (ConfigPage, error) {
+	//	Our return value:
+	retval := ConfigPage{Items: []ConfigItem{}}
+
+	field, descending, after, err := parseListOptions(options)
+	if err != nil {
+		return retval, err
+	}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	items := []ConfigItem{}
+	err = db.View(func(tx *bolt.Tx) error {
+		var err error
+		if field == SortApplication {
+			items, err = listBoltConfigItems(tx, options, descending, after, options.Limit)
+			return err
+		}
+
+		items, err = listBoltConfigItems(tx, options, false, nil, 0)
+		items = sortListItems(items, field, descending, after, options.Limit)
+		return err
+	})
+	if err != nil {
+		return retval, err
+	}
+
+	return getListPage(items, options.Limit), nil
+}
+
+//	Gets the config items for the application (every application if it's blank)
+//	with the prefix as their name, or under it (like db.primary.host under db.primary)
+func (store BoltDB) ListPrefix(application, prefix string) ([]ConfigItem, error) {
+	return listConfigSubtree(store, application, prefix, false)
+}
+
+//	Removes the config items for the application under the prefix (see ListPrefix)
+func (store BoltDB) RemovePrefix(application, prefix string) ([]ConfigItem, error) {
+	return removeConfigSubtree(store, application, prefix)
+}
+
+//	Moves the config items for the application under the from prefix to the to prefix
+func (store BoltDB) RenamePrefix(application, from, to string) ([]ConfigItem, []ConfigItem, error) {
+	return renameConfigSubtree(store, application, from, to)
+}
+
+//	Searches the names and values of config items (see SearchOptions)
+func (store BoltDB) Search(options SearchOptions) ([]ConfigItem, error) {
+	return searchConfigItems(store, options)
+}
+
 func (store BoltDB) GetAllApplications() ([]string, error) {
 
 	//	Our return items:
 	var bucketList []string
 
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 	if err != nil {
 		return bucketList, err
@@ -222,9 +330,9 @@ func (store BoltDB) GetAllApplications() ([]string, error) {
 	//	Get a list of all buckets
 	err = db.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
-			//	As long as the bucket name isn't the reserved 'system_ids' bucket
+			//	As long as the bucket name isn't one of the reserved system buckets
 			//	return the bucket name as an application name
-			if string(name) != system_ids {
+			if !isBoltSystemBucket(name) {
 				bucketList = append(bucketList, string(name))
 			}
 			return nil
@@ -240,7 +348,7 @@ func (store BoltDB) Set(configItem ConfigItem) (ConfigItem, error) {
 	retval := ConfigItem{}
 
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 	if err != nil {
 		return retval, err
@@ -248,78 +356,727 @@ func (store BoltDB) Set(configItem ConfigItem) (ConfigItem, error) {
 
 	//	Update the database:
 	err = db.Update(func(tx *bolt.Tx) error {
-		//	Put the item in the bucket with the app name
-		b, err := tx.CreateBucketIfNotExists([]byte(configItem.Application))
+		retval, err = putBoltConfigItem(tx, configItem)
+		return err
+	})
+
+	return retval, err
+}
+
+//	Gets the Bolt key for a config item (the name, with the machine name if there is one)
+func getBoltKey(configItem ConfigItem) []byte {
+	if configItem.Machine != "" {
+		return []byte(configItem.Name + "|" + configItem.Machine)
+	}
+
+	return []byte(configItem.Name)
+}
+
+//	Gets the config items that match the list options in application, name and
+//	machine order (like the other stores), starting after the given item (if there
+//	is one).  Stops after limit + 1 items (so we know if there's another page)
+func listBoltConfigItems(tx *bolt.Tx, options ListOptions, descending bool, after *ConfigItem, limit int) ([]ConfigItem, error) {
+	//	Get the application buckets in order:
+	applications := []string{}
+	tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if isBoltSystemBucket(name) {
+			return nil
+		}
+
+		for _, application := range options.Applications {
+			if application == string(name) {
+				applications = append(applications, application)
+				return nil
+			}
+		}
+
+		if len(options.Applications) == 0 {
+			applications = append(applications, string(name))
+		}
+		return nil
+	})
+
+	if descending {
+		for i, j := 0, len(applications)-1; i < j; i, j = i+1, j-1 {
+			applications[i], applications[j] = applications[j], applications[i]
+		}
+	}
+
+	items := []ConfigItem{}
+	prefix := []byte(options.NamePrefix)
+	for _, application := range applications {
+		//	Skip the applications before the cursor:
+		if after != nil && ((!descending && application < after.Application) || (descending && application > after.Application)) {
+			continue
+		}
+
+		//	Only the keys starting with the prefix can match.  Keys are the name
+		//	and machine joined with a pipe, so they don't sort like the other
+		//	stores (a|web01 comes after a.b) and need to be sorted here:
+		appItems := []ConfigItem{}
+		c := tx.Bucket([]byte(application)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			item := ConfigItem{}
+			if err := json.Unmarshal(v, &item); err != nil {
+				return items, err
+			}
+
+			if matchesListOptions(item, options) {
+				appItems = append(appItems, item)
+			}
+		}
+
+		items = append(items, sortListItems(appItems, SortApplication, descending, after, 0)...)
+		if limit > 0 && len(items) > limit {
+			return items[:limit+1], nil
+		}
+	}
+
+	return items, nil
+}
+
+//	Stores a config item in the bucket for its application
+func putBoltConfigItem(tx *bolt.Tx, configItem ConfigItem) (ConfigItem, error) {
+	//	Make sure we can store the item:
+	if err := validateConfigItem(configItem); err != nil {
+		return configItem, err
+	}
+
+	//	Put the item in the bucket with the app name
+	b, err := tx.CreateBucketIfNotExists([]byte(configItem.Application))
+	if err != nil {
+		return configItem, err
+	}
+
+	// If we don't have an id, generate an id for the configitem.
+	// This returns an error only if the Tx is closed or not writeable.
+	// That can't happen in an Update() call so I ignore the error check.
+	if configItem.Id == 0 {
+		bids, err := tx.CreateBucketIfNotExists([]byte(system_ids))
+		if err != nil {
+			return configItem, err
+		}
+		id, _ := bids.NextSequence()
+		configItem.Id = int64(id)
+	}
+
+	//	Set the current datetime:
+	configItem.LastUpdated = time.Now()
+
+	//	Serialize to JSON format
+	encoded, err := json.Marshal(configItem)
+	if err != nil {
+		return configItem, err
+	}
+
+	//	If we have a machine name, append it in the key:
+	keyName := configItem.Name
+	if configItem.Machine != "" {
+		keyName = keyName + "|" + configItem.Machine
+	}
+
+	//	Store it, with the 'name' as the key:
+	if err := b.Put([]byte(keyName), encoded); err != nil {
+		return configItem, err
+	}
+
+	return configItem, updateBoltIndex(tx, configItem.Application)
+}
+
+func (store BoltDB) Remove(configItem ConfigItem) error {
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return err
+	}
+
+	//	Update the database:
+	err = db.Update(func(tx *bolt.Tx) error {
+		return deleteBoltConfigItem(tx, configItem)
+	})
+
+	return err
+}
+
+//	Removes the config items that have expired by the given time, and returns them
+func (store BoltDB) RemoveExpired(now time.Time) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	//	Find and remove the expired items in a single transaction:
+	err = db.Update(func(tx *bolt.Tx) error {
+		expired := []ConfigItem{}
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if isBoltSystemBucket(name) {
+				return nil
+			}
+
+			return b.ForEach(func(k, v []byte) error {
+				item := ConfigItem{}
+				if err := json.Unmarshal(v, &item); err != nil {
+					return err
+				}
+
+				if IsExpired(item, now) {
+					expired = append(expired, item)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		//	Buckets can't change while we're walking them, so remove the items after:
+		for _, item := range expired {
+			if err := deleteBoltConfigItem(tx, item); err != nil {
+				return err
+			}
+		}
+
+		retval = expired
+		return nil
+	})
+	if err != nil {
+		return []ConfigItem{}, err
+	}
+
+	return retval, nil
+}
+
+//	Stores the scheduled change with a new id (scheduled changes are kept in
+//	their own bucket, by id)
+func (store BoltDB) ScheduleChange(change ScheduledChange) (ScheduledChange, error) {
+	//	Make sure we can store the change:
+	if err := validateScheduledChange(change); err != nil {
+		return ScheduledChange{}, err
+	}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return ScheduledChange{}, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(system_schedule))
+		if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		change.Id = int64(id)
+		change.Created = time.Now()
+
+		encoded, err := json.Marshal(change)
 		if err != nil {
 			return err
 		}
 
-		// If we don't have an id, generate an id for the configitem.
-		// This returns an error only if the Tx is closed or not writeable.
-		// That can't happen in an Update() call so I ignore the error check.
-		if configItem.Id == 0 {
-			bids, err := tx.CreateBucketIfNotExists([]byte(system_ids))
+		return b.Put(getBoltIdKey(change.Id), encoded)
+	})
+	if err != nil {
+		return ScheduledChange{}, err
+	}
+
+	return change, nil
+}
+
+func (store BoltDB) GetScheduledChanges() ([]ScheduledChange, error) {
+	//	Our return items:
+	retval := []ScheduledChange{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(system_schedule))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			change := ScheduledChange{}
+			if err := json.Unmarshal(v, &change); err != nil {
+				return err
+			}
+
+			retval = append(retval, change)
+			return nil
+		})
+	})
+	if err != nil {
+		return []ScheduledChange{}, err
+	}
+
+	sortScheduledChanges(retval)
+	return retval, nil
+}
+
+func (store BoltDB) CancelScheduledChange(id int64) (ScheduledChange, error) {
+	//	Our return item:
+	retval := ScheduledChange{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(system_schedule))
+		if b == nil {
+			return scheduledChangeNotFoundError(id)
+		}
+
+		encoded := b.Get(getBoltIdKey(id))
+		if encoded == nil {
+			return scheduledChangeNotFoundError(id)
+		}
+
+		if err := json.Unmarshal(encoded, &retval); err != nil {
+			return err
+		}
+
+		return b.Delete(getBoltIdKey(id))
+	})
+	if err != nil {
+		return ScheduledChange{}, err
+	}
+
+	return retval, nil
+}
+
+//	Makes the scheduled changes that are effective by the given time (in
+//	order) and removes them, all in one transaction.  If a change can't be
+//	made, none of them are removed, so they're made the next time
+func (store BoltDB) ApplyScheduledChanges(now time.Time) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(system_schedule))
+		if b == nil {
+			return nil
+		}
+
+		changes := []ScheduledChange{}
+		if err := b.ForEach(func(k, v []byte) error {
+			change := ScheduledChange{}
+			if err := json.Unmarshal(v, &change); err != nil {
+				return err
+			}
+
+			changes = append(changes, change)
+			return nil
+		}); err != nil {
+			return err
+		}
+		sortScheduledChanges(changes)
+
+		for _, change := range changes {
+			if change.Effective.After(now) {
+				break
+			}
+
+			item, err := getBoltScheduledItem(tx, change, now)
+			if err != nil {
+				return err
+			}
+
+			updated, err := putBoltConfigItem(tx, item)
 			if err != nil {
 				return err
 			}
-			id, _ := bids.NextSequence()
-			configItem.Id = int64(id)
+
+			if err := b.Delete(getBoltIdKey(change.Id)); err != nil {
+				return err
+			}
+
+			retval = append(retval, updated)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return []ConfigItem{}, err
+	}
+
+	return retval, nil
+}
+
+//	Gets the item a scheduled change sets, with the new value.  Items keep
+//	their description, labels and expiry time (unless they've expired)
+func getBoltScheduledItem(tx *bolt.Tx, change ScheduledChange, now time.Time) (ConfigItem, error) {
+	item := ConfigItem{Application: change.Application, Machine: change.Machine, Name: change.Name}
+
+	if b := tx.Bucket([]byte(change.Application)); b != nil {
+		if encoded := b.Get(getBoltKey(item)); encoded != nil {
+			if err := json.Unmarshal(encoded, &item); err != nil {
+				return item, err
+			}
+		}
+	}
+	item.Value = change.Value
+
+	//	An expired item is replaced (it just hasn't been removed yet):
+	if IsExpired(item, now) {
+		item.Expires = nil
+	}
+
+	return item, nil
+}
+
+//	Gets the key for a scheduled change or proposal (big endian, so they're
+//	kept in id order)
+func getBoltIdKey(id int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+//	Stores the proposal with a new id (proposals are kept in their own bucket, by id)
+func (store BoltDB) ProposeChange(proposal ChangeProposal) (ChangeProposal, error) {
+	//	Make sure we can store the proposal:
+	if err := validateChangeProposal(proposal); err != nil {
+		return ChangeProposal{}, err
+	}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(system_proposals))
+		if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		proposal.Id = int64(id)
+		proposal.Item.Id = 0
+		proposal.Status = ProposalPending
+		proposal.Proposed = time.Now()
+
+		encoded, err := json.Marshal(proposal)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(getBoltIdKey(proposal.Id), encoded)
+	})
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	return proposal, nil
+}
+
+func (store BoltDB) GetProposals() ([]ChangeProposal, error) {
+	//	Our return items:
+	retval := []ChangeProposal{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(system_proposals))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			proposal := ChangeProposal{}
+			if err := json.Unmarshal(v, &proposal); err != nil {
+				return err
+			}
+
+			retval = append(retval, proposal)
+			return nil
+		})
+	})
+	if err != nil {
+		return []ChangeProposal{}, err
+	}
+
+	return retval, nil
+}
+
+func (store BoltDB) GetProposal(id int64) (ChangeProposal, error) {
+	//	Our return item:
+	retval := ChangeProposal{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		retval, err = getBoltProposal(tx, id)
+		return err
+	})
+
+	return retval, err
+}
+
+//	Records the review in a single transaction, so the status can't change in between
+func (store BoltDB) UpdateProposal(proposal ChangeProposal, status string) (ChangeProposal, error) {
+	//	Our return item:
+	retval := ChangeProposal{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		stored, err := getBoltProposal(tx, proposal.Id)
+		if err != nil {
+			return err
+		}
+
+		if stored.Status != status {
+			return proposalStatusError(stored)
 		}
 
-		//	Set the current datetime:
-		configItem.LastUpdated = time.Now()
+		//	Only the review can change:
+		stored.Status = proposal.Status
+		stored.ReviewedBy = proposal.ReviewedBy
+		stored.Reviewed = proposal.Reviewed
+		stored.Comment = proposal.Comment
+		stored.Previous = proposal.Previous
+		stored.Error = proposal.Error
 
-		//	Serialize to JSON format
-		encoded, err := json.Marshal(configItem)
+		encoded, err := json.Marshal(stored)
 		if err != nil {
 			return err
 		}
 
+		retval = stored
+		return tx.Bucket([]byte(system_proposals)).Put(getBoltIdKey(stored.Id), encoded)
+	})
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	return retval, nil
+}
+
+func (store BoltDB) ApproveProposal(id int64, reviewer, comment string) (ChangeProposal, ConfigItem, error) {
+	return approveChangeProposal(store, id, reviewer, comment)
+}
+
+func (store BoltDB) RejectProposal(id int64, reviewer, comment string) (ChangeProposal, error) {
+	return rejectChangeProposal(store, id, reviewer, comment)
+}
+
+//	Gets a proposal from the proposals bucket
+func getBoltProposal(tx *bolt.Tx, id int64) (ChangeProposal, error) {
+	retval := ChangeProposal{}
+
+	b := tx.Bucket([]byte(system_proposals))
+	if b == nil {
+		return retval, proposalNotFoundError(id)
+	}
+
+	encoded := b.Get(getBoltIdKey(id))
+	if encoded == nil {
+		return retval, proposalNotFoundError(id)
+	}
+
+	err := json.Unmarshal(encoded, &retval)
+	return retval, err
+}
+
+func (store BoltDB) Batch(set []ConfigItem, remove []ConfigItem) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	//	Make all of the changes in a single transaction:
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, configItem := range set {
+			updated, err := putBoltConfigItem(tx, configItem)
+			if err != nil {
+				return err
+			}
+			retval = append(retval, updated)
+		}
+
+		for _, configItem := range remove {
+			if err := deleteBoltConfigItem(tx, configItem); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	//	If the transaction was rolled back, nothing was set:
+	if err != nil {
+		return []ConfigItem{}, err
+	}
+
+	return retval, nil
+}
+
+//	Deletes a config item from the bucket for its application
+func deleteBoltConfigItem(tx *bolt.Tx, configItem ConfigItem) error {
+	//	Get the item from the bucket with the app name
+	b := tx.Bucket([]byte(configItem.Application))
+
+	if b != nil {
+
 		//	If we have a machine name, append it in the key:
 		keyName := configItem.Name
 		if configItem.Machine != "" {
 			keyName = keyName + "|" + configItem.Machine
 		}
 
-		//	Store it, with the 'name' as the key:
-		return b.Put([]byte(keyName), encoded)
-	})
+		//	If it's there, delete it, with the 'name' as the key:
+		if b.Get([]byte(keyName)) == nil {
+			return nil
+		}
+
+		if err := b.Delete([]byte(keyName)); err != nil {
+			return err
+		}
+
+		return updateBoltIndex(tx, configItem.Application)
+	}
 
-	//	Set our return item:
-	retval = configItem
+	return nil
+}
+
+//	Sets the application's modification index to the next index
+func updateBoltIndex(tx *bolt.Tx, application string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(system_indexes))
+	if err != nil {
+		return err
+	}
+
+	index, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint64(encoded, index)
+	return b.Put([]byte(application), encoded)
+}
+
+func (store BoltDB) GetEventSequence() (int64, error) {
+	//	Our return value:
+	retval := int64(0)
+
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(system_events)); b != nil {
+			retval = int64(b.Sequence())
+		}
+		return nil
+	})
 
 	return retval, err
 }
 
-func (store BoltDB) Remove(configItem ConfigItem) error {
+func (store BoltDB) NextEventSequence() (int64, error) {
+	//	Our return value:
+	retval := int64(0)
 
 	//	Open the database:
-	db, err := bolt.Open(store.Database, 0600, nil)
+	db, err := store.open()
 	defer db.Close()
 	if err != nil {
-		return err
+		return retval, err
 	}
 
-	//	Update the database:
 	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(system_events))
+		if err != nil {
+			return err
+		}
 
-		//	Get the item from the bucket with the app name
-		b := tx.Bucket([]byte(configItem.Application))
+		sequence, err := b.NextSequence()
+		retval = int64(sequence)
+		return err
+	})
 
-		if b != nil {
+	return retval, err
+}
 
-			//	If we have a machine name, append it in the key:
-			keyName := configItem.Name
-			if configItem.Machine != "" {
-				keyName = keyName + "|" + configItem.Machine
-			}
+func (store BoltDB) GetApplicationIndex(application string) (int64, error) {
+	//	Our return value:
+	retval := int64(0)
 
-			//	Delete it, with the 'name' as the key:
-			return b.Delete([]byte(keyName))
-		}
+	//	Open the database:
+	db, err := store.open()
+	defer db.Close()
+	if err != nil {
+		return retval, err
+	}
 
+	err = db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(system_indexes)); b != nil {
+			if encoded := b.Get([]byte(application)); len(encoded) == 8 {
+				retval = int64(binary.BigEndian.Uint64(encoded))
+			}
+		}
 		return nil
 	})
 
-	return err
+	return retval, err
 }
@@ -2,8 +2,11 @@ package datastores_test
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/cagedtornado/centralconfig/datastores"
 )
 
@@ -38,8 +41,8 @@ func TestBoltDB_Init_Successful(t *testing.T) {
 	}
 }
 
-//	Bolt get should return successfully even if the item doesn't exist
-func TestBoltDB_Get_ItemDoesntExist_Successful(t *testing.T) {
+//	Bolt get should return a not found error if the item doesn't exist
+func TestBoltDB_Get_ItemDoesntExist_NotFound(t *testing.T) {
 	//	Arrange
 	filename := "testing.db"
 	defer os.Remove(filename)
@@ -55,8 +58,8 @@ func TestBoltDB_Get_ItemDoesntExist_Successful(t *testing.T) {
 	response, err := db.Get(query)
 
 	//	Assert
-	if err != nil {
-		t.Errorf("Get failed: Should have returned an empty dataset without error: %s", err)
+	if !datastores.IsNotFound(err) {
+		t.Errorf("Get failed: Should have returned a not found error: %v", err)
 	}
 
 	if query.Value != response.Value && response.Value != "" {
@@ -570,3 +573,865 @@ func TestBoltDB_Set_ThenGet_MultipleApps_HaveDifferentIds(t *testing.T) {
 		t.Errorf("Get failed: Should have returned unique ids but returned %v instead", response1.Id)
 	}
 }
+
+//	Bolt batch should set and remove items together
+func TestBoltDB_Batch_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	existing, _ := db.Set(datastores.ConfigItem{
+		Application: "MyTestAppName",
+		Name:        "TestItem1",
+		Value:       "Value1"})
+
+	set := []datastores.ConfigItem{
+		{Application: "MyTestAppName", Name: "TestItem2", Value: "Value2"},
+		{Application: "MyTestAppName", Name: "TestItem3", Value: "Value3"}}
+
+	//	Act
+	response, err := db.Batch(set, []datastores.ConfigItem{existing})
+
+	//	Assert
+	if err != nil {
+		t.Errorf("Batch failed: Should have completed without error: %s", err)
+	}
+
+	if len(response) != 2 || response[0].Id == 0 || response[1].Id == 0 {
+		t.Errorf("Batch failed: Should have returned the set items with ids: %+v", response)
+	}
+
+	items, _ := db.GetAllForApplication("MyTestAppName")
+	if len(items) != 2 {
+		t.Errorf("Batch failed: Should have 2 items left but found %d: %+v", len(items), items)
+	}
+}
+
+//	BoltDB event sequence numbers should start at 0 and increase
+func TestBoltDB_EventSequence_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	//	Act
+	initial, initialErr := db.GetEventSequence()
+	first, _ := db.NextEventSequence()
+	second, err := db.NextEventSequence()
+	current, currentErr := db.GetEventSequence()
+	applications, _ := db.GetAllApplications()
+
+	//	Assert
+	if initialErr != nil || err != nil || currentErr != nil {
+		t.Errorf("EventSequence failed: Should have completed without error: %v / %v / %v", initialErr, err, currentErr)
+	}
+
+	if initial != 0 || first != 1 || second != 2 || current != 2 {
+		t.Errorf("EventSequence failed: Should have returned 0, 1, 2, 2 but returned %d, %d, %d, %d", initial, first, second, current)
+	}
+
+	if len(applications) != 0 {
+		t.Errorf("EventSequence failed: The sequence shouldn't show up as an application: %v", applications)
+	}
+}
+
+//	BoltDB application indexes should increase when the application changes
+func TestBoltDB_ApplicationIndex_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	item := datastores.ConfigItem{Application: "Formbuilder", Name: "Item1", Value: "Value1"}
+
+	//	Act
+	initial, initialErr := db.GetApplicationIndex("Formbuilder")
+	db.Set(item)
+	afterSet, _ := db.GetApplicationIndex("Formbuilder")
+	db.Set(datastores.ConfigItem{Application: "Other", Name: "Item1", Value: "Value1"})
+	afterOther, _ := db.GetApplicationIndex("Formbuilder")
+	db.Remove(datastores.ConfigItem{Application: "Formbuilder", Name: "Missing"})
+	afterMissing, _ := db.GetApplicationIndex("Formbuilder")
+	db.Remove(item)
+	afterRemove, err := db.GetApplicationIndex("Formbuilder")
+
+	//	Assert
+	if initialErr != nil || err != nil {
+		t.Errorf("ApplicationIndex failed: Should have completed without error: %v / %v", initialErr, err)
+	}
+
+	if initial != 0 || afterSet <= initial || afterRemove <= afterSet {
+		t.Errorf("ApplicationIndex failed: Should have increased with each change but returned %d, %d, %d", initial, afterSet, afterRemove)
+	}
+
+	if afterOther != afterSet || afterMissing != afterSet {
+		t.Errorf("ApplicationIndex failed: Other changes shouldn't affect the index: %d, %d, %d", afterSet, afterOther, afterMissing)
+	}
+}
+
+//	Bolt set should reject items without an application or name
+func TestBoltDB_Set_MissingName_Validation(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	//	Act
+	_, setErr := db.Set(datastores.ConfigItem{Application: "MyTestAppName", Value: "Value1"})
+	_, batchErr := db.Batch([]datastores.ConfigItem{{Name: "TestItem1", Value: "Value1"}}, nil)
+
+	//	Assert
+	if datastores.ErrorCode(setErr) != datastores.CodeValidation || datastores.ErrorCode(batchErr) != datastores.CodeValidation {
+		t.Errorf("Set failed: Should have returned validation errors: %v / %v", setErr, batchErr)
+	}
+}
+
+//	Bolt should be unavailable if the database can't be opened
+func TestBoltDB_Get_CantOpen_Unavailable(t *testing.T) {
+	//	Arrange
+	db := datastores.BoltDB{
+		Database: "missing-directory/testing.db"}
+
+	//	Act
+	_, err := db.GetAll()
+
+	//	Assert
+	if datastores.ErrorCode(err) != datastores.CodeUnavailable {
+		t.Errorf("GetAll failed: Should have returned an unavailable error: %v", err)
+	}
+}
+
+//	Sets up config items for the list tests
+func setupBoltListItems(db datastores.BoltDB) {
+	for _, item := range []datastores.ConfigItem{
+		{Application: "*", Name: "db.timeout", Value: "30"},
+		{Application: "billing", Name: "db.host", Value: "db01.example.com"},
+		{Application: "billing", Name: "db.host", Machine: "WEB01", Value: "db02.example.com"},
+		{Application: "billing", Name: "db.timeout", Value: "10"},
+		{Application: "billing", Name: "ui.theme", Value: "dark"},
+		{Application: "payroll", Name: "db.host", Value: "db03.example.com"},
+	} {
+		db.Set(item)
+	}
+}
+
+//	Bolt list should page through every item in order
+func TestBoltDB_List_Pages_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	setupBoltListItems(db)
+
+	for _, sort := range []string{"", "-application", "name", "-updated"} {
+		//	Act
+		names := []string{}
+		pages := 0
+		options := datastores.ListOptions{Sort: sort, Limit: 4}
+		for {
+			page, err := db.List(options)
+			if err != nil {
+				t.Fatalf("List failed: Should have completed without error: %s", err)
+			}
+
+			pages++
+			for _, item := range page.Items {
+				names = append(names, item.Application+"/"+item.Name+"/"+item.Machine)
+			}
+
+			if page.Next == "" {
+				break
+			}
+			options.Cursor = page.Next
+		}
+
+		//	Assert
+		if len(names) != 6 || pages != 2 {
+			t.Errorf("List failed: Should have returned 6 items on 2 pages sorted by '%s': %v (%d pages)", sort, names, pages)
+		}
+
+		seen := map[string]bool{}
+		for _, name := range names {
+			if seen[name] {
+				t.Errorf("List failed: %s was returned twice sorted by '%s'", name, sort)
+			}
+			seen[name] = true
+		}
+
+		if sort == "" && (names[0] != "*/db.timeout/" || names[5] != "payroll/db.host/") {
+			t.Errorf("List failed: Should have sorted by application: %v", names)
+		}
+
+		if sort == "-application" && (names[0] != "payroll/db.host/" || names[5] != "*/db.timeout/") {
+			t.Errorf("List failed: Should have sorted by application in descending order: %v", names)
+		}
+
+		if sort == "name" && (names[0] != "billing/db.host/" || names[5] != "billing/ui.theme/") {
+			t.Errorf("List failed: Should have sorted by name: %v", names)
+		}
+
+		if sort == "-updated" && names[0] != "payroll/db.host/" {
+			t.Errorf("List failed: Should have sorted by the last update in descending order: %v", names)
+		}
+	}
+}
+
+//	Bolt list should sort by application, name and machine like the other
+//	stores, even though its keys join the name and machine (db|web01 comes after db.host)
+func TestBoltDB_List_NameAndMachine_SortedLikeSQL(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "db.host", Value: "db01.example.com"},
+		{Application: "billing", Name: "db", Machine: "web01", Value: "db02.example.com"},
+		{Application: "billing", Name: "db", Value: "db03.example.com"},
+	} {
+		db.Set(item)
+	}
+
+	for _, sort := range []string{"", "-application"} {
+		//	Act
+		names := []string{}
+		options := datastores.ListOptions{Sort: sort, Limit: 1}
+		for {
+			page, err := db.List(options)
+			if err != nil {
+				t.Fatalf("List failed: Should have completed without error: %s", err)
+			}
+
+			for _, item := range page.Items {
+				names = append(names, item.Name+"/"+item.Machine)
+			}
+
+			if page.Next == "" {
+				break
+			}
+			options.Cursor = page.Next
+		}
+
+		//	Assert
+		expected := "db/ db/web01 db.host/"
+		if sort == "-application" {
+			expected = "db.host/ db/web01 db/"
+		}
+
+		if strings.Join(names, " ") != expected {
+			t.Errorf("List failed: Should have returned '%s' sorted by '%s', but got %v", expected, sort, names)
+		}
+	}
+}
+
+//	Bolt list should filter by application, machine, name prefix and value
+func TestBoltDB_List_Filters_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	setupBoltListItems(db)
+
+	tests := []struct {
+		options datastores.ListOptions
+		count   int
+	}{
+		{datastores.ListOptions{Applications: []string{"billing", "*"}}, 5},
+		{datastores.ListOptions{NamePrefix: "db."}, 5},
+		{datastores.ListOptions{NamePrefix: "db.h", Applications: []string{"billing"}, Sort: "-application"}, 2},
+		{datastores.ListOptions{ValueContains: "example"}, 3},
+		{datastores.ListOptions{Machines: []string{"WEB01"}}, 1},
+		{datastores.ListOptions{Machines: []string{""}, NamePrefix: "db.host"}, 2},
+		{datastores.ListOptions{Machines: []string{}}, 0},
+	}
+
+	for _, test := range tests {
+		//	Act
+		page, err := db.List(test.options)
+
+		//	Assert
+		if err != nil || len(page.Items) != test.count || page.Next != "" {
+			t.Errorf("List failed: %+v should have returned %d items: %v / %v", test.options, test.count, page, err)
+		}
+	}
+}
+
+//	Bolt list should skip expired items that haven't been removed yet, unless
+//	they're asked for
+func TestBoltDB_List_Expired_Skipped(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	expired := time.Now().Add(-time.Minute)
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Banner", Value: "Down for maintenance", Expires: &expired})
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Timeout", Value: "30"})
+
+	//	Act
+	page, err := db.List(datastores.ListOptions{Applications: []string{"billing"}})
+	all, allErr := db.List(datastores.ListOptions{Applications: []string{"billing"}, IncludeExpired: true})
+	item, found, exactErr := datastores.GetExactConfigItem(db, "billing", "", "Banner")
+
+	//	Assert
+	if err != nil || len(page.Items) != 1 || page.Items[0].Name != "Timeout" {
+		t.Errorf("List failed: Should have skipped the expired item: %+v / %v", page.Items, err)
+	}
+
+	if allErr != nil || len(all.Items) != 2 {
+		t.Errorf("List failed: Should have included the expired item: %+v / %v", all.Items, allErr)
+	}
+
+	if exactErr != nil || !found || item.Name != "Banner" {
+		t.Errorf("GetExactConfigItem failed: Should have found the expired item: %+v / %v", item, exactErr)
+	}
+}
+
+//	Bolt list should reject invalid options
+func TestBoltDB_List_InvalidOptions_Validation(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	for _, options := range []datastores.ListOptions{
+		{Sort: "value"},
+		{Cursor: "not a cursor"},
+		{Limit: datastores.MaxListLimit + 1},
+	} {
+		//	Act
+		_, err := db.List(options)
+
+		//	Assert
+		if datastores.ErrorCode(err) != datastores.CodeValidation {
+			t.Errorf("List failed: %+v should have returned a validation error: %v", options, err)
+		}
+	}
+}
+
+//	Bolt search should match names and values, but not secret values
+func TestBoltDB_Search_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	setupBoltListItems(db)
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "db.password", Value: "db01.example.com"})
+
+	tests := []struct {
+		options datastores.SearchOptions
+		count   int
+	}{
+		{datastores.SearchOptions{Query: "db01.example.com"}, 1},
+		{datastores.SearchOptions{Query: "DB0", IgnoreCase: true}, 3},
+		{datastores.SearchOptions{Query: "DB0"}, 0},
+		{datastores.SearchOptions{Query: `^db0[12]\.`, Match: datastores.MatchRegex}, 2},
+		{datastores.SearchOptions{Query: "db.host", Match: datastores.MatchExact}, 3},
+		{datastores.SearchOptions{Query: "db.host", Match: datastores.MatchExact, Fields: []string{datastores.SearchValue}}, 0},
+		{datastores.SearchOptions{Query: "password", Fields: []string{datastores.SearchName}}, 1},
+		{datastores.SearchOptions{Query: "example", Applications: []string{"payroll"}}, 1},
+		{datastores.SearchOptions{Query: "example", Machines: []string{"WEB01"}}, 1},
+		{datastores.SearchOptions{Query: "example", Limit: 2}, 2},
+	}
+
+	for _, test := range tests {
+		//	Act
+		items, err := db.Search(test.options)
+
+		//	Assert
+		if err != nil || len(items) != test.count {
+			t.Errorf("Search failed: %+v should have returned %d items: %v / %v", test.options, test.count, items, err)
+		}
+	}
+}
+
+//	Bolt search should reject invalid options
+func TestBoltDB_Search_InvalidOptions_Validation(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	for _, options := range []datastores.SearchOptions{
+		{},
+		{Query: "db", Match: "fuzzy"},
+		{Query: "db(", Match: datastores.MatchRegex},
+		{Query: "db", Fields: []string{"machine"}},
+		{Query: "db", Limit: datastores.MaxListLimit + 1},
+	} {
+		//	Act
+		_, err := db.Search(options)
+
+		//	Assert
+		if datastores.ErrorCode(err) != datastores.CodeValidation {
+			t.Errorf("Search failed: %+v should have returned a validation error: %v", options, err)
+		}
+	}
+}
+
+//	Bolt should list, move and remove subtrees of dotted names
+func TestBoltDB_Prefix_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	setupBoltListItems(db)
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "db", Value: "mysql"})
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "dbhost", Value: "db09.example.com"})
+
+	//	Act
+	listed, err := db.ListPrefix("billing", "db")
+	if err != nil || len(listed) != 4 {
+		t.Fatalf("ListPrefix failed: should have listed 4 items: %v / %v", listed, err)
+	}
+
+	moved, replaced, err := db.RenamePrefix("billing", "db", "database.primary")
+	if err != nil || len(moved) != 4 || len(replaced) != 4 {
+		t.Fatalf("RenamePrefix failed: should have moved 4 items: %v / %v / %v", moved, replaced, err)
+	}
+
+	machine, err := db.Get(datastores.ConfigItem{Application: "billing", Name: "database.primary.host", Machine: "WEB01"})
+	if err != nil || machine.Value != "db02.example.com" {
+		t.Errorf("RenamePrefix failed: the machine item should have moved: %v / %v", machine, err)
+	}
+
+	removed, err := db.RemovePrefix("billing", "database")
+	if err != nil || len(removed) != 4 {
+		t.Fatalf("RemovePrefix failed: should have removed 4 items: %v / %v", removed, err)
+	}
+
+	//	Assert
+	remaining, err := db.GetAllForApplication("billing")
+	if err != nil {
+		t.Fatalf("GetAllForApplication failed: %s", err)
+	}
+
+	names := []string{}
+	for _, item := range remaining {
+		if item.Application == "billing" {
+			names = append(names, item.Name)
+		}
+	}
+
+	if len(names) != 2 || names[0] != "dbhost" || names[1] != "ui.theme" {
+		t.Errorf("Only dbhost and ui.theme should be left: %v", names)
+	}
+}
+
+//	Bolt shouldn't move a subtree onto existing items
+func TestBoltDB_RenamePrefix_Exists_Conflict(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	setupBoltListItems(db)
+
+	tests := []struct {
+		from string
+		to   string
+		code string
+	}{
+		{"db", "ui", datastores.CodeConflict},
+		{"db", "db.old", datastores.CodeValidation},
+		{"db.", "old", datastores.CodeValidation},
+		{"cache", "old", datastores.CodeNotFound},
+	}
+
+	for _, test := range tests {
+		//	Act
+		_, _, err := db.RenamePrefix("billing", test.from, test.to)
+
+		//	Assert
+		if datastores.ErrorCode(err) != test.code {
+			t.Errorf("RenamePrefix %s to %s should have returned a %s error: %v", test.from, test.to, test.code, err)
+		}
+	}
+
+	if listed, _ := db.ListPrefix("billing", "db"); len(listed) != 3 {
+		t.Errorf("Nothing should have moved: %v", listed)
+	}
+}
+
+//	Bolt should store descriptions and labels, and filter lists by labels
+func TestBoltDB_Labels_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "db.host", Value: "db01.example.com", Description: "The primary database", Labels: map[string]string{"owner": "payments", "pii": ""}},
+		{Application: "billing", Name: "db.user", Value: "billing", Labels: map[string]string{"owner": "payments-ops"}},
+		{Application: "billing", Name: "ui.theme", Value: "dark"},
+	} {
+		db.Set(item)
+	}
+
+	//	Act
+	item, err := db.Get(datastores.ConfigItem{Application: "billing", Name: "db.host"})
+	owned, ownedErr := db.List(datastores.ListOptions{Labels: map[string]string{"owner": "payments"}})
+	anyOwner, anyOwnerErr := db.List(datastores.ListOptions{Labels: map[string]string{"owner": ""}, Sort: datastores.SortName})
+
+	//	Assert
+	if err != nil || ownedErr != nil || anyOwnerErr != nil {
+		t.Errorf("Labels failed: Should have completed without error: %v / %v / %v", err, ownedErr, anyOwnerErr)
+	}
+
+	if item.Description != "The primary database" || item.Labels["owner"] != "payments" || len(item.Labels) != 2 {
+		t.Errorf("Labels failed: Should have stored the description and labels: %+v", item)
+	}
+
+	if len(owned.Items) != 1 || owned.Items[0].Name != "db.host" || len(anyOwner.Items) != 2 {
+		t.Errorf("Labels failed: Should have filtered by the labels: %+v / %+v", owned, anyOwner)
+	}
+}
+
+//	Bolt shouldn't store labels without a name
+func TestBoltDB_Set_BadLabel_Validation(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	for _, label := range []string{"", `team"owner`} {
+		//	Act
+		_, err := db.Set(datastores.ConfigItem{Application: "billing", Name: "db.host", Labels: map[string]string{label: "payments"}})
+
+		//	Assert
+		if datastores.ErrorCode(err) != datastores.CodeValidation {
+			t.Errorf("Set failed: label '%s' should have been a validation error: %v", label, err)
+		}
+	}
+}
+
+//	Bolt get should skip expired items, and RemoveExpired should remove them
+func TestBoltDB_Expired_Removed(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	for _, item := range []datastores.ConfigItem{
+		{Application: "*", Name: "LogLevel", Value: "info"},
+		{Application: "billing", Name: "LogLevel", Value: "debug", Expires: &past},
+		{Application: "billing", Name: "Maintenance", Value: "true", Expires: &future},
+	} {
+		db.Set(item)
+	}
+
+	//	Act
+	fallback, fallbackErr := db.Get(datastores.ConfigItem{Application: "billing", Name: "LogLevel"})
+	removed, err := db.RemoveExpired(time.Now())
+
+	//	Assert
+	if fallbackErr != nil || fallback.Value != "info" {
+		t.Errorf("Get failed: Should have skipped the expired item: %+v / %v", fallback, fallbackErr)
+	}
+
+	if err != nil || len(removed) != 1 || removed[0].Name != "LogLevel" || removed[0].Application != "billing" {
+		t.Errorf("RemoveExpired failed: Should have removed the expired item: %+v / %v", removed, err)
+	}
+
+	items, _ := db.GetAllForApplication("billing")
+	if len(items) != 2 {
+		t.Errorf("RemoveExpired failed: Should have left 2 items: %+v", items)
+	}
+}
+
+//	Bolt should make scheduled changes when they're due (keeping the item's
+//	labels), and leave the rest scheduled
+func TestBoltDB_ApplyScheduledChanges_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Maintenance", Value: "false", Labels: map[string]string{"owner": "payments"}})
+
+	now := time.Now()
+	due, _ := db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "true", Effective: now.Add(-time.Second)})
+	created, _ := db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Banner", Machine: "WEB01", Value: "Down for maintenance", Effective: now.Add(-2 * time.Second)})
+	later, _ := db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "false", Effective: now.Add(time.Hour)})
+
+	//	Act
+	updated, err := db.ApplyScheduledChanges(now)
+
+	//	Assert
+	if err != nil || len(updated) != 2 {
+		t.Fatalf("ApplyScheduledChanges failed: Should have made 2 changes: %+v / %v", updated, err)
+	}
+
+	if updated[0].Name != "Banner" || updated[0].Machine != "WEB01" || updated[1].Name != "Maintenance" {
+		t.Errorf("ApplyScheduledChanges failed: Should have made the changes in effective order: %+v", updated)
+	}
+
+	item, _ := db.Get(datastores.ConfigItem{Application: "billing", Name: "Maintenance"})
+	if item.Value != "true" || item.Labels["owner"] != "payments" {
+		t.Errorf("ApplyScheduledChanges failed: Should have changed only the value: %+v", item)
+	}
+
+	changes, _ := db.GetScheduledChanges()
+	if len(changes) != 1 || changes[0].Id != later.Id || due.Id == created.Id {
+		t.Errorf("ApplyScheduledChanges failed: Should have left the later change scheduled: %+v", changes)
+	}
+}
+
+//	Bolt should keep scheduled changes it can't make, so they're made the next time
+func TestBoltDB_ApplyScheduledChanges_Failed_KeptScheduled(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Maintenance", Value: "false"})
+
+	now := time.Now()
+	db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Banner", Value: "Down for maintenance", Effective: now.Add(-2 * time.Second)})
+	db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "true", Effective: now.Add(-time.Second)})
+
+	//	Break the stored item, so the change can't be made:
+	corrupt := func(value []byte) {
+		boltdb, err := bolt.Open(filename, 0600, nil)
+		if err != nil {
+			t.Fatalf("Open failed: %s", err)
+		}
+		defer boltdb.Close()
+
+		boltdb.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("billing")).Put([]byte("Maintenance"), value)
+		})
+	}
+	corrupt([]byte("not json"))
+
+	//	Act
+	_, err := db.ApplyScheduledChanges(now)
+	changes, _ := db.GetScheduledChanges()
+	_, bannerErr := db.Get(datastores.ConfigItem{Application: "billing", Name: "Banner"})
+
+	corrupt([]byte(`{"application":"billing","name":"Maintenance","value":"false"}`))
+	updated, retryErr := db.ApplyScheduledChanges(now)
+
+	//	Assert
+	if err == nil || len(changes) != 2 {
+		t.Errorf("ApplyScheduledChanges failed: Should have kept both changes scheduled: %+v / %v", changes, err)
+	}
+
+	if !datastores.IsNotFound(bannerErr) {
+		t.Errorf("ApplyScheduledChanges failed: Shouldn't have made any changes: %v", bannerErr)
+	}
+
+	if retryErr != nil || len(updated) != 2 {
+		t.Errorf("ApplyScheduledChanges failed: Should have made the changes the next time: %+v / %v", updated, retryErr)
+	}
+}
+
+//	Bolt cancel should remove the scheduled change, and return not found after that
+func TestBoltDB_CancelScheduledChange_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	scheduled, err := db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "true", Effective: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("ScheduleChange failed: %s", err)
+	}
+
+	//	Act
+	cancelled, err := db.CancelScheduledChange(scheduled.Id)
+	_, againErr := db.CancelScheduledChange(scheduled.Id)
+
+	//	Assert
+	if err != nil || cancelled.Value != "true" {
+		t.Errorf("CancelScheduledChange failed: %+v / %v", cancelled, err)
+	}
+
+	if !datastores.IsNotFound(againErr) {
+		t.Errorf("CancelScheduledChange failed: Cancelling twice should be not found: %v", againErr)
+	}
+
+	if _, err := db.ApplyScheduledChanges(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Errorf("ApplyScheduledChanges failed: %s", err)
+	}
+
+	if _, err := db.Get(datastores.ConfigItem{Application: "billing", Name: "Maintenance"}); !datastores.IsNotFound(err) {
+		t.Errorf("The cancelled change shouldn't have been made: %v", err)
+	}
+}
+
+//	Bolt should only make a proposed change once someone else approves it,
+//	and keep the review and previous item as an audit trail
+func TestBoltDB_ApproveProposal_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	db.Set(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "30"})
+
+	proposed, err := db.ProposeChange(datastores.ChangeProposal{
+		Action:     datastores.ProposalSet,
+		Item:       datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "60"},
+		ProposedBy: "bob"})
+	if err != nil || proposed.Status != datastores.ProposalPending {
+		t.Fatalf("ProposeChange failed: %+v / %v", proposed, err)
+	}
+
+	//	Act
+	_, _, selfErr := db.ApproveProposal(proposed.Id, "Bob", "")
+	before, _ := db.Get(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"})
+	approved, item, err := db.ApproveProposal(proposed.Id, "alice", "Looks good")
+	_, _, againErr := db.ApproveProposal(proposed.Id, "carol", "")
+
+	//	Assert
+	if datastores.ErrorCode(selfErr) != datastores.CodeForbidden || before.Value != "30" {
+		t.Errorf("ApproveProposal failed: The proposer shouldn't be able to approve it: %+v / %v", before, selfErr)
+	}
+
+	if err != nil || item.Value != "60" || approved.Status != datastores.ProposalApproved || approved.ReviewedBy != "alice" || approved.Reviewed == nil {
+		t.Fatalf("ApproveProposal failed: %+v / %+v / %v", approved, item, err)
+	}
+
+	if approved.Previous == nil || approved.Previous.Value != "30" {
+		t.Errorf("ApproveProposal failed: Should have recorded the previous item: %+v", approved.Previous)
+	}
+
+	if datastores.ErrorCode(againErr) != datastores.CodeConflict {
+		t.Errorf("ApproveProposal failed: Approving twice should be a conflict: %v", againErr)
+	}
+
+	after, _ := db.Get(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"})
+	if after.Value != "60" {
+		t.Errorf("ApproveProposal failed: Should have made the change: %+v", after)
+	}
+
+	stored, err := db.GetProposal(proposed.Id)
+	if err != nil || stored.Comment != "Looks good" || stored.Status != datastores.ProposalApproved {
+		t.Errorf("GetProposal failed: Should have stored the review: %+v / %v", stored, err)
+	}
+}
+
+//	Bolt should keep rejected proposals without making the change
+func TestBoltDB_RejectProposal_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	db.Set(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "30"})
+
+	proposed, err := db.ProposeChange(datastores.ChangeProposal{
+		Action:     datastores.ProposalRemove,
+		Item:       datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"},
+		ProposedBy: "bob"})
+	if err != nil {
+		t.Fatalf("ProposeChange failed: %s", err)
+	}
+
+	//	Act
+	rejected, err := db.RejectProposal(proposed.Id, "alice", "Not during the sale")
+	_, _, approveErr := db.ApproveProposal(proposed.Id, "carol", "")
+	_, missingErr := db.RejectProposal(proposed.Id+1, "alice", "")
+
+	//	Assert
+	if err != nil || rejected.Status != datastores.ProposalRejected || rejected.Comment != "Not during the sale" {
+		t.Errorf("RejectProposal failed: %+v / %v", rejected, err)
+	}
+
+	if datastores.ErrorCode(approveErr) != datastores.CodeConflict {
+		t.Errorf("ApproveProposal failed: A rejected proposal shouldn't be approved: %v", approveErr)
+	}
+
+	if !datastores.IsNotFound(missingErr) {
+		t.Errorf("RejectProposal failed: Should be not found: %v", missingErr)
+	}
+
+	if item, err := db.Get(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout"}); err != nil || item.Value != "30" {
+		t.Errorf("The rejected change shouldn't have been made: %+v / %v", item, err)
+	}
+
+	proposals, err := db.GetProposals()
+	if err != nil || len(proposals) != 1 {
+		t.Errorf("GetProposals failed: Should have kept the rejected proposal: %+v / %v", proposals, err)
+	}
+}
+
+//	Bolt shouldn't let a change be proposed or reviewed without a user name
+func TestBoltDB_Proposal_NoUser_Forbidden(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	proposed, err := db.ProposeChange(datastores.ChangeProposal{
+		Action:     datastores.ProposalSet,
+		Item:       datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "60"},
+		ProposedBy: "bob"})
+	if err != nil {
+		t.Fatalf("ProposeChange failed: %s", err)
+	}
+
+	//	Act
+	_, proposeErr := db.ProposeChange(datastores.ChangeProposal{
+		Action:     datastores.ProposalSet,
+		Item:       datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "90"},
+		ProposedBy: " "})
+	_, _, approveErr := db.ApproveProposal(proposed.Id, "", "")
+	_, rejectErr := db.RejectProposal(proposed.Id, " ", "")
+
+	//	Assert
+	for _, err := range []error{proposeErr, approveErr, rejectErr} {
+		if datastores.ErrorCode(err) != datastores.CodeForbidden {
+			t.Errorf("Should have been forbidden without a user name: %v", err)
+		}
+	}
+
+	if stored, err := db.GetProposal(proposed.Id); err != nil || stored.Status != datastores.ProposalPending {
+		t.Errorf("The proposal shouldn't have been reviewed: %+v / %v", stored, err)
+	}
+}
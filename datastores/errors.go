@@ -0,0 +1,110 @@
+package datastores
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+//	Error codes for the kinds of errors the datastores return.  These are part
+//	of the API (they're sent in error responses), so they shouldn't change
+const (
+	//	There isn't a config item with that application, machine and name
+	CodeNotFound = "not_found"
+
+	//	The change conflicts with an existing config item
+	CodeConflict = "conflict"
+
+	//	The config item (or request) isn't valid
+	CodeValidation = "validation"
+
+	//	The change isn't allowed (like approving your own proposal)
+	CodeForbidden = "forbidden"
+
+	//	The database can't be reached right now
+	CodeUnavailable = "unavailable"
+
+	//	Anything else
+	CodeInternal = "internal"
+)
+
+//	ConfigError is an error with a code describing what went wrong.  The message
+//	is safe to show to users -- the underlying (driver) error is in Err
+type ConfigError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Message
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+//	ErrorCode gets the code for an error returned by a datastore.  Errors
+//	without a code are CodeInternal
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		return configErr.Code
+	}
+
+	return CodeInternal
+}
+
+//	IsNotFound returns true if the error means the config item doesn't exist
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == CodeNotFound
+}
+
+//	Creates an error with a code (wrapping the underlying error, if there is one)
+func newConfigError(code string, err error, format string, args ...interface{}) *ConfigError {
+	return &ConfigError{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+//	Gets the error for a config item that doesn't exist
+func notFoundError() error {
+	return newConfigError(CodeNotFound, nil, "No config item found with that application and name")
+}
+
+//	Gets the error for a database that can't be reached
+func unavailableError(err error) error {
+	return newConfigError(CodeUnavailable, err, "The datastore is unavailable")
+}
+
+//	Gets the error for a config item that conflicts with an existing item
+func conflictError(err error) error {
+	return newConfigError(CodeConflict, err, "A config item with that application, machine and name already exists")
+}
+
+//	Makes sure a config item can be stored
+func validateConfigItem(configItem ConfigItem) error {
+	if configItem.Application == "" || configItem.Name == "" {
+		return newConfigError(CodeValidation, nil, "An application and name are required")
+	}
+
+	//	Label names are matched in the encoded labels (see getSQLLabelPattern),
+	//	so they can't have quotes that could end them early
+	for label := range configItem.Labels {
+		if label == "" || strings.ContainsAny(label, "\"\\") {
+			return newConfigError(CodeValidation, nil, "Label names can't be blank or have quotes or backslashes")
+		}
+	}
+
+	return nil
+}
+
+//	Returns true if the error means we couldn't talk to the database
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
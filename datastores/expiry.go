@@ -0,0 +1,40 @@
+package datastores
+
+import (
+	"database/sql"
+	"time"
+)
+
+//	IsExpired returns true if the item has an expiry time that has passed.
+//	Expired items aren't returned by Get or used when resolving config, and
+//	are removed by RemoveExpired
+func IsExpired(item ConfigItem, now time.Time) bool {
+	return item.Expires != nil && !item.Expires.After(now)
+}
+
+//	Gets a time that can be null (like an expiry time) to store in a SQL column
+//	(in UTC, since the columns don't have a time zone).  Items that don't
+//	expire are stored as null
+func getSQLNullTime(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return value.UTC()
+}
+
+//	Reads a time that can be null (like an expiry time) from a SQL column
+func readSQLNullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	retval := readSQLTime(value.Time)
+	return &retval
+}
+
+//	Reads a time stored in UTC from a SQL column (drivers read them in their
+//	own time zone)
+func readSQLTime(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), value.Hour(), value.Minute(), value.Second(), value.Nanosecond(), time.UTC)
+}
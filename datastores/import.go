@@ -0,0 +1,150 @@
+package datastores
+
+import (
+	"sort"
+	"time"
+)
+
+//	Import modes
+const (
+	//	Merge sets the imported items and leaves everything else alone
+	ImportMerge = "merge"
+
+	//	Replace sets the imported items and removes any other items
+	//	for the application and machine
+	ImportReplace = "replace"
+)
+
+//	ConfigChange represents a single change made (or that would be made) by an import
+type ConfigChange struct {
+	Action      string `json:"action"`
+	Application string `json:"application"`
+	Machine     string `json:"machine"`
+	Name        string `json:"name"`
+	Previous    string `json:"previous"`
+	Value       string `json:"value"`
+
+	//	The id, description, labels and expiry time of the existing item (if
+	//	there is one).  Imports only change values, so these are kept
+	id          int64
+	description string
+	labels      map[string]string
+	expires     *time.Time
+}
+
+//	ImportResult represents the outcome of an import
+type ImportResult struct {
+	DryRun  bool           `json:"dryrun"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+//	PlanImport compares the imported values with the existing items for the application
+//	and machine, and returns the changes needed to import them (sorted by name).
+//	Values that are already set don't show up as changes (unless the item has
+//	expired)
+func PlanImport(store ConfigService, application, machine string, values map[string]string, mode string) ([]ConfigChange, error) {
+	retval := []ConfigChange{}
+
+	//	Get the existing items for exactly this application and machine:
+	configItems, err := store.GetAllForApplication(application)
+	if err != nil {
+		return retval, err
+	}
+
+	now := time.Now()
+	existing := make(map[string]ConfigItem)
+	for _, item := range configItems {
+		if item.Application == application && item.Machine == machine {
+			existing[item.Name] = item
+		}
+	}
+
+	//	Adds and updates:
+	for name, value := range values {
+		change := ConfigChange{
+			Action:      "add",
+			Application: application,
+			Machine:     machine,
+			Name:        name,
+			Value:       value}
+
+		if item, ok := existing[name]; ok {
+			//	An expired item that hasn't been removed yet is imported again
+			//	(without its expiry time), even if the value is the same:
+			expired := IsExpired(item, now)
+			if item.Value == value && !expired {
+				continue
+			}
+
+			change.Action = "update"
+			change.Previous = item.Value
+			change.id = item.Id
+			change.description = item.Description
+			change.labels = item.Labels
+			change.expires = item.Expires
+			if expired {
+				change.expires = nil
+			}
+		}
+
+		retval = append(retval, change)
+	}
+
+	//	Removes:
+	if mode == ImportReplace {
+		for name, item := range existing {
+			if _, ok := values[name]; ok {
+				continue
+			}
+
+			retval = append(retval, ConfigChange{
+				Action:      "remove",
+				Application: application,
+				Machine:     machine,
+				Name:        name,
+				Previous:    item.Value,
+				id:          item.Id,
+				description: item.Description,
+				labels:      item.Labels,
+				expires:     item.Expires})
+		}
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].Name < retval[j].Name
+	})
+
+	return retval, nil
+}
+
+//	ApplyChanges makes the planned changes in a single batch.  It returns
+//	the items that were set and the items that were removed
+func ApplyChanges(store ConfigService, changes []ConfigChange) ([]ConfigItem, []ConfigItem, error) {
+	set := []ConfigItem{}
+	remove := []ConfigItem{}
+
+	for _, change := range changes {
+		item := ConfigItem{
+			Id:          change.id,
+			Application: change.Application,
+			Machine:     change.Machine,
+			Name:        change.Name,
+			Value:       change.Value,
+			Description: change.description,
+			Labels:      change.labels,
+			Expires:     change.expires}
+
+		if change.Action == "remove" {
+			remove = append(remove, item)
+		} else {
+			set = append(set, item)
+		}
+	}
+
+	updated, err := store.Batch(set, remove)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, remove, nil
+}
@@ -0,0 +1,132 @@
+package datastores_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Import planning in merge mode should only add and update
+func TestPlanImport_Merge_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "Unchanged", Value: "same"})
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "Updated", Value: "old"})
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "NotInFile", Value: "keep"})
+
+	values := map[string]string{
+		"Unchanged": "same",
+		"Updated":   "new",
+		"Added":     "added"}
+
+	//	Act
+	changes, err := datastores.PlanImport(db, "MyTestAppName", "", values, datastores.ImportMerge)
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("PlanImport failed: %s", err)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("PlanImport failed: Should have planned 2 changes: %+v", changes)
+	}
+
+	if changes[0].Name != "Added" || changes[0].Action != "add" {
+		t.Errorf("PlanImport failed: Should have added 'Added': %+v", changes[0])
+	}
+
+	if changes[1].Name != "Updated" || changes[1].Action != "update" || changes[1].Previous != "old" {
+		t.Errorf("PlanImport failed: Should have updated 'Updated': %+v", changes[1])
+	}
+}
+
+//	Import in replace mode should remove items that aren't in the file
+func TestApplyChanges_Replace_Successful(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "Updated", Value: "old"})
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "NotInFile", Value: "remove me"})
+
+	values := map[string]string{
+		"Updated": "new"}
+
+	changes, err := datastores.PlanImport(db, "MyTestAppName", "", values, datastores.ImportReplace)
+	if err != nil {
+		t.Fatalf("PlanImport failed: %s", err)
+	}
+
+	//	Act
+	_, removed, err := datastores.ApplyChanges(db, changes)
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("ApplyChanges failed: %s", err)
+	}
+
+	if len(removed) != 1 || removed[0].Name != "NotInFile" {
+		t.Errorf("ApplyChanges failed: Should have removed 'NotInFile': %+v", removed)
+	}
+
+	items, _ := db.GetAllForApplication("MyTestAppName")
+	if len(items) != 1 || items[0].Value != "new" {
+		t.Errorf("ApplyChanges failed: Should only have the updated item left: %+v", items)
+	}
+}
+
+//	Importing over an expired item that hasn't been removed yet should bring
+//	it back (without its expiry time), whether or not the value changed
+func TestApplyChanges_Expired_Imported(t *testing.T) {
+	//	Arrange
+	filename := "testing.db"
+	defer os.Remove(filename)
+
+	db := datastores.BoltDB{
+		Database: filename}
+
+	expired := time.Now().Add(-time.Minute)
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "Same", Value: "same", Expires: &expired})
+	db.Set(datastores.ConfigItem{Application: "MyTestAppName", Name: "Changed", Value: "old", Expires: &expired})
+
+	values := map[string]string{
+		"Same":    "same",
+		"Changed": "new"}
+
+	//	Act
+	changes, err := datastores.PlanImport(db, "MyTestAppName", "", values, datastores.ImportMerge)
+	if err != nil {
+		t.Fatalf("PlanImport failed: %s", err)
+	}
+	_, _, applyErr := datastores.ApplyChanges(db, changes)
+
+	//	Assert
+	if len(changes) != 2 || changes[0].Action != "update" || changes[1].Action != "update" {
+		t.Fatalf("PlanImport failed: Should have updated both expired items: %+v", changes)
+	}
+
+	if applyErr != nil {
+		t.Fatalf("ApplyChanges failed: %s", applyErr)
+	}
+
+	for name, value := range values {
+		item, err := db.Get(datastores.ConfigItem{Application: "MyTestAppName", Name: name})
+		if err != nil || item.Value != value || item.Expires != nil {
+			t.Errorf("ApplyChanges failed: '%s' shouldn't expire anymore: %+v / %v", name, item, err)
+		}
+	}
+
+	if removed, err := db.RemoveExpired(time.Now()); err != nil || len(removed) != 0 {
+		t.Errorf("RemoveExpired failed: Shouldn't have removed the imported items: %+v / %v", removed, err)
+	}
+}
@@ -0,0 +1,66 @@
+package datastores
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+//	Returns true if the item has all of the labels.  A blank label value
+//	matches any value (so pii matches pii: yes and pii with a blank value)
+func HasConfigLabels(item ConfigItem, labels map[string]string) bool {
+	for label, value := range labels {
+		itemValue, ok := item.Labels[label]
+		if !ok || (value != "" && itemValue != value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//	Gets the labels as JSON for the SQL labels column (blank if there aren't any).
+//	Labels are encoded in name order, so they can be matched with getSQLLabelPattern
+func encodeSQLLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	encoded, _ := json.Marshal(labels)
+	return string(encoded)
+}
+
+//	Reads the labels from the SQL labels column
+func decodeSQLLabels(encoded string) map[string]string {
+	if encoded == "" {
+		return nil
+	}
+
+	labels := make(map[string]string)
+	json.Unmarshal([]byte(encoded), &labels)
+	return labels
+}
+
+//	Gets the LIKE pattern that matches a label in the SQL labels column
+//	(like %"owner":"payments"% -- or %"pii":% for any value)
+func getSQLLabelPattern(label, value string) string {
+	encodedLabel, _ := json.Marshal(label)
+	pattern := string(encodedLabel) + ":"
+
+	if value != "" {
+		encodedValue, _ := json.Marshal(value)
+		pattern += string(encodedValue)
+	}
+
+	return "%" + escapeSQLLike(pattern) + "%"
+}
+
+//	Gets the label names in sorted order (so SQL arguments are in a stable order)
+func sortedLabelNames(labels map[string]string) []string {
+	names := []string{}
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
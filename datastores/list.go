@@ -0,0 +1,338 @@
+package datastores
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+//	Sort orders for List.  Prefix them with - (like -updated) to sort in descending order
+const (
+	//	By application, then name, then machine (the default)
+	SortApplication = "application"
+
+	//	By name, then application, then machine
+	SortName = "name"
+
+	//	By the last time the item was updated
+	SortUpdated = "updated"
+)
+
+//	The most items List will return at once
+const MaxListLimit = 1000
+
+//	ListOptions filters, sorts and pages the config items returned by List
+type ListOptions struct {
+	//	Only items for these applications (every application if there aren't any)
+	Applications []string
+
+	//	Only items for these machines (use a blank machine for items that don't
+	//	have one).  If this is nil, items for every machine are returned
+	Machines []string
+
+	//	Only items with names that start with this prefix
+	NamePrefix string
+
+	//	Only items with values that contain this string
+	ValueContains string
+
+	//	Only items with all of these labels.  A blank value matches any value
+	Labels map[string]string
+
+	//	The sort order (like name or -updated).  Defaults to SortApplication
+	Sort string
+
+	//	The Next cursor from the previous page (blank for the first page)
+	Cursor string
+
+	//	The most items to return (0 for all of them, up to MaxListLimit)
+	Limit int
+
+	//	Include the items that have expired but haven't been removed yet
+	IncludeExpired bool
+}
+
+//	ConfigPage is a page of config items returned by List
+type ConfigPage struct {
+	Items []ConfigItem `json:"items"`
+
+	//	The cursor for the next page (blank if this is the last page)
+	Next string `json:"next,omitempty"`
+}
+
+//	The last item on a page.  Cursors only need the fields items are sorted by
+type listCursor struct {
+	Id          int64     `json:"i,omitempty"`
+	Application string    `json:"a"`
+	Name        string    `json:"n"`
+	Machine     string    `json:"m,omitempty"`
+	Updated     time.Time `json:"u"`
+}
+
+//	Checks the list options, and gets the sort field, the sort direction
+//	and the item the page starts after (if there is one)
+func parseListOptions(options ListOptions) (string, bool, *ConfigItem, error) {
+	if options.Limit < 0 || options.Limit > MaxListLimit {
+		return "", false, nil, newConfigError(CodeValidation, nil, "The limit should be between 0 (for no limit) and %d", MaxListLimit)
+	}
+
+	field := strings.TrimPrefix(options.Sort, "-")
+	descending := strings.HasPrefix(options.Sort, "-")
+	switch field {
+	case "":
+		field = SortApplication
+	case SortApplication, SortName, SortUpdated:
+	default:
+		return "", false, nil, newConfigError(CodeValidation, nil, "The sort should be application, name or updated (with a - prefix to sort in descending order)")
+	}
+
+	if options.Cursor == "" {
+		return field, descending, nil, nil
+	}
+
+	encoded, err := base64.RawURLEncoding.DecodeString(options.Cursor)
+	cursor := listCursor{}
+	if err == nil {
+		err = json.Unmarshal(encoded, &cursor)
+	}
+	if err != nil {
+		return "", false, nil, newConfigError(CodeValidation, err, "The cursor isn't valid")
+	}
+
+	after := ConfigItem{
+		Id:          cursor.Id,
+		Application: cursor.Application,
+		Name:        cursor.Name,
+		Machine:     cursor.Machine,
+		LastUpdated: cursor.Updated}
+
+	return field, descending, &after, nil
+}
+
+//	Gets the cursor for the page after the given item
+func getListCursor(item ConfigItem) string {
+	encoded, _ := json.Marshal(listCursor{
+		Id:          item.Id,
+		Application: item.Application,
+		Name:        item.Name,
+		Machine:     item.Machine,
+		Updated:     item.LastUpdated})
+
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+//	Gets the page for the items found by a store.  Stores look for one more item
+//	than the limit, so we know if there's another page
+func getListPage(items []ConfigItem, limit int) ConfigPage {
+	if limit > 0 && len(items) > limit {
+		return ConfigPage{Items: items[:limit], Next: getListCursor(items[limit-1])}
+	}
+
+	return ConfigPage{Items: items}
+}
+
+//	Returns true if the item matches the list filters (other than the applications)
+func matchesListOptions(item ConfigItem, options ListOptions) bool {
+	if !strings.HasPrefix(item.Name, options.NamePrefix) || !strings.Contains(item.Value, options.ValueContains) {
+		return false
+	}
+
+	if !HasConfigLabels(item, options.Labels) {
+		return false
+	}
+
+	if !options.IncludeExpired && IsExpired(item, time.Now()) {
+		return false
+	}
+
+	if options.Machines == nil {
+		return true
+	}
+
+	for _, machine := range options.Machines {
+		if item.Machine == machine {
+			return true
+		}
+	}
+
+	return false
+}
+
+//	Compares two items by the sort field.  Returns -1, 0 or 1
+func compareListItems(a, b ConfigItem, field string) int {
+	var keys [][2]string
+	switch field {
+	case SortName:
+		keys = [][2]string{{a.Name, b.Name}, {a.Application, b.Application}, {a.Machine, b.Machine}}
+	case SortUpdated:
+		if a.LastUpdated.Before(b.LastUpdated) {
+			return -1
+		}
+		if a.LastUpdated.After(b.LastUpdated) {
+			return 1
+		}
+		switch {
+		case a.Id < b.Id:
+			return -1
+		case a.Id > b.Id:
+			return 1
+		}
+		return 0
+	default:
+		keys = [][2]string{{a.Application, b.Application}, {a.Name, b.Name}, {a.Machine, b.Machine}}
+	}
+
+	for _, key := range keys {
+		if result := strings.Compare(key[0], key[1]); result != 0 {
+			return result
+		}
+	}
+
+	return 0
+}
+
+//	Sorts the items and gets the ones on the page after the given item (if there is one)
+func sortListItems(items []ConfigItem, field string, descending bool, after *ConfigItem, limit int) []ConfigItem {
+	direction := 1
+	if descending {
+		direction = -1
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareListItems(items[i], items[j], field)*direction < 0
+	})
+
+	if after != nil {
+		start := sort.Search(len(items), func(i int) bool {
+			return compareListItems(items[i], *after, field)*direction > 0
+		})
+		items = items[start:]
+	}
+
+	if limit > 0 && len(items) > limit+1 {
+		items = items[:limit+1]
+	}
+
+	return items
+}
+
+//	The columns items are sorted by in SQL (in order)
+func getSQLSortColumns(field string) []string {
+	switch field {
+	case SortName:
+		return []string{"name", "application", "machine"}
+	case SortUpdated:
+		return []string{"updated", "id"}
+	}
+
+	return []string{"application", "name", "machine"}
+}
+
+//	Gets the value of an item's SQL sort column
+func getSQLSortValue(item ConfigItem, column string) interface{} {
+	switch column {
+	case "name":
+		return item.Name
+	case "machine":
+		return item.Machine
+	case "updated":
+		return item.LastUpdated
+	case "id":
+		return item.Id
+	}
+
+	return item.Application
+}
+
+//	Escapes the LIKE wildcards in a string (with ! as the escape character, since
+//	MySQL and MSSQL treat backslashes in strings differently)
+func escapeSQLLike(value string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`, `[`, `![`).Replace(value)
+}
+
+//	Gets the where and order by clauses (and the arguments) for a SQL list query.
+//	Pages start after the cursor item with a 'keyset' condition, so the database
+//	can use its indexes instead of skipping rows
+func getSQLListClauses(options ListOptions, field string, descending bool, after *ConfigItem) (string, string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	//	Adds a condition like column in (?, ?, ?)
+	addIn := func(column string, values []string) {
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = "?"
+			args = append(args, value)
+		}
+		conditions = append(conditions, column+" in ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if len(options.Applications) > 0 {
+		addIn("application", options.Applications)
+	}
+
+	if options.Machines != nil {
+		if len(options.Machines) == 0 {
+			conditions = append(conditions, "1 = 0")
+		} else {
+			addIn("machine", options.Machines)
+		}
+	}
+
+	if options.NamePrefix != "" {
+		conditions = append(conditions, `name like ? escape '!'`)
+		args = append(args, escapeSQLLike(options.NamePrefix)+"%")
+	}
+
+	if options.ValueContains != "" {
+		conditions = append(conditions, `value like ? escape '!'`)
+		args = append(args, "%"+escapeSQLLike(options.ValueContains)+"%")
+	}
+
+	//	Labels are stored as JSON, so look for each label in it:
+	for _, label := range sortedLabelNames(options.Labels) {
+		conditions = append(conditions, `labels like ? escape '!'`)
+		args = append(args, getSQLLabelPattern(label, options.Labels[label]))
+	}
+
+	if !options.IncludeExpired {
+		conditions = append(conditions, "(expires is null or expires > ?)")
+		args = append(args, time.Now().UTC())
+	}
+
+	columns := getSQLSortColumns(field)
+	comparison, direction := ">", "asc"
+	if descending {
+		comparison, direction = "<", "desc"
+	}
+
+	//	Start after the cursor item: (a > ?) or (a = ? and b > ?) or ...
+	if after != nil {
+		keyset := []string{}
+		for i := range columns {
+			parts := []string{}
+			for _, column := range columns[:i] {
+				parts = append(parts, column+" = ?")
+				args = append(args, getSQLSortValue(*after, column))
+			}
+			parts = append(parts, columns[i]+" "+comparison+" ?")
+			args = append(args, getSQLSortValue(*after, columns[i]))
+			keyset = append(keyset, "("+strings.Join(parts, " and ")+")")
+		}
+		conditions = append(conditions, "("+strings.Join(keyset, " or ")+")")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " where " + strings.Join(conditions, " and ")
+	}
+
+	orderBy := make([]string, len(columns))
+	for i, column := range columns {
+		orderBy[i] = column + " " + direction
+	}
+
+	return where, " order by " + strings.Join(orderBy, ", "), args
+}
@@ -1,21 +1,28 @@
 package datastores
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"database/sql"
 	_ "github.com/denisenkom/go-mssqldb"
 )
 
-//	Database creation DDL
-var dbCreateMSSQL = []byte(`
+//	The statements at the start of the creation DDL
+const mssqlCreateHeader = `
 SET ANSI_NULLS ON
 GO
 
 SET QUOTED_IDENTIFIER ON
 GO
+`
 
+//	The tables (and their indexes), in the order they're created
+var mssqlTables = []sqlUpgrade{
+	{Check: getMSSQLTableCheck("configitem"), Statements: []string{`
 CREATE TABLE [dbo].[configitem](
 	[id] [bigint] IDENTITY(1,1) NOT NULL,
 	[application] [nvarchar](100) NOT NULL CONSTRAINT [DF_configitem_application]  DEFAULT (N'*'),
@@ -23,6 +30,9 @@ CREATE TABLE [dbo].[configitem](
 	[value] [nvarchar](max) NOT NULL,
 	[machine] [nvarchar](100) NOT NULL CONSTRAINT [DF_configitem_machine]  DEFAULT (N''),
 	[updated] [datetime] NOT NULL CONSTRAINT [DF_configitem_updated]  DEFAULT (getdate()),
+	[description] [nvarchar](1000) NOT NULL CONSTRAINT [DF_configitem_description]  DEFAULT (N''),
+	[labels] [nvarchar](4000) NOT NULL CONSTRAINT [DF_configitem_labels]  DEFAULT (N''),
+	[expires] [datetime] NULL,
  CONSTRAINT [PK_configitem] PRIMARY KEY CLUSTERED 
 (
 	[id] ASC
@@ -33,10 +43,108 @@ CREATE TABLE [dbo].[configitem](
 	[name] ASC,
 	[machine] ASC
 )WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
-) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]`, `
+CREATE NONCLUSTERED INDEX [idx_configitem_name] ON [dbo].[configitem]
+(
+	[name] ASC
+)`, `
+CREATE NONCLUSTERED INDEX [idx_configitem_updated] ON [dbo].[configitem]
+(
+	[updated] ASC
+)`, `
+CREATE NONCLUSTERED INDEX [idx_configitem_expires] ON [dbo].[configitem]
+(
+	[expires] ASC
+)`}},
 
-GO
-`)
+	{Check: getMSSQLTableCheck("configsequence"), Statements: []string{`
+CREATE TABLE [dbo].[configsequence](
+	[name] [nvarchar](100) NOT NULL,
+	[seq] [bigint] NOT NULL CONSTRAINT [DF_configsequence_seq]  DEFAULT ((0)),
+ CONSTRAINT [PK_configsequence] PRIMARY KEY CLUSTERED 
+(
+	[name] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY]`}},
+
+	{Check: getMSSQLTableCheck("configindex"), Statements: []string{`
+CREATE TABLE [dbo].[configindex](
+	[application] [nvarchar](100) NOT NULL,
+	[idx] [bigint] NOT NULL CONSTRAINT [DF_configindex_idx]  DEFAULT ((0)),
+ CONSTRAINT [PK_configindex] PRIMARY KEY CLUSTERED 
+(
+	[application] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY]`}},
+
+	{Check: getMSSQLTableCheck("configschedule"), Statements: []string{`
+CREATE TABLE [dbo].[configschedule](
+	[id] [bigint] IDENTITY(1,1) NOT NULL,
+	[application] [nvarchar](100) NOT NULL,
+	[name] [nvarchar](100) NOT NULL,
+	[value] [nvarchar](max) NOT NULL,
+	[machine] [nvarchar](100) NOT NULL CONSTRAINT [DF_configschedule_machine]  DEFAULT (N''),
+	[effective] [datetime2] NOT NULL,
+	[created] [datetime2] NOT NULL,
+ CONSTRAINT [PK_configschedule] PRIMARY KEY CLUSTERED 
+(
+	[id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]`, `
+CREATE NONCLUSTERED INDEX [idx_configschedule_effective] ON [dbo].[configschedule]
+(
+	[effective] ASC
+)`}},
+
+	{Check: getMSSQLTableCheck("configproposal"), Statements: []string{`
+CREATE TABLE [dbo].[configproposal](
+	[id] [bigint] IDENTITY(1,1) NOT NULL,
+	[action] [nvarchar](10) NOT NULL,
+	[application] [nvarchar](100) NOT NULL,
+	[name] [nvarchar](100) NOT NULL,
+	[machine] [nvarchar](100) NOT NULL CONSTRAINT [DF_configproposal_machine]  DEFAULT (N''),
+	[item] [nvarchar](max) NOT NULL,
+	[status] [nvarchar](20) NOT NULL,
+	[proposedby] [nvarchar](100) NOT NULL,
+	[proposed] [datetime2] NOT NULL,
+	[reviewedby] [nvarchar](100) NOT NULL CONSTRAINT [DF_configproposal_reviewedby]  DEFAULT (N''),
+	[reviewed] [datetime2] NULL,
+	[comment] [nvarchar](1000) NOT NULL CONSTRAINT [DF_configproposal_comment]  DEFAULT (N''),
+	[previous] [nvarchar](max) NULL,
+	[error] [nvarchar](1000) NOT NULL CONSTRAINT [DF_configproposal_error]  DEFAULT (N''),
+ CONSTRAINT [PK_configproposal] PRIMARY KEY CLUSTERED 
+(
+	[id] ASC
+)WITH (PAD_INDEX = OFF, STATISTICS_NORECOMPUTE = OFF, IGNORE_DUP_KEY = OFF, ALLOW_ROW_LOCKS = ON, ALLOW_PAGE_LOCKS = ON) ON [PRIMARY]
+) ON [PRIMARY] TEXTIMAGE_ON [PRIMARY]`, `
+CREATE NONCLUSTERED INDEX [idx_configproposal_status] ON [dbo].[configproposal]
+(
+	[status] ASC
+)`}},
+}
+
+//	The columns added to tables that databases from earlier releases have
+var mssqlColumns = []sqlUpgrade{
+	{Check: getMSSQLColumnCheck("configitem", "description"), Statements: []string{
+		"ALTER TABLE [dbo].[configitem] ADD [description] [nvarchar](1000) NOT NULL CONSTRAINT [DF_configitem_description]  DEFAULT (N'')"}},
+
+	{Check: getMSSQLColumnCheck("configitem", "labels"), Statements: []string{
+		"ALTER TABLE [dbo].[configitem] ADD [labels] [nvarchar](4000) NOT NULL CONSTRAINT [DF_configitem_labels]  DEFAULT (N'')"}},
+
+	{Check: getMSSQLColumnCheck("configitem", "expires"), Statements: []string{
+		"ALTER TABLE [dbo].[configitem] ADD [expires] [datetime] NULL",
+		"CREATE NONCLUSTERED INDEX [idx_configitem_expires] ON [dbo].[configitem] ([expires] ASC)"}},
+}
+
+//	Gets the query that checks for a table in the current database
+func getMSSQLTableCheck(table string) string {
+	return fmt.Sprintf("select count(*) from sys.tables where object_id=object_id(N'[dbo].[%s]')", table)
+}
+
+//	Gets the query that checks for a column in the current database
+func getMSSQLColumnCheck(table, column string) string {
+	return fmt.Sprintf("select count(*) from sys.columns where object_id=object_id(N'[dbo].[%s]') and name='%s'", table, column)
+}
 
 //	The MSSQL database information
 type MSSqlDB struct {
@@ -51,16 +159,22 @@ func (store MSSqlDB) InitStore(overwrite bool) error {
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return err
+		return getMSSQLError(err)
 	}
 
 	// Open doesn't open a connection. Validate DSN data:
 	err = db.Ping()
 	if err != nil {
-		return err
+		return getMSSQLError(err)
+	}
+
+	//	Create the tables and columns the database doesn't have yet (so
+	//	databases from earlier releases are upgraded):
+	if err := upgradeSQLStore(db, mssqlTables); err != nil {
+		return getMSSQLError(err)
 	}
 
-	return nil
+	return getMSSQLError(upgradeSQLStore(db, mssqlColumns))
 }
 
 func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
@@ -71,21 +185,21 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	//	Prepare our query
-	stmt, err := db.Prepare("select id, application, name, value, machine, updated from configitem where application=? and name=? and machine=? order by name")
+	stmt, err := db.Prepare("select id, application, name, value, machine, updated, description, labels, expires from configitem where application=? and name=? and machine=? order by name")
 	defer stmt.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	//	Get the application/name/machine combo
 	rows, err := stmt.Query(configItem.Application, configItem.Name, configItem.Machine)
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	for rows.Next() {
@@ -95,12 +209,15 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 		var value string
 		var machine string
 		var updated time.Time
+		var description string
+		var labels string
+		var expires sql.NullTime
 
 		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		//	Set our return value
@@ -110,7 +227,15 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			Name:        name,
 			Value:       value,
 			Machine:     machine,
-			LastUpdated: updated}
+			LastUpdated: updated,
+			Description: description,
+			Labels:      decodeSQLLabels(labels),
+			Expires:     readSQLNullTime(expires)}
+
+		//	Expired items are skipped (until they're swept)
+		if IsExpired(retval, time.Now()) {
+			retval = ConfigItem{}
+		}
 
 		break
 	}
@@ -120,7 +245,7 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 		rows, err = stmt.Query(configItem.Application, configItem.Name, "")
 		defer rows.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		for rows.Next() {
@@ -130,12 +255,15 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			var value string
 			var machine string
 			var updated time.Time
+			var description string
+			var labels string
+			var expires sql.NullTime
 
 			//	Scan the row into our variables
-			err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+			err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 			if err != nil {
-				return retval, err
+				return retval, getMSSQLError(err)
 			}
 
 			//	Set our return value
@@ -145,7 +273,15 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 				Name:        name,
 				Value:       value,
 				Machine:     machine,
-				LastUpdated: updated}
+				LastUpdated: updated,
+				Description: description,
+				Labels:      decodeSQLLabels(labels),
+				Expires:     readSQLNullTime(expires)}
+
+			//	Expired items are skipped (until they're swept)
+			if IsExpired(retval, time.Now()) {
+				retval = ConfigItem{}
+			}
 
 			break
 		}
@@ -156,7 +292,7 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 		rows, err = stmt.Query("*", configItem.Name, "")
 		defer rows.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		for rows.Next() {
@@ -166,12 +302,15 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			var value string
 			var machine string
 			var updated time.Time
+			var description string
+			var labels string
+			var expires sql.NullTime
 
 			//	Scan the row into our variables
-			err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+			err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 			if err != nil {
-				return retval, err
+				return retval, getMSSQLError(err)
 			}
 
 			//	Set our return value
@@ -181,12 +320,25 @@ func (store MSSqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 				Name:        name,
 				Value:       value,
 				Machine:     machine,
-				LastUpdated: updated}
+				LastUpdated: updated,
+				Description: description,
+				Labels:      decodeSQLLabels(labels),
+				Expires:     readSQLNullTime(expires)}
+
+			//	Expired items are skipped (until they're swept)
+			if IsExpired(retval, time.Now()) {
+				retval = ConfigItem{}
+			}
 
 			break
 		}
 	}
 
+	//	If we still haven't found it, say so:
+	if retval.Id == 0 {
+		return retval, notFoundError()
+	}
+
 	return retval, nil
 }
 
@@ -198,21 +350,21 @@ func (store MSSqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
-	//	Prepare our query
-	stmt, err := db.Prepare("select id, application, name, value, machine, updated from configitem where application=? order by name")
+	//	Prepare our query (the default * items are included)
+	stmt, err := db.Prepare("select id, application, name, value, machine, updated, description, labels, expires from configitem where application in ('*', ?) order by name")
 	defer stmt.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
-	//	Get all global config items
-	rows, err := stmt.Query("*")
+	//	Get the global and application config items:
+	rows, err := stmt.Query(application)
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	for rows.Next() {
@@ -222,12 +374,15 @@ func (store MSSqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 		var value string
 		var machine string
 		var updated time.Time
+		var description string
+		var labels string
+		var expires sql.NullTime
 
 		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		//	Append to return values
@@ -237,14 +392,31 @@ func (store MSSqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 			Name:        name,
 			Value:       value,
 			Machine:     machine,
-			LastUpdated: updated})
+			LastUpdated: updated,
+			Description: description,
+			Labels:      decodeSQLLabels(labels),
+			Expires:     readSQLNullTime(expires)})
 	}
 
-	//	Get config items for the given application:
-	rows, err = stmt.Query(application)
+	return retval, nil
+}
+
+func (store MSSqlDB) GetAll() ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	//	Get all config items
+	rows, err := db.Query("select id, application, name, value, machine, updated, description, labels, expires from configitem order by application, name")
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	for rows.Next() {
@@ -254,12 +426,15 @@ func (store MSSqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 		var value string
 		var machine string
 		var updated time.Time
+		var description string
+		var labels string
+		var expires sql.NullTime
 
 		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		//	Append to return values
@@ -269,56 +444,84 @@ func (store MSSqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 			Name:        name,
 			Value:       value,
 			Machine:     machine,
-			LastUpdated: updated})
+			LastUpdated: updated,
+			Description: description,
+			Labels:      decodeSQLLabels(labels),
+			Expires:     readSQLNullTime(expires)})
 	}
 
 	return retval, nil
 }
 
-func (store MSSqlDB) GetAll() ([]ConfigItem, error) {
-	//	Our return items:
-	retval := []ConfigItem{}
+//	Gets a page of config items (see ListOptions)
+func (store MSSqlDB) List(options ListOptions) (ConfigPage, error) {
+	field, descending, after, err := parseListOptions(options)
+	if err != nil {
+		return ConfigPage{Items: []ConfigItem{}}, err
+	}
 
 	//	Open the database:
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return ConfigPage{Items: []ConfigItem{}}, getMSSQLError(err)
 	}
 
-	//	Get all config items
-	rows, err := db.Query("select id, application, name, value, machine, updated from configitem order by application, name")
-	defer rows.Close()
+	//	Get one more item than the limit, so we know if there's another page:
+	where, orderBy, args := getSQLListClauses(options, field, descending, after)
+	query := "select id, application, name, value, machine, updated, description, labels, expires from configitem" + where + orderBy
+	if options.Limit > 0 {
+		query = "select top (?)" + strings.TrimPrefix(query, "select")
+		args = append([]interface{}{options.Limit + 1}, args...)
+	}
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return retval, err
+		return ConfigPage{Items: []ConfigItem{}}, getMSSQLError(err)
 	}
+	defer rows.Close()
 
+	items := []ConfigItem{}
 	for rows.Next() {
-		var id int64
-		var application string
-		var name string
-		var value string
-		var machine string
-		var updated time.Time
-
-		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		item := ConfigItem{}
+		var labels string
+		var expires sql.NullTime
 
-		if err != nil {
-			return retval, err
+		//	Scan the row into our item
+		if err := rows.Scan(&item.Id, &item.Application, &item.Name, &item.Value, &item.Machine, &item.LastUpdated, &item.Description, &labels, &expires); err != nil {
+			return ConfigPage{Items: []ConfigItem{}}, getMSSQLError(err)
 		}
+		item.Labels = decodeSQLLabels(labels)
+		item.Expires = readSQLNullTime(expires)
 
-		//	Append to return values
-		retval = append(retval, ConfigItem{
-			Id:          id,
-			Application: application,
-			Name:        name,
-			Value:       value,
-			Machine:     machine,
-			LastUpdated: updated})
+		items = append(items, item)
 	}
 
-	return retval, nil
+	if err := rows.Err(); err != nil {
+		return ConfigPage{Items: []ConfigItem{}}, getMSSQLError(err)
+	}
+
+	return getListPage(items, options.Limit), nil
+}
+
+//	Gets the config items for the application (every application if it's blank)
+//	with the prefix as their name, or under it (like db.primary.host under db.primary)
+func (store MSSqlDB) ListPrefix(application, prefix string) ([]ConfigItem, error) {
+	return listConfigSubtree(store, application, prefix, false)
+}
+
+//	Removes the config items for the application under the prefix (see ListPrefix)
+func (store MSSqlDB) RemovePrefix(application, prefix string) ([]ConfigItem, error) {
+	return removeConfigSubtree(store, application, prefix)
+}
+
+//	Moves the config items for the application under the from prefix to the to prefix
+func (store MSSqlDB) RenamePrefix(application, from, to string) ([]ConfigItem, []ConfigItem, error) {
+	return renameConfigSubtree(store, application, from, to)
+}
+
+//	Searches the names and values of config items (see SearchOptions)
+func (store MSSqlDB) Search(options SearchOptions) ([]ConfigItem, error) {
+	return searchConfigItems(store, options)
 }
 
 func (store MSSqlDB) GetAllApplications() ([]string, error) {
@@ -329,14 +532,14 @@ func (store MSSqlDB) GetAllApplications() ([]string, error) {
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	//	Get all applications
 	rows, err := db.Query("select distinct application from configitem order by application")
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	for rows.Next() {
@@ -346,7 +549,7 @@ func (store MSSqlDB) GetAllApplications() ([]string, error) {
 		err = rows.Scan(&application)
 
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		//	Append to return values
@@ -360,29 +563,34 @@ func (store MSSqlDB) Set(configItem ConfigItem) (ConfigItem, error) {
 	//	Our return item:
 	retval := ConfigItem{}
 
+	//	Make sure we can store the item:
+	if err := validateConfigItem(configItem); err != nil {
+		return retval, err
+	}
+
 	//	Open the database:
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMSSQLError(err)
 	}
 
 	if configItem.Id == 0 {
 		//	If we have a brand new item, insert it
-		stmt, err := db.Prepare("insert into configitem(application, name, value, machine) values(?, ?, ?, ?)")
+		stmt, err := db.Prepare("insert into configitem(application, name, value, machine, description, labels, expires) values(?, ?, ?, ?, ?, ?, ?)")
 		defer stmt.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
-		res, err := stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine)
+		res, err := stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires))
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		lastId, err := res.LastInsertId()
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		retval = ConfigItem{
@@ -391,19 +599,22 @@ func (store MSSqlDB) Set(configItem ConfigItem) (ConfigItem, error) {
 			Name:        configItem.Name,
 			Value:       configItem.Value,
 			Machine:     configItem.Machine,
-			LastUpdated: time.Now()}
+			LastUpdated: time.Now(),
+			Description: configItem.Description,
+			Labels:      configItem.Labels,
+			Expires:     configItem.Expires}
 
 	} else {
 		//	If we have an existing id, just update the old item
-		stmt, err := db.Prepare("update configitem set application=?, name=?, value=?, machine=? where id=?")
+		stmt, err := db.Prepare("update configitem set application=?, name=?, value=?, machine=?, description=?, labels=?, expires=? where id=?")
 		defer stmt.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
-		_, err = stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Id)
+		_, err = stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires), configItem.Id)
 		if err != nil {
-			return retval, err
+			return retval, getMSSQLError(err)
 		}
 
 		retval = ConfigItem{
@@ -412,10 +623,13 @@ func (store MSSqlDB) Set(configItem ConfigItem) (ConfigItem, error) {
 			Name:        configItem.Name,
 			Value:       configItem.Value,
 			Machine:     configItem.Machine,
-			LastUpdated: time.Now()}
+			LastUpdated: time.Now(),
+			Description: configItem.Description,
+			Labels:      configItem.Labels,
+			Expires:     configItem.Expires}
 	}
 
-	return retval, nil
+	return retval, getMSSQLError(updateMSSQLIndex(db, retval.Application))
 }
 
 func (store MSSqlDB) Remove(configItem ConfigItem) error {
@@ -423,17 +637,501 @@ func (store MSSqlDB) Remove(configItem ConfigItem) error {
 	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
 	defer db.Close()
 	if err != nil {
-		return err
+		return getMSSQLError(err)
 	}
 
-	_, err = db.Exec("delete from configitem where application=? and name=? and machine=?", configItem.Application, configItem.Name, configItem.Machine)
+	res, err := db.Exec("delete from configitem where application=? and name=? and machine=?", configItem.Application, configItem.Name, configItem.Machine)
 	if err != nil {
-		return err
+		return getMSSQLError(err)
+	}
+
+	//	Only removing something changes the application:
+	if removed, err := res.RowsAffected(); err != nil || removed == 0 {
+		return getMSSQLError(err)
+	}
+
+	return getMSSQLError(updateMSSQLIndex(db, configItem.Application))
+}
+
+//	Removes the config items that have expired by the given time, and returns them
+func (store MSSqlDB) RemoveExpired(now time.Time) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	//	Find the expired items:
+	rows, err := db.Query("select id, application, name, value, machine, updated, description, labels, expires from configitem where expires <= ?", now.UTC())
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+	defer rows.Close()
+
+	expired := []ConfigItem{}
+	for rows.Next() {
+		item := ConfigItem{}
+		var labels string
+		var expires sql.NullTime
+
+		//	Scan the row into our item
+		if err := rows.Scan(&item.Id, &item.Application, &item.Name, &item.Value, &item.Machine, &item.LastUpdated, &item.Description, &labels, &expires); err != nil {
+			return retval, getMSSQLError(err)
+		}
+		item.Labels = decodeSQLLabels(labels)
+		item.Expires = readSQLNullTime(expires)
+
+		expired = append(expired, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return retval, getMSSQLError(err)
+	}
+	rows.Close()
+
+	//	Remove them in a single transaction.  Items that were given a new
+	//	expiry time since we looked are left alone:
+	tx, err := db.Begin()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	for _, item := range expired {
+		res, err := tx.Exec("delete from configitem where id=? and expires <= ?", item.Id, now.UTC())
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMSSQLError(err)
+		}
+
+		removed, err := res.RowsAffected()
+		if err == nil && removed > 0 {
+			err = updateMSSQLIndex(tx, item.Application)
+			retval = append(retval, item)
+		}
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMSSQLError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []ConfigItem{}, getMSSQLError(err)
+	}
+
+	return retval, nil
+}
+
+func (store MSSqlDB) ScheduleChange(change ScheduledChange) (ScheduledChange, error) {
+	//	Make sure we can store the change:
+	if err := validateScheduledChange(change); err != nil {
+		return ScheduledChange{}, err
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return ScheduledChange{}, getMSSQLError(err)
+	}
+
+	//	Times are stored in UTC:
+	change.Created = time.Now()
+	err = db.QueryRow("insert into configschedule(application, name, value, machine, effective, created) output inserted.id values(?, ?, ?, ?, ?, ?)", change.Application, change.Name, change.Value, change.Machine, change.Effective.UTC(), change.Created.UTC()).Scan(&change.Id)
+	if err != nil {
+		return ScheduledChange{}, getMSSQLError(err)
+	}
+
+	return change, nil
+}
+
+func (store MSSqlDB) GetScheduledChanges() ([]ScheduledChange, error) {
+	//	Our return items:
+	retval := []ScheduledChange{}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	rows, err := db.Query("select id, application, name, value, machine, effective, created from configschedule order by effective, id")
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change := ScheduledChange{}
+
+		//	Scan the row into our change
+		if err := rows.Scan(&change.Id, &change.Application, &change.Name, &change.Value, &change.Machine, &change.Effective, &change.Created); err != nil {
+			return []ScheduledChange{}, getMSSQLError(err)
+		}
+		change.Effective = readSQLTime(change.Effective)
+		change.Created = readSQLTime(change.Created)
+
+		retval = append(retval, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []ScheduledChange{}, getMSSQLError(err)
+	}
+
+	return retval, nil
+}
+
+func (store MSSqlDB) CancelScheduledChange(id int64) (ScheduledChange, error) {
+	//	Our return item:
+	retval := ScheduledChange{}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	err = db.QueryRow("select id, application, name, value, machine, effective, created from configschedule where id=?", id).Scan(&retval.Id, &retval.Application, &retval.Name, &retval.Value, &retval.Machine, &retval.Effective, &retval.Created)
+	if err == sql.ErrNoRows {
+		return ScheduledChange{}, scheduledChangeNotFoundError(id)
+	}
+	if err != nil {
+		return ScheduledChange{}, getMSSQLError(err)
+	}
+	retval.Effective = readSQLTime(retval.Effective)
+	retval.Created = readSQLTime(retval.Created)
+
+	//	If someone else removed it first, it's theirs:
+	res, err := db.Exec("delete from configschedule where id=?", id)
+	if err != nil {
+		return ScheduledChange{}, getMSSQLError(err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return ScheduledChange{}, getMSSQLError(err)
+	}
+
+	if removed == 0 {
+		return ScheduledChange{}, scheduledChangeNotFoundError(id)
+	}
+
+	return retval, nil
+}
+
+func (store MSSqlDB) ApplyScheduledChanges(now time.Time) ([]ConfigItem, error) {
+	return applyScheduledChanges(store, now)
+}
+
+func (store MSSqlDB) ProposeChange(proposal ChangeProposal) (ChangeProposal, error) {
+	//	Make sure we can store the proposal:
+	if err := validateChangeProposal(proposal); err != nil {
+		return ChangeProposal{}, err
+	}
+
+	proposal.Item.Id = 0
+	proposal.Status = ProposalPending
+	proposal.Proposed = time.Now()
+
+	item, err := json.Marshal(proposal.Item)
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, getMSSQLError(err)
+	}
+
+	err = db.QueryRow("insert into configproposal(action, application, name, machine, item, status, proposedby, proposed) output inserted.id values(?, ?, ?, ?, ?, ?, ?, ?)", proposal.Action, proposal.Item.Application, proposal.Item.Name, proposal.Item.Machine, string(item), proposal.Status, proposal.ProposedBy, proposal.Proposed.UTC()).Scan(&proposal.Id)
+	if err != nil {
+		return ChangeProposal{}, getMSSQLError(err)
+	}
+
+	return proposal, nil
+}
+
+func (store MSSqlDB) GetProposals() ([]ChangeProposal, error) {
+	//	Our return items:
+	retval := []ChangeProposal{}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	rows, err := db.Query("select " + sqlProposalColumns + " from configproposal order by id")
+	if err != nil {
+		return retval, getMSSQLError(err)
 	}
+	defer rows.Close()
 
-	return nil
+	for rows.Next() {
+		proposal, err := scanSQLProposal(rows)
+		if err != nil {
+			return []ChangeProposal{}, getMSSQLError(err)
+		}
+
+		retval = append(retval, proposal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []ChangeProposal{}, getMSSQLError(err)
+	}
+
+	return retval, nil
+}
+
+func (store MSSqlDB) GetProposal(id int64) (ChangeProposal, error) {
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, getMSSQLError(err)
+	}
+
+	retval, err := scanSQLProposal(db.QueryRow("select "+sqlProposalColumns+" from configproposal where id=?", id))
+	if err == sql.ErrNoRows {
+		return ChangeProposal{}, proposalNotFoundError(id)
+	}
+
+	return retval, getMSSQLError(err)
+}
+
+//	Records the review if the proposal still has the status (in a single
+//	statement, so the status can't change in between)
+func (store MSSqlDB) UpdateProposal(proposal ChangeProposal, status string) (ChangeProposal, error) {
+	previous, err := getSQLProposalPrevious(proposal)
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, getMSSQLError(err)
+	}
+
+	res, err := db.Exec("update configproposal set status=?, reviewedby=?, reviewed=?, comment=?, previous=?, error=? where id=? and status=?", proposal.Status, proposal.ReviewedBy, getSQLNullTime(proposal.Reviewed), proposal.Comment, previous, proposal.Error, proposal.Id, status)
+	if err != nil {
+		return ChangeProposal{}, getMSSQLError(err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return ChangeProposal{}, getMSSQLError(err)
+	}
+
+	stored, err := store.GetProposal(proposal.Id)
+	if err == nil && updated == 0 {
+		err = proposalStatusError(stored)
+	}
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	return stored, nil
+}
+
+func (store MSSqlDB) ApproveProposal(id int64, reviewer, comment string) (ChangeProposal, ConfigItem, error) {
+	return approveChangeProposal(store, id, reviewer, comment)
+}
+
+func (store MSSqlDB) RejectProposal(id int64, reviewer, comment string) (ChangeProposal, error) {
+	return rejectChangeProposal(store, id, reviewer, comment)
+}
+
+func (store MSSqlDB) Batch(set []ConfigItem, remove []ConfigItem) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Make sure we can store the items:
+	for _, configItem := range set {
+		if err := validateConfigItem(configItem); err != nil {
+			return retval, err
+		}
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	//	Make all of the changes in a single transaction:
+	tx, err := db.Begin()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	for _, configItem := range set {
+		if configItem.Id == 0 {
+			//	If we have a brand new item, insert it
+			res, err := tx.Exec("insert into configitem(application, name, value, machine, description, labels, expires) values(?, ?, ?, ?, ?, ?, ?)", configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires))
+			if err != nil {
+				tx.Rollback()
+				return []ConfigItem{}, getMSSQLError(err)
+			}
+
+			configItem.Id, err = res.LastInsertId()
+			if err != nil {
+				tx.Rollback()
+				return []ConfigItem{}, getMSSQLError(err)
+			}
+		} else {
+			//	If we have an existing id, just update the old item
+			_, err := tx.Exec("update configitem set application=?, name=?, value=?, machine=?, description=?, labels=?, expires=? where id=?", configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires), configItem.Id)
+			if err != nil {
+				tx.Rollback()
+				return []ConfigItem{}, getMSSQLError(err)
+			}
+		}
+
+		if err := updateMSSQLIndex(tx, configItem.Application); err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMSSQLError(err)
+		}
+
+		configItem.LastUpdated = time.Now()
+		retval = append(retval, configItem)
+	}
+
+	for _, configItem := range remove {
+		res, err := tx.Exec("delete from configitem where application=? and name=? and machine=?", configItem.Application, configItem.Name, configItem.Machine)
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMSSQLError(err)
+		}
+
+		removed, err := res.RowsAffected()
+		if err == nil && removed > 0 {
+			err = updateMSSQLIndex(tx, configItem.Application)
+		}
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMSSQLError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []ConfigItem{}, getMSSQLError(err)
+	}
+
+	return retval, nil
 }
 
 func GetMSsqlCreateDDL() []byte {
-	return dbCreateMSSQL
+	return getSQLCreateDDL(mssqlCreateHeader, mssqlTables, "\nGO")
+}
+
+func (store MSSqlDB) GetEventSequence() (int64, error) {
+	//	Our return value:
+	retval := int64(0)
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	err = db.QueryRow("select seq from configsequence where name='events'").Scan(&retval)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return retval, getMSSQLError(err)
+}
+
+func (store MSSqlDB) NextEventSequence() (int64, error) {
+	//	Our return value:
+	retval := int64(0)
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	//	Increment (or start) the sequence in a single statement:
+	err = db.QueryRow(`merge configsequence with (holdlock) as target
+		using (select 'events' as name) as source on target.name = source.name
+		when matched then update set seq = target.seq + 1
+		when not matched then insert (name, seq) values (source.name, 1)
+		output inserted.seq;`).Scan(&retval)
+
+	return retval, getMSSQLError(err)
+}
+
+func (store MSSqlDB) GetApplicationIndex(application string) (int64, error) {
+	//	Our return value:
+	retval := int64(0)
+
+	//	Open the database:
+	db, err := sql.Open("mssql", fmt.Sprintf("server=%s;database=%s;user id=%s;password=%s", store.Address, store.Database, store.User, store.Password))
+	defer db.Close()
+	if err != nil {
+		return retval, getMSSQLError(err)
+	}
+
+	err = db.QueryRow("select idx from configindex where application=?", application).Scan(&retval)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return retval, getMSSQLError(err)
+}
+
+//	Sets the application's modification index to the next index
+func updateMSSQLIndex(db sqlExecutor, application string) error {
+	index := int64(0)
+	err := db.QueryRow(`merge configsequence with (holdlock) as target
+		using (select 'index' as name) as source on target.name = source.name
+		when matched then update set seq = target.seq + 1
+		when not matched then insert (name, seq) values (source.name, 1)
+		output inserted.seq;`).Scan(&index)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`merge configindex with (holdlock) as target
+		using (select ? as application) as source on target.application = source.application
+		when matched then update set idx = ?
+		when not matched then insert (application, idx) values (source.application, ?);`, application, index, index)
+	return err
+}
+
+//	Gets the error to return for an MSSQL error (with a code, if we know what it means)
+func getMSSQLError(err error) error {
+	if err == nil || ErrorCode(err) != CodeInternal {
+		return err
+	}
+
+	var mssqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &mssqlErr) {
+		switch mssqlErr.SQLErrorNumber() {
+		case 2601, 2627: //	Duplicate key
+			return conflictError(err)
+		case 4060, 18456: //	Can't open the database, login failed
+			return unavailableError(err)
+		}
+	}
+
+	if isConnectionError(err) {
+		return unavailableError(err)
+	}
+
+	return err
 }
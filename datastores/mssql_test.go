@@ -30,6 +30,10 @@ func resetMSSQLTestDB(store datastores.MSSqlDB) {
 	defer db.Close()
 
 	db.Exec("TRUNCATE TABLE configitem")
+	db.Exec("TRUNCATE TABLE configsequence")
+	db.Exec("TRUNCATE TABLE configindex")
+	db.Exec("TRUNCATE TABLE configschedule")
+	db.Exec("TRUNCATE TABLE configproposal")
 }
 
 //	MSSQL init should ping the database
@@ -51,8 +55,8 @@ func TestMssql_Init_Successful(t *testing.T) {
 	}
 }
 
-//	MSSQL get should return successfully even if the item doesn't exist
-func TestMssql_Get_ItemDoesntExist_Successful(t *testing.T) {
+//	MSSQL get should return a not found error if the item doesn't exist
+func TestMssql_Get_ItemDoesntExist_NotFound(t *testing.T) {
 
 	if runtime.GOOS != "windows" {
 		t.Skip("Skipping MSSQL tests: Not on Windows")
@@ -70,8 +74,8 @@ func TestMssql_Get_ItemDoesntExist_Successful(t *testing.T) {
 	response, err := db.Get(query)
 
 	//	Assert
-	if err != nil {
-		t.Errorf("Get failed: Should have returned an empty dataset without error: %s", err)
+	if !datastores.IsNotFound(err) {
+		t.Errorf("Get failed: Should have returned a not found error: %v", err)
 	}
 
 	if query.Value != response.Value && response.Value != "" {
@@ -555,3 +559,102 @@ func TestMssql_Remove_WithMachine_Successful(t *testing.T) {
 		t.Errorf("Remove failed: Should have removed an item without error: %s", err)
 	}
 }
+
+//	MSSQL batch should set and remove items together
+func TestMssql_Batch_Successful(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("Skipping MSSQL tests: Not on Windows")
+	}
+
+	//	Arrange
+	db := getMSSQLDBConnection()
+	resetMSSQLTestDB(db)
+
+	existing, _ := db.Set(datastores.ConfigItem{
+		Application: "MyTestAppName",
+		Name:        "TestItem1",
+		Value:       "Value1"})
+
+	set := []datastores.ConfigItem{
+		{Application: "MyTestAppName", Name: "TestItem2", Value: "Value2"},
+		{Application: "MyTestAppName", Name: "TestItem3", Value: "Value3"}}
+
+	//	Act
+	response, err := db.Batch(set, []datastores.ConfigItem{existing})
+
+	//	Assert
+	if err != nil {
+		t.Errorf("Batch failed: Should have completed without error: %s", err)
+	}
+
+	if len(response) != 2 {
+		t.Errorf("Batch failed: Should have returned the set items: %+v", response)
+	}
+
+	items, _ := db.GetAllForApplication("MyTestAppName")
+	if len(items) != 2 {
+		t.Errorf("Batch failed: Should have 2 items left but found %d: %+v", len(items), items)
+	}
+}
+
+//	MSSQL event sequence numbers should start at 0 and increase
+func TestMssql_EventSequence_Successful(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("Skipping MSSQL tests: Not on Windows")
+	}
+
+	//	Arrange
+	db := getMSSQLDBConnection()
+	resetMSSQLTestDB(db)
+
+	//	Act
+	initial, initialErr := db.GetEventSequence()
+	first, _ := db.NextEventSequence()
+	second, err := db.NextEventSequence()
+	current, currentErr := db.GetEventSequence()
+
+	//	Assert
+	if initialErr != nil || err != nil || currentErr != nil {
+		t.Errorf("EventSequence failed: Should have completed without error: %v / %v / %v", initialErr, err, currentErr)
+	}
+
+	if initial != 0 || first != 1 || second != 2 || current != 2 {
+		t.Errorf("EventSequence failed: Should have returned 0, 1, 2, 2 but returned %d, %d, %d, %d", initial, first, second, current)
+	}
+}
+
+//	MSSQL application indexes should increase when the application changes
+func TestMssql_ApplicationIndex_Successful(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("Skipping MSSQL tests: Not on Windows")
+	}
+
+	//	Arrange
+	db := getMSSQLDBConnection()
+	resetMSSQLTestDB(db)
+	item := datastores.ConfigItem{Application: "Formbuilder", Name: "Item1", Value: "Value1"}
+
+	//	Act
+	initial, initialErr := db.GetApplicationIndex("Formbuilder")
+	db.Set(item)
+	afterSet, _ := db.GetApplicationIndex("Formbuilder")
+	db.Set(datastores.ConfigItem{Application: "Other", Name: "Item1", Value: "Value1"})
+	afterOther, _ := db.GetApplicationIndex("Formbuilder")
+	db.Remove(datastores.ConfigItem{Application: "Formbuilder", Name: "Missing"})
+	afterMissing, _ := db.GetApplicationIndex("Formbuilder")
+	db.Remove(item)
+	afterRemove, err := db.GetApplicationIndex("Formbuilder")
+
+	//	Assert
+	if initialErr != nil || err != nil {
+		t.Errorf("ApplicationIndex failed: Should have completed without error: %v / %v", initialErr, err)
+	}
+
+	if initial != 0 || afterSet <= initial || afterRemove <= afterSet {
+		t.Errorf("ApplicationIndex failed: Should have increased with each change but returned %d, %d, %d", initial, afterSet, afterRemove)
+	}
+
+	if afterOther != afterSet || afterMissing != afterSet {
+		t.Errorf("ApplicationIndex failed: Other changes shouldn't affect the index: %d, %d, %d", afterSet, afterOther, afterMissing)
+	}
+}
@@ -1,15 +1,19 @@
 package datastores
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
-//	Requires at least MySQL 5.6 (for the auto updating datetime)
-var dbCreateMySQL = []byte(`
+//	The tables, in the order they're created.  Requires at least MySQL 5.6
+//	(for the auto updating datetime)
+var mysqlTables = []sqlUpgrade{
+	{Check: getMySQLTableCheck("configitem"), Statements: []string{`
 CREATE TABLE configitem (
   id int(11) NOT NULL AUTO_INCREMENT,
   application varchar(100) NOT NULL DEFAULT '*',
@@ -17,12 +21,94 @@ CREATE TABLE configitem (
   value longtext NOT NULL,
   machine varchar(100) NOT NULL DEFAULT '',
   updated datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  description varchar(1000) NOT NULL DEFAULT '',
+  labels varchar(4000) NOT NULL DEFAULT '',
+  expires datetime NULL DEFAULT NULL,
   PRIMARY KEY (id),
   UNIQUE KEY id_UNIQUE (id),
   UNIQUE KEY app_name_machine (application,name,machine),
-  KEY idx_application (application)
-) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8;
-`)
+  KEY idx_application (application),
+  KEY idx_name (name),
+  KEY idx_updated (updated),
+  KEY idx_expires (expires)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8`}},
+
+	{Check: getMySQLTableCheck("configsequence"), Statements: []string{`
+CREATE TABLE configsequence (
+  name varchar(100) NOT NULL,
+  seq bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8`}},
+
+	{Check: getMySQLTableCheck("configindex"), Statements: []string{`
+CREATE TABLE configindex (
+  application varchar(100) NOT NULL,
+  idx bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (application)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8`}},
+
+	{Check: getMySQLTableCheck("configschedule"), Statements: []string{`
+CREATE TABLE configschedule (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  application varchar(100) NOT NULL,
+  name varchar(100) NOT NULL,
+  value longtext NOT NULL,
+  machine varchar(100) NOT NULL DEFAULT '',
+  effective datetime(6) NOT NULL,
+  created datetime(6) NOT NULL,
+  PRIMARY KEY (id),
+  KEY idx_effective (effective)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8`}},
+
+	{Check: getMySQLTableCheck("configproposal"), Statements: []string{`
+CREATE TABLE configproposal (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  action varchar(10) NOT NULL,
+  application varchar(100) NOT NULL,
+  name varchar(100) NOT NULL,
+  machine varchar(100) NOT NULL DEFAULT '',
+  item longtext NOT NULL,
+  status varchar(20) NOT NULL,
+  proposedby varchar(100) NOT NULL,
+  proposed datetime(6) NOT NULL,
+  reviewedby varchar(100) NOT NULL DEFAULT '',
+  reviewed datetime(6) NULL DEFAULT NULL,
+  comment varchar(1000) NOT NULL DEFAULT '',
+  previous longtext NULL,
+  error varchar(1000) NOT NULL DEFAULT '',
+  PRIMARY KEY (id),
+  KEY idx_application (application),
+  KEY idx_status (status)
+) ENGINE=InnoDB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8`}},
+}
+
+//	The columns added to tables that databases from earlier releases have
+var mysqlColumns = []sqlUpgrade{
+	{Check: getMySQLColumnCheck("configitem", "description"), Statements: []string{
+		"ALTER TABLE configitem ADD COLUMN description varchar(1000) NOT NULL DEFAULT ''"}},
+
+	{Check: getMySQLColumnCheck("configitem", "labels"), Statements: []string{
+		"ALTER TABLE configitem ADD COLUMN labels varchar(4000) NOT NULL DEFAULT ''"}},
+
+	{Check: getMySQLColumnCheck("configitem", "expires"), Statements: []string{
+		"ALTER TABLE configitem ADD COLUMN expires datetime NULL DEFAULT NULL, ADD KEY idx_expires (expires)"}},
+}
+
+//	Gets the query that checks for a table in the current database
+func getMySQLTableCheck(table string) string {
+	return fmt.Sprintf("select count(*) from information_schema.tables where table_schema=database() and table_name='%s'", table)
+}
+
+//	Gets the query that checks for a column in the current database
+func getMySQLColumnCheck(table, column string) string {
+	return fmt.Sprintf("select count(*) from information_schema.columns where table_schema=database() and table_name='%s' and column_name='%s'", table, column)
+}
+
+//	Runs SQL statements (a *sql.DB or a *sql.Tx)
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
 
 //	The MysqlDB database information
 type MySqlDB struct {
@@ -38,16 +124,22 @@ func (store MySqlDB) InitStore(overwrite bool) error {
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
 	if err != nil {
-		return err
+		return getMySQLError(err)
 	}
 
 	// Open doesn't open a connection. Validate DSN data:
 	err = db.Ping()
 	if err != nil {
-		return err
+		return getMySQLError(err)
 	}
 
-	return nil
+	//	Create the tables and columns the database doesn't have yet (so
+	//	databases from earlier releases are upgraded):
+	if err := upgradeSQLStore(db, mysqlTables); err != nil {
+		return getMySQLError(err)
+	}
+
+	return getMySQLError(upgradeSQLStore(db, mysqlColumns))
 }
 
 func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
@@ -58,21 +150,21 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	//	Prepare our query
-	stmt, err := db.Prepare("select id, application, name, value, machine, updated from configitem where application=? and name=? and machine=? order by name")
+	stmt, err := db.Prepare("select id, application, name, value, machine, updated, description, labels, expires from configitem where application=? and name=? and machine=? order by name")
 	defer stmt.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	//	Get the application/name/machine combo
 	rows, err := stmt.Query(configItem.Application, configItem.Name, configItem.Machine)
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	for rows.Next() {
@@ -82,12 +174,15 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 		var value string
 		var machine string
 		var updated time.Time
+		var description string
+		var labels string
+		var expires sql.NullTime
 
 		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		//	Set our return value
@@ -97,7 +192,15 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			Name:        name,
 			Value:       value,
 			Machine:     machine,
-			LastUpdated: updated}
+			LastUpdated: updated,
+			Description: description,
+			Labels:      decodeSQLLabels(labels),
+			Expires:     readSQLNullTime(expires)}
+
+		//	Expired items are skipped (until they're swept)
+		if IsExpired(retval, time.Now()) {
+			retval = ConfigItem{}
+		}
 
 		break
 	}
@@ -107,7 +210,7 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 		rows, err = stmt.Query(configItem.Application, configItem.Name, "")
 		defer rows.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		for rows.Next() {
@@ -117,12 +220,15 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			var value string
 			var machine string
 			var updated time.Time
+			var description string
+			var labels string
+			var expires sql.NullTime
 
 			//	Scan the row into our variables
-			err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+			err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 			if err != nil {
-				return retval, err
+				return retval, getMySQLError(err)
 			}
 
 			//	Set our return value
@@ -132,7 +238,15 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 				Name:        name,
 				Value:       value,
 				Machine:     machine,
-				LastUpdated: updated}
+				LastUpdated: updated,
+				Description: description,
+				Labels:      decodeSQLLabels(labels),
+				Expires:     readSQLNullTime(expires)}
+
+			//	Expired items are skipped (until they're swept)
+			if IsExpired(retval, time.Now()) {
+				retval = ConfigItem{}
+			}
 
 			break
 		}
@@ -143,7 +257,7 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 		rows, err = stmt.Query("*", configItem.Name, "")
 		defer rows.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		for rows.Next() {
@@ -153,12 +267,15 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 			var value string
 			var machine string
 			var updated time.Time
+			var description string
+			var labels string
+			var expires sql.NullTime
 
 			//	Scan the row into our variables
-			err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+			err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 			if err != nil {
-				return retval, err
+				return retval, getMySQLError(err)
 			}
 
 			//	Set our return value
@@ -168,12 +285,25 @@ func (store MySqlDB) Get(configItem ConfigItem) (ConfigItem, error) {
 				Name:        name,
 				Value:       value,
 				Machine:     machine,
-				LastUpdated: updated}
+				LastUpdated: updated,
+				Description: description,
+				Labels:      decodeSQLLabels(labels),
+				Expires:     readSQLNullTime(expires)}
+
+			//	Expired items are skipped (until they're swept)
+			if IsExpired(retval, time.Now()) {
+				retval = ConfigItem{}
+			}
 
 			break
 		}
 	}
 
+	//	If we still haven't found it, say so:
+	if retval.Id == 0 {
+		return retval, notFoundError()
+	}
+
 	return retval, nil
 }
 
@@ -185,21 +315,21 @@ func (store MySqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
-	//	Prepare our query
-	stmt, err := db.Prepare("select id, application, name, value, machine, updated from configitem where application=? order by name")
+	//	Prepare our query (the default * items are included)
+	stmt, err := db.Prepare("select id, application, name, value, machine, updated, description, labels, expires from configitem where application in ('*', ?) order by name")
 	defer stmt.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
-	//	Get all global config items
-	rows, err := stmt.Query("*")
+	//	Get the global and application config items:
+	rows, err := stmt.Query(application)
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	for rows.Next() {
@@ -209,12 +339,15 @@ func (store MySqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 		var value string
 		var machine string
 		var updated time.Time
+		var description string
+		var labels string
+		var expires sql.NullTime
 
 		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		//	Append to return values
@@ -224,14 +357,31 @@ func (store MySqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 			Name:        name,
 			Value:       value,
 			Machine:     machine,
-			LastUpdated: updated})
+			LastUpdated: updated,
+			Description: description,
+			Labels:      decodeSQLLabels(labels),
+			Expires:     readSQLNullTime(expires)})
 	}
 
-	//	Get config items for the given application:
-	rows, err = stmt.Query(application)
+	return retval, nil
+}
+
+func (store MySqlDB) GetAll() ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	//	Get all config items
+	rows, err := db.Query("select id, application, name, value, machine, updated, description, labels, expires from configitem order by application, name")
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	for rows.Next() {
@@ -241,12 +391,15 @@ func (store MySqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 		var value string
 		var machine string
 		var updated time.Time
+		var description string
+		var labels string
+		var expires sql.NullTime
 
 		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
+		err = rows.Scan(&id, &application, &name, &value, &machine, &updated, &description, &labels, &expires)
 
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		//	Append to return values
@@ -256,56 +409,84 @@ func (store MySqlDB) GetAllForApplication(application string) ([]ConfigItem, err
 			Name:        name,
 			Value:       value,
 			Machine:     machine,
-			LastUpdated: updated})
+			LastUpdated: updated,
+			Description: description,
+			Labels:      decodeSQLLabels(labels),
+			Expires:     readSQLNullTime(expires)})
 	}
 
 	return retval, nil
 }
 
-func (store MySqlDB) GetAll() ([]ConfigItem, error) {
-	//	Our return items:
-	retval := []ConfigItem{}
+//	Gets a page of config items (see ListOptions)
+func (store MySqlDB) List(options ListOptions) (ConfigPage, error) {
+	field, descending, after, err := parseListOptions(options)
+	if err != nil {
+		return ConfigPage{Items: []ConfigItem{}}, err
+	}
 
 	//	Open the database:
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return ConfigPage{Items: []ConfigItem{}}, getMySQLError(err)
 	}
 
-	//	Get all config items
-	rows, err := db.Query("select id, application, name, value, machine, updated from configitem order by application, name")
-	defer rows.Close()
+	//	Get one more item than the limit, so we know if there's another page:
+	where, orderBy, args := getSQLListClauses(options, field, descending, after)
+	query := "select id, application, name, value, machine, updated, description, labels, expires from configitem" + where + orderBy
+	if options.Limit > 0 {
+		query += " limit ?"
+		args = append(args, options.Limit+1)
+	}
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return retval, err
+		return ConfigPage{Items: []ConfigItem{}}, getMySQLError(err)
 	}
+	defer rows.Close()
 
+	items := []ConfigItem{}
 	for rows.Next() {
-		var id int64
-		var application string
-		var name string
-		var value string
-		var machine string
-		var updated time.Time
+		item := ConfigItem{}
+		var labels string
+		var expires sql.NullTime
 
-		//	Scan the row into our variables
-		err = rows.Scan(&id, &application, &name, &value, &machine, &updated)
-
-		if err != nil {
-			return retval, err
+		//	Scan the row into our item
+		if err := rows.Scan(&item.Id, &item.Application, &item.Name, &item.Value, &item.Machine, &item.LastUpdated, &item.Description, &labels, &expires); err != nil {
+			return ConfigPage{Items: []ConfigItem{}}, getMySQLError(err)
 		}
+		item.Labels = decodeSQLLabels(labels)
+		item.Expires = readSQLNullTime(expires)
 
-		//	Append to return values
-		retval = append(retval, ConfigItem{
-			Id:          id,
-			Application: application,
-			Name:        name,
-			Value:       value,
-			Machine:     machine,
-			LastUpdated: updated})
+		items = append(items, item)
 	}
 
-	return retval, nil
+	if err := rows.Err(); err != nil {
+		return ConfigPage{Items: []ConfigItem{}}, getMySQLError(err)
+	}
+
+	return getListPage(items, options.Limit), nil
+}
+
+//	Gets the config items for the application (every application if it's blank)
+//	with the prefix as their name, or under it (like db.primary.host under db.primary)
+func (store MySqlDB) ListPrefix(application, prefix string) ([]ConfigItem, error) {
+	return listConfigSubtree(store, application, prefix, false)
+}
+
+//	Removes the config items for the application under the prefix (see ListPrefix)
+func (store MySqlDB) RemovePrefix(application, prefix string) ([]ConfigItem, error) {
+	return removeConfigSubtree(store, application, prefix)
+}
+
+//	Moves the config items for the application under the from prefix to the to prefix
+func (store MySqlDB) RenamePrefix(application, from, to string) ([]ConfigItem, []ConfigItem, error) {
+	return renameConfigSubtree(store, application, from, to)
+}
+
+//	Searches the names and values of config items (see SearchOptions)
+func (store MySqlDB) Search(options SearchOptions) ([]ConfigItem, error) {
+	return searchConfigItems(store, options)
 }
 
 func (store MySqlDB) GetAllApplications() ([]string, error) {
@@ -316,14 +497,14 @@ func (store MySqlDB) GetAllApplications() ([]string, error) {
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	//	Get all applications
 	rows, err := db.Query("select distinct application from configitem order by application")
 	defer rows.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	for rows.Next() {
@@ -333,7 +514,7 @@ func (store MySqlDB) GetAllApplications() ([]string, error) {
 		err = rows.Scan(&application)
 
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		//	Append to return values
@@ -347,29 +528,34 @@ func (store MySqlDB) Set(configItem ConfigItem) (ConfigItem, error) {
 	//	Our return item:
 	retval := ConfigItem{}
 
+	//	Make sure we can store the item:
+	if err := validateConfigItem(configItem); err != nil {
+		return retval, err
+	}
+
 	//	Open the database:
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
 	if err != nil {
-		return retval, err
+		return retval, getMySQLError(err)
 	}
 
 	if configItem.Id == 0 {
 		//	If we have a brand new item, insert it
-		stmt, err := db.Prepare("insert into configitem(application, name, value, machine) values(?, ?, ?, ?)")
+		stmt, err := db.Prepare("insert into configitem(application, name, value, machine, description, labels, expires) values(?, ?, ?, ?, ?, ?, ?)")
 		defer stmt.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
-		res, err := stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine)
+		res, err := stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires))
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		lastId, err := res.LastInsertId()
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		retval = ConfigItem{
@@ -378,19 +564,22 @@ func (store MySqlDB) Set(configItem ConfigItem) (ConfigItem, error) {
 			Name:        configItem.Name,
 			Value:       configItem.Value,
 			Machine:     configItem.Machine,
-			LastUpdated: time.Now()}
+			LastUpdated: time.Now(),
+			Description: configItem.Description,
+			Labels:      configItem.Labels,
+			Expires:     configItem.Expires}
 
 	} else {
 		//	If we have an existing id, just update the old item
-		stmt, err := db.Prepare("update configitem set application=?, name=?, value=?, machine=? where id=?")
+		stmt, err := db.Prepare("update configitem set application=?, name=?, value=?, machine=?, description=?, labels=?, expires=? where id=?")
 		defer stmt.Close()
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
-		_, err = stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Id)
+		_, err = stmt.Exec(configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires), configItem.Id)
 		if err != nil {
-			return retval, err
+			return retval, getMySQLError(err)
 		}
 
 		retval = ConfigItem{
@@ -399,28 +588,518 @@ func (store MySqlDB) Set(configItem ConfigItem) (ConfigItem, error) {
 			Name:        configItem.Name,
 			Value:       configItem.Value,
 			Machine:     configItem.Machine,
-			LastUpdated: time.Now()}
+			LastUpdated: time.Now(),
+			Description: configItem.Description,
+			Labels:      configItem.Labels,
+			Expires:     configItem.Expires}
 	}
 
-	return retval, nil
+	return retval, getMySQLError(updateMySQLIndex(db, retval.Application))
 }
 
 func (store MySqlDB) Remove(configItem ConfigItem) error {
 	//	Open the database:
 	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
 	defer db.Close()
+	if err != nil {
+		return getMySQLError(err)
+	}
+
+	res, err := db.Exec("delete from configitem where application=? and name=? and machine=?", configItem.Application, configItem.Name, configItem.Machine)
+	if err != nil {
+		return getMySQLError(err)
+	}
+
+	//	Only removing something changes the application:
+	if removed, err := res.RowsAffected(); err != nil || removed == 0 {
+		return getMySQLError(err)
+	}
+
+	return getMySQLError(updateMySQLIndex(db, configItem.Application))
+}
+
+//	Removes the config items that have expired by the given time, and returns them
+func (store MySqlDB) RemoveExpired(now time.Time) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	//	Find the expired items:
+	rows, err := db.Query("select id, application, name, value, machine, updated, description, labels, expires from configitem where expires <= ?", now.UTC())
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+	defer rows.Close()
+
+	expired := []ConfigItem{}
+	for rows.Next() {
+		item := ConfigItem{}
+		var labels string
+		var expires sql.NullTime
+
+		//	Scan the row into our item
+		if err := rows.Scan(&item.Id, &item.Application, &item.Name, &item.Value, &item.Machine, &item.LastUpdated, &item.Description, &labels, &expires); err != nil {
+			return retval, getMySQLError(err)
+		}
+		item.Labels = decodeSQLLabels(labels)
+		item.Expires = readSQLNullTime(expires)
+
+		expired = append(expired, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return retval, getMySQLError(err)
+	}
+	rows.Close()
+
+	//	Remove them in a single transaction.  Items that were given a new
+	//	expiry time since we looked are left alone:
+	tx, err := db.Begin()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	for _, item := range expired {
+		res, err := tx.Exec("delete from configitem where id=? and expires <= ?", item.Id, now.UTC())
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMySQLError(err)
+		}
+
+		removed, err := res.RowsAffected()
+		if err == nil && removed > 0 {
+			err = updateMySQLIndex(tx, item.Application)
+			retval = append(retval, item)
+		}
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMySQLError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []ConfigItem{}, getMySQLError(err)
+	}
+
+	return retval, nil
+}
+
+func (store MySqlDB) ScheduleChange(change ScheduledChange) (ScheduledChange, error) {
+	//	Make sure we can store the change:
+	if err := validateScheduledChange(change); err != nil {
+		return ScheduledChange{}, err
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return ScheduledChange{}, getMySQLError(err)
+	}
+
+	//	Times are stored in UTC:
+	change.Created = time.Now()
+	res, err := db.Exec("insert into configschedule(application, name, value, machine, effective, created) values(?, ?, ?, ?, ?, ?)", change.Application, change.Name, change.Value, change.Machine, change.Effective.UTC(), change.Created.UTC())
+	if err != nil {
+		return ScheduledChange{}, getMySQLError(err)
+	}
+
+	change.Id, err = res.LastInsertId()
+	if err != nil {
+		return ScheduledChange{}, getMySQLError(err)
+	}
+
+	return change, nil
+}
+
+func (store MySqlDB) GetScheduledChanges() ([]ScheduledChange, error) {
+	//	Our return items:
+	retval := []ScheduledChange{}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	rows, err := db.Query("select id, application, name, value, machine, effective, created from configschedule order by effective, id")
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change := ScheduledChange{}
+
+		//	Scan the row into our change
+		if err := rows.Scan(&change.Id, &change.Application, &change.Name, &change.Value, &change.Machine, &change.Effective, &change.Created); err != nil {
+			return []ScheduledChange{}, getMySQLError(err)
+		}
+		change.Effective = readSQLTime(change.Effective)
+		change.Created = readSQLTime(change.Created)
+
+		retval = append(retval, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []ScheduledChange{}, getMySQLError(err)
+	}
+
+	return retval, nil
+}
+
+func (store MySqlDB) CancelScheduledChange(id int64) (ScheduledChange, error) {
+	//	Our return item:
+	retval := ScheduledChange{}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	err = db.QueryRow("select id, application, name, value, machine, effective, created from configschedule where id=?", id).Scan(&retval.Id, &retval.Application, &retval.Name, &retval.Value, &retval.Machine, &retval.Effective, &retval.Created)
+	if err == sql.ErrNoRows {
+		return ScheduledChange{}, scheduledChangeNotFoundError(id)
+	}
+	if err != nil {
+		return ScheduledChange{}, getMySQLError(err)
+	}
+	retval.Effective = readSQLTime(retval.Effective)
+	retval.Created = readSQLTime(retval.Created)
+
+	//	If someone else removed it first, it's theirs:
+	res, err := db.Exec("delete from configschedule where id=?", id)
+	if err != nil {
+		return ScheduledChange{}, getMySQLError(err)
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return ScheduledChange{}, getMySQLError(err)
+	}
+
+	if removed == 0 {
+		return ScheduledChange{}, scheduledChangeNotFoundError(id)
+	}
+
+	return retval, nil
+}
+
+func (store MySqlDB) ApplyScheduledChanges(now time.Time) ([]ConfigItem, error) {
+	return applyScheduledChanges(store, now)
+}
+
+func (store MySqlDB) ProposeChange(proposal ChangeProposal) (ChangeProposal, error) {
+	//	Make sure we can store the proposal:
+	if err := validateChangeProposal(proposal); err != nil {
+		return ChangeProposal{}, err
+	}
+
+	proposal.Item.Id = 0
+	proposal.Status = ProposalPending
+	proposal.Proposed = time.Now()
+
+	item, err := json.Marshal(proposal.Item)
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	res, err := db.Exec("insert into configproposal(action, application, name, machine, item, status, proposedby, proposed) values(?, ?, ?, ?, ?, ?, ?, ?)", proposal.Action, proposal.Item.Application, proposal.Item.Name, proposal.Item.Machine, string(item), proposal.Status, proposal.ProposedBy, proposal.Proposed.UTC())
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	proposal.Id, err = res.LastInsertId()
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	return proposal, nil
+}
+
+func (store MySqlDB) GetProposals() ([]ChangeProposal, error) {
+	//	Our return items:
+	retval := []ChangeProposal{}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	rows, err := db.Query("select " + sqlProposalColumns + " from configproposal order by id")
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		proposal, err := scanSQLProposal(rows)
+		if err != nil {
+			return []ChangeProposal{}, getMySQLError(err)
+		}
+
+		retval = append(retval, proposal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []ChangeProposal{}, getMySQLError(err)
+	}
+
+	return retval, nil
+}
+
+func (store MySqlDB) GetProposal(id int64) (ChangeProposal, error) {
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	retval, err := scanSQLProposal(db.QueryRow("select "+sqlProposalColumns+" from configproposal where id=?", id))
+	if err == sql.ErrNoRows {
+		return ChangeProposal{}, proposalNotFoundError(id)
+	}
+
+	return retval, getMySQLError(err)
+}
+
+//	Records the review if the proposal still has the status (in a single
+//	statement, so the status can't change in between)
+func (store MySqlDB) UpdateProposal(proposal ChangeProposal, status string) (ChangeProposal, error) {
+	previous, err := getSQLProposalPrevious(proposal)
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	res, err := db.Exec("update configproposal set status=?, reviewedby=?, reviewed=?, comment=?, previous=?, error=? where id=? and status=?", proposal.Status, proposal.ReviewedBy, getSQLNullTime(proposal.Reviewed), proposal.Comment, previous, proposal.Error, proposal.Id, status)
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return ChangeProposal{}, getMySQLError(err)
+	}
+
+	stored, err := store.GetProposal(proposal.Id)
+	if err == nil && updated == 0 {
+		err = proposalStatusError(stored)
+	}
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	return stored, nil
+}
+
+func (store MySqlDB) ApproveProposal(id int64, reviewer, comment string) (ChangeProposal, ConfigItem, error) {
+	return approveChangeProposal(store, id, reviewer, comment)
+}
+
+func (store MySqlDB) RejectProposal(id int64, reviewer, comment string) (ChangeProposal, error) {
+	return rejectChangeProposal(store, id, reviewer, comment)
+}
+
+func (store MySqlDB) Batch(set []ConfigItem, remove []ConfigItem) ([]ConfigItem, error) {
+	//	Our return items:
+	retval := []ConfigItem{}
+
+	//	Make sure we can store the items:
+	for _, configItem := range set {
+		if err := validateConfigItem(configItem); err != nil {
+			return retval, err
+		}
+	}
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	//	Make all of the changes in a single transaction:
+	tx, err := db.Begin()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	for _, configItem := range set {
+		if configItem.Id == 0 {
+			//	If we have a brand new item, insert it
+			res, err := tx.Exec("insert into configitem(application, name, value, machine, description, labels, expires) values(?, ?, ?, ?, ?, ?, ?)", configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires))
+			if err != nil {
+				tx.Rollback()
+				return []ConfigItem{}, getMySQLError(err)
+			}
+
+			configItem.Id, err = res.LastInsertId()
+			if err != nil {
+				tx.Rollback()
+				return []ConfigItem{}, getMySQLError(err)
+			}
+		} else {
+			//	If we have an existing id, just update the old item
+			_, err := tx.Exec("update configitem set application=?, name=?, value=?, machine=?, description=?, labels=?, expires=? where id=?", configItem.Application, configItem.Name, configItem.Value, configItem.Machine, configItem.Description, encodeSQLLabels(configItem.Labels), getSQLNullTime(configItem.Expires), configItem.Id)
+			if err != nil {
+				tx.Rollback()
+				return []ConfigItem{}, getMySQLError(err)
+			}
+		}
+
+		if err := updateMySQLIndex(tx, configItem.Application); err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMySQLError(err)
+		}
+
+		configItem.LastUpdated = time.Now()
+		retval = append(retval, configItem)
+	}
+
+	for _, configItem := range remove {
+		res, err := tx.Exec("delete from configitem where application=? and name=? and machine=?", configItem.Application, configItem.Name, configItem.Machine)
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMySQLError(err)
+		}
+
+		removed, err := res.RowsAffected()
+		if err == nil && removed > 0 {
+			err = updateMySQLIndex(tx, configItem.Application)
+		}
+		if err != nil {
+			tx.Rollback()
+			return []ConfigItem{}, getMySQLError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []ConfigItem{}, getMySQLError(err)
+	}
+
+	return retval, nil
+}
+
+func GetMysqlCreateDDL() []byte {
+	return getSQLCreateDDL("", mysqlTables, ";")
+}
+
+func (store MySqlDB) GetEventSequence() (int64, error) {
+	//	Our return value:
+	retval := int64(0)
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	err = db.QueryRow("select seq from configsequence where name='events'").Scan(&retval)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return retval, getMySQLError(err)
+}
+
+func (store MySqlDB) NextEventSequence() (int64, error) {
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return 0, getMySQLError(err)
+	}
+
+	//	LAST_INSERT_ID(expr) hands the new value back to us on this connection:
+	res, err := db.Exec("insert into configsequence(name, seq) values('events', LAST_INSERT_ID(1)) on duplicate key update seq=LAST_INSERT_ID(seq+1)")
+	if err != nil {
+		return 0, getMySQLError(err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (store MySqlDB) GetApplicationIndex(application string) (int64, error) {
+	//	Our return value:
+	retval := int64(0)
+
+	//	Open the database:
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+	if err != nil {
+		return retval, getMySQLError(err)
+	}
+
+	err = db.QueryRow("select idx from configindex where application=?", application).Scan(&retval)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return retval, getMySQLError(err)
+}
+
+//	Sets the application's modification index to the next index
+func updateMySQLIndex(db sqlExecutor, application string) error {
+	res, err := db.Exec("insert into configsequence(name, seq) values('index', LAST_INSERT_ID(1)) on duplicate key update seq=LAST_INSERT_ID(seq+1)")
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec("delete from configitem where application=? and name=? and machine=?", configItem.Application, configItem.Name, configItem.Machine)
+	index, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = db.Exec("insert into configindex(application, idx) values(?, ?) on duplicate key update idx=values(idx)", application, index)
+	return err
 }
 
-func GetMysqlCreateDDL() []byte {
-	return dbCreateMySQL
+//	Gets the error to return for a MySQL error (with a code, if we know what it means)
+func getMySQLError(err error) error {
+	if err == nil || ErrorCode(err) != CodeInternal {
+		return err
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1062: //	Duplicate entry
+			return conflictError(err)
+		case 1040, 1044, 1045, 1049: //	Too many connections, access denied, unknown database
+			return unavailableError(err)
+		}
+	}
+
+	if errors.Is(err, mysql.ErrInvalidConn) || isConnectionError(err) {
+		return unavailableError(err)
+	}
+
+	return err
 }
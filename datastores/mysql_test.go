@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
@@ -29,6 +30,10 @@ func resetTestDB(store datastores.MySqlDB) {
 	defer db.Close()
 
 	db.Exec("TRUNCATE TABLE configitem")
+	db.Exec("TRUNCATE TABLE configsequence")
+	db.Exec("TRUNCATE TABLE configindex")
+	db.Exec("TRUNCATE TABLE configschedule")
+	db.Exec("TRUNCATE TABLE configproposal")
 }
 
 //	MySQL init should ping the database
@@ -46,8 +51,44 @@ func TestMysql_Init_Successful(t *testing.T) {
 	}
 }
 
-//	MySQL get should return successfully even if the item doesn't exist
-func TestMysql_Get_ItemDoesntExist_Successful(t *testing.T) {
+//	MySQL init should add the tables and columns a database from an earlier
+//	release doesn't have
+func TestMysql_Init_Upgrades(t *testing.T) {
+	//	Arrange
+	store := getDBConnection()
+	resetTestDB(store)
+
+	db, _ := sql.Open("mysql", fmt.Sprintf("%s:%s@%s(%s)/%s", store.User, store.Password, store.Protocol, store.Address, store.Database))
+	defer db.Close()
+
+	db.Exec("DROP TABLE configproposal")
+	db.Exec("ALTER TABLE configitem DROP INDEX idx_expires, DROP COLUMN expires, DROP COLUMN labels, DROP COLUMN description")
+
+	//	Act
+	err := store.InitStore(false)
+	againErr := store.InitStore(false)
+
+	//	Assert
+	if err != nil || againErr != nil {
+		t.Fatalf("Init failed: Should have upgraded the database: %v / %v", err, againErr)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	if _, err := store.Set(datastores.ConfigItem{Application: "billing", Name: "Timeout", Value: "30", Description: "Seconds", Labels: map[string]string{"owner": "payments"}, Expires: &expires}); err != nil {
+		t.Errorf("Set failed: Should have used the new columns: %s", err)
+	}
+
+	if item, err := store.Get(datastores.ConfigItem{Application: "billing", Name: "Timeout"}); err != nil || item.Description != "Seconds" || item.Expires == nil {
+		t.Errorf("Get failed: Should have read the new columns: %+v / %v", item, err)
+	}
+
+	if _, err := store.GetProposals(); err != nil {
+		t.Errorf("GetProposals failed: Should have created the table: %s", err)
+	}
+}
+
+//	MySQL get should return a not found error if the item doesn't exist
+func TestMysql_Get_ItemDoesntExist_NotFound(t *testing.T) {
 
 	//	Arrange
 	db := getDBConnection()
@@ -61,8 +102,8 @@ func TestMysql_Get_ItemDoesntExist_Successful(t *testing.T) {
 	response, err := db.Get(query)
 
 	//	Assert
-	if err != nil {
-		t.Errorf("Get failed: Should have returned an empty dataset without error: %s", err)
+	if !datastores.IsNotFound(err) {
+		t.Errorf("Get failed: Should have returned a not found error: %v", err)
 	}
 
 	if query.Value != response.Value && response.Value != "" {
@@ -494,3 +535,358 @@ func TestMysql_Remove_WithMachine_Successful(t *testing.T) {
 		t.Errorf("Remove failed: Should have removed an item without error: %s", err)
 	}
 }
+
+//	MySQL batch should set and remove items together
+func TestMysql_Batch_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	existing, _ := db.Set(datastores.ConfigItem{
+		Application: "MyTestAppName",
+		Name:        "TestItem1",
+		Value:       "Value1"})
+
+	set := []datastores.ConfigItem{
+		{Application: "MyTestAppName", Name: "TestItem2", Value: "Value2"},
+		{Application: "MyTestAppName", Name: "TestItem3", Value: "Value3"}}
+
+	//	Act
+	response, err := db.Batch(set, []datastores.ConfigItem{existing})
+
+	//	Assert
+	if err != nil {
+		t.Errorf("Batch failed: Should have completed without error: %s", err)
+	}
+
+	if len(response) != 2 || response[0].Id == 0 || response[1].Id == 0 {
+		t.Errorf("Batch failed: Should have returned the set items with ids: %+v", response)
+	}
+
+	items, _ := db.GetAllForApplication("MyTestAppName")
+	if len(items) != 2 {
+		t.Errorf("Batch failed: Should have 2 items left but found %d: %+v", len(items), items)
+	}
+}
+
+//	MySQL event sequence numbers should start at 0 and increase
+func TestMysql_EventSequence_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	//	Act
+	initial, initialErr := db.GetEventSequence()
+	first, _ := db.NextEventSequence()
+	second, err := db.NextEventSequence()
+	current, currentErr := db.GetEventSequence()
+
+	//	Assert
+	if initialErr != nil || err != nil || currentErr != nil {
+		t.Errorf("EventSequence failed: Should have completed without error: %v / %v / %v", initialErr, err, currentErr)
+	}
+
+	if initial != 0 || first != 1 || second != 2 || current != 2 {
+		t.Errorf("EventSequence failed: Should have returned 0, 1, 2, 2 but returned %d, %d, %d, %d", initial, first, second, current)
+	}
+}
+
+//	MySQL application indexes should increase when the application changes
+func TestMysql_ApplicationIndex_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+	item := datastores.ConfigItem{Application: "Formbuilder", Name: "Item1", Value: "Value1"}
+
+	//	Act
+	initial, initialErr := db.GetApplicationIndex("Formbuilder")
+	db.Set(item)
+	afterSet, _ := db.GetApplicationIndex("Formbuilder")
+	db.Set(datastores.ConfigItem{Application: "Other", Name: "Item1", Value: "Value1"})
+	afterOther, _ := db.GetApplicationIndex("Formbuilder")
+	db.Remove(datastores.ConfigItem{Application: "Formbuilder", Name: "Missing"})
+	afterMissing, _ := db.GetApplicationIndex("Formbuilder")
+	db.Remove(item)
+	afterRemove, err := db.GetApplicationIndex("Formbuilder")
+
+	//	Assert
+	if initialErr != nil || err != nil {
+		t.Errorf("ApplicationIndex failed: Should have completed without error: %v / %v", initialErr, err)
+	}
+
+	if initial != 0 || afterSet <= initial || afterRemove <= afterSet {
+		t.Errorf("ApplicationIndex failed: Should have increased with each change but returned %d, %d, %d", initial, afterSet, afterRemove)
+	}
+
+	if afterOther != afterSet || afterMissing != afterSet {
+		t.Errorf("ApplicationIndex failed: Other changes shouldn't affect the index: %d, %d, %d", afterSet, afterOther, afterMissing)
+	}
+}
+
+//	MySQL set should return a conflict for a new item that already exists
+func TestMysql_Set_Duplicate_Conflict(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+	item := datastores.ConfigItem{Application: "Formbuilder", Name: "Item1", Value: "Value1"}
+
+	//	Act
+	_, firstErr := db.Set(item)
+	_, err := db.Set(item)
+
+	//	Assert
+	if firstErr != nil || datastores.ErrorCode(err) != datastores.CodeConflict {
+		t.Errorf("Set failed: Should have returned a conflict error: %v / %v", firstErr, err)
+	}
+}
+
+//	MySQL list should filter, sort and page through items
+func TestMysql_List_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+	for _, item := range []datastores.ConfigItem{
+		{Application: "*", Name: "db.timeout", Value: "30"},
+		{Application: "billing", Name: "db.host", Value: "db01.example.com"},
+		{Application: "billing", Name: "db.host", Machine: "WEB01", Value: "db02.example.com"},
+		{Application: "billing", Name: "db_host", Value: "db04.example.com"},
+		{Application: "billing", Name: "ui.theme", Value: "dark"},
+	} {
+		db.Set(item)
+	}
+
+	//	Act
+	first, firstErr := db.List(datastores.ListOptions{Sort: "-name", Limit: 3})
+	second, secondErr := db.List(datastores.ListOptions{Sort: "-name", Limit: 3, Cursor: first.Next})
+	filtered, filteredErr := db.List(datastores.ListOptions{Applications: []string{"billing"}, Machines: []string{""}, NamePrefix: "db.", ValueContains: "example"})
+
+	//	Assert
+	if firstErr != nil || secondErr != nil || filteredErr != nil {
+		t.Errorf("List failed: Should have completed without error: %v / %v / %v", firstErr, secondErr, filteredErr)
+	}
+
+	if len(first.Items) != 3 || first.Items[0].Name != "ui.theme" || first.Next == "" || len(second.Items) != 2 || second.Next != "" {
+		t.Errorf("List failed: Should have returned 2 pages sorted by name: %+v / %+v", first, second)
+	}
+
+	if len(filtered.Items) != 1 || filtered.Items[0].Value != "db01.example.com" {
+		t.Errorf("List failed: Should have returned the machine-less db. item (and not db_host): %+v", filtered)
+	}
+}
+
+//	Mysql list should skip expired items that haven't been removed yet, unless
+//	they're asked for
+func TestMysql_List_Expired_Skipped(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	expired := time.Now().Add(-time.Minute)
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Banner", Value: "Down for maintenance", Expires: &expired})
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Timeout", Value: "30"})
+
+	//	Act
+	page, err := db.List(datastores.ListOptions{Applications: []string{"billing"}})
+	all, allErr := db.List(datastores.ListOptions{Applications: []string{"billing"}, IncludeExpired: true})
+
+	//	Assert
+	if err != nil || len(page.Items) != 1 || page.Items[0].Name != "Timeout" {
+		t.Errorf("List failed: Should have skipped the expired item: %+v / %v", page.Items, err)
+	}
+
+	if allErr != nil || len(all.Items) != 2 {
+		t.Errorf("List failed: Should have included the expired item: %+v / %v", all.Items, allErr)
+	}
+}
+
+//	MySQL should store descriptions and labels, and filter lists by labels
+func TestMysql_Labels_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "db.host", Value: "db01.example.com", Description: "The primary database", Labels: map[string]string{"owner": "payments", "pii": ""}},
+		{Application: "billing", Name: "db.user", Value: "billing", Labels: map[string]string{"owner": "payments-ops"}},
+		{Application: "billing", Name: "ui.theme", Value: "dark", Labels: map[string]string{"owner_team": "payments"}},
+	} {
+		db.Set(item)
+	}
+
+	//	Act
+	item, err := db.Get(datastores.ConfigItem{Application: "billing", Name: "db.host"})
+	owned, ownedErr := db.List(datastores.ListOptions{Labels: map[string]string{"owner": "payments"}})
+	pii, piiErr := db.List(datastores.ListOptions{Labels: map[string]string{"pii": ""}})
+
+	//	Assert
+	if err != nil || ownedErr != nil || piiErr != nil {
+		t.Errorf("Labels failed: Should have completed without error: %v / %v / %v", err, ownedErr, piiErr)
+	}
+
+	if item.Description != "The primary database" || item.Labels["owner"] != "payments" || len(item.Labels) != 2 {
+		t.Errorf("Labels failed: Should have stored the description and labels: %+v", item)
+	}
+
+	if len(owned.Items) != 1 || len(pii.Items) != 1 || owned.Items[0].Name != "db.host" || pii.Items[0].Name != "db.host" {
+		t.Errorf("Labels failed: Should have only listed db.host: %+v / %+v", owned, pii)
+	}
+}
+
+//	MySQL get should skip expired items, and RemoveExpired should remove them
+func TestMysql_Expired_Removed(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	for _, item := range []datastores.ConfigItem{
+		{Application: "billing", Name: "LogLevel", Value: "info"},
+		{Application: "billing", Name: "LogLevel", Machine: "WEB01", Value: "debug", Expires: &past},
+		{Application: "billing", Name: "Maintenance", Value: "true", Expires: &future},
+	} {
+		db.Set(item)
+	}
+
+	//	Act
+	fallback, fallbackErr := db.Get(datastores.ConfigItem{Application: "billing", Name: "LogLevel", Machine: "WEB01"})
+	maintenance, maintenanceErr := db.Get(datastores.ConfigItem{Application: "billing", Name: "Maintenance"})
+	removed, err := db.RemoveExpired(time.Now())
+
+	//	Assert
+	if fallbackErr != nil || fallback.Value != "info" {
+		t.Errorf("Get failed: Should have skipped the expired item: %+v / %v", fallback, fallbackErr)
+	}
+
+	if maintenanceErr != nil || maintenance.Expires == nil || maintenance.Expires.Sub(future) > time.Second || future.Sub(*maintenance.Expires) > time.Second {
+		t.Errorf("Get failed: Should have stored the expiry time: %+v / %v", maintenance, maintenanceErr)
+	}
+
+	if err != nil || len(removed) != 1 || removed[0].Machine != "WEB01" {
+		t.Errorf("RemoveExpired failed: Should have removed the expired item: %+v / %v", removed, err)
+	}
+}
+
+//	MySQL should store scheduled changes and make them when they're due
+func TestMysql_ApplyScheduledChanges_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	effective := time.Now().Add(time.Hour)
+	scheduled, err := db.ScheduleChange(datastores.ScheduledChange{Application: "billing", Name: "Maintenance", Value: "true", Effective: effective})
+	if err != nil {
+		t.Fatalf("ScheduleChange failed: %s", err)
+	}
+
+	//	Act
+	changes, listErr := db.GetScheduledChanges()
+	early, earlyErr := db.ApplyScheduledChanges(time.Now())
+	updated, err := db.ApplyScheduledChanges(effective.Add(time.Second))
+
+	//	Assert
+	if listErr != nil || len(changes) != 1 || changes[0].Id != scheduled.Id || changes[0].Effective.Sub(effective) > time.Second || effective.Sub(changes[0].Effective) > time.Second {
+		t.Errorf("GetScheduledChanges failed: Should have listed the change: %+v / %v", changes, listErr)
+	}
+
+	if earlyErr != nil || len(early) != 0 {
+		t.Errorf("ApplyScheduledChanges failed: Shouldn't have made the change early: %+v / %v", early, earlyErr)
+	}
+
+	if err != nil || len(updated) != 1 || updated[0].Value != "true" {
+		t.Errorf("ApplyScheduledChanges failed: Should have made the change: %+v / %v", updated, err)
+	}
+}
+
+//	MySQL should store proposals and make the change when they're approved
+func TestMysql_ApproveProposal_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	db.Set(datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "30"})
+
+	proposed, err := db.ProposeChange(datastores.ChangeProposal{
+		Action:     datastores.ProposalSet,
+		Item:       datastores.ConfigItem{Application: "prod-billing", Name: "Timeout", Value: "60"},
+		ProposedBy: "bob"})
+	if err != nil {
+		t.Fatalf("ProposeChange failed: %s", err)
+	}
+
+	//	Act
+	approved, item, err := db.ApproveProposal(proposed.Id, "alice", "Looks good")
+	_, _, againErr := db.ApproveProposal(proposed.Id, "carol", "")
+	proposals, listErr := db.GetProposals()
+
+	//	Assert
+	if err != nil || item.Value != "60" || approved.Previous == nil || approved.Previous.Value != "30" {
+		t.Errorf("ApproveProposal failed: %+v / %+v / %v", approved, item, err)
+	}
+
+	if datastores.ErrorCode(againErr) != datastores.CodeConflict {
+		t.Errorf("ApproveProposal failed: Approving twice should be a conflict: %v", againErr)
+	}
+
+	if listErr != nil || len(proposals) != 1 || proposals[0].Status != datastores.ProposalApproved || proposals[0].ReviewedBy != "alice" || proposals[0].Item.Value != "60" {
+		t.Errorf("GetProposals failed: Should have stored the review: %+v / %v", proposals, listErr)
+	}
+}
+
+//	MySQL should find an expired item that hasn't been removed yet by its exact
+//	key, so setting it again updates the row instead of conflicting with it
+func TestMysql_Set_ExpiredItem_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	past := time.Now().Add(-time.Minute)
+	original, err := db.Set(datastores.ConfigItem{Application: "billing", Name: "Maintenance", Value: "true", Expires: &past})
+	if err != nil {
+		t.Fatalf("Set failed: %s", err)
+	}
+
+	//	Act
+	existing, found, err := datastores.GetExactConfigItem(db, "billing", "", "Maintenance")
+	existing.Value = "false"
+	existing.Expires = nil
+	updated, setErr := db.Set(existing)
+
+	//	Assert
+	if err != nil || !found || existing.Id != original.Id {
+		t.Fatalf("GetExactConfigItem failed: Should have found the expired item: %+v / %v", existing, err)
+	}
+
+	if setErr != nil || updated.Id != original.Id {
+		t.Errorf("Set failed: Should have updated the expired item: %+v / %v", updated, setErr)
+	}
+
+	if item, err := db.Get(datastores.ConfigItem{Application: "billing", Name: "Maintenance"}); err != nil || item.Value != "false" {
+		t.Errorf("Get failed: Should have returned the updated item: %+v / %v", item, err)
+	}
+}
+
+//	MySQL GetAllForApplication should include the default (*) items, so they
+//	can be resolved
+func TestMysql_GetAllForApplication_Defaults_Successful(t *testing.T) {
+	//	Arrange
+	db := getDBConnection()
+	resetTestDB(db)
+
+	db.Set(datastores.ConfigItem{Application: "*", Name: "LogLevel", Value: "info"})
+	db.Set(datastores.ConfigItem{Application: "*", Name: "Timeout", Value: "30"})
+	db.Set(datastores.ConfigItem{Application: "billing", Name: "Timeout", Value: "60"})
+	db.Set(datastores.ConfigItem{Application: "payroll", Name: "Timeout", Value: "90"})
+
+	//	Act
+	items, err := db.GetAllForApplication("billing")
+	resolved := datastores.ResolveConfigItems(items, "billing", "")
+
+	//	Assert
+	if err != nil || len(items) != 3 {
+		t.Fatalf("GetAllForApplication failed: Should have returned the default and application items: %+v / %v", items, err)
+	}
+
+	if len(resolved) != 2 || resolved[0].Name != "LogLevel" || resolved[0].Value != "info" || resolved[1].Value != "60" {
+		t.Errorf("ResolveConfigItems failed: Should have resolved the default item: %+v", resolved)
+	}
+}
@@ -0,0 +1,125 @@
+package datastores
+
+import (
+	"strings"
+)
+
+//	Returns true if the name is the prefix, or is under it (like db.primary.host
+//	is under db.primary, but db.primaryhost isn't)
+func IsInConfigSubtree(name, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+".")
+}
+
+//	Makes sure a prefix is a dotted name (like db.primary)
+func validateConfigPrefix(prefix string) error {
+	if prefix == "" || strings.HasPrefix(prefix, ".") || strings.HasSuffix(prefix, ".") || strings.Contains(prefix, "..") {
+		return newConfigError(CodeValidation, nil, "The prefix '%s' should be a dotted name (like db.primary)", prefix)
+	}
+
+	return nil
+}
+
+//	Gets the items in the subtree a page at a time (with the name prefix, so
+//	stores only read the items that start with it).  Changes to the subtree
+//	include the expired items that haven't been removed yet
+func listConfigSubtree(store ConfigService, application, prefix string, includeExpired bool) ([]ConfigItem, error) {
+	retval := []ConfigItem{}
+
+	if err := validateConfigPrefix(prefix); err != nil {
+		return retval, err
+	}
+
+	options := ListOptions{
+		NamePrefix:     prefix,
+		Sort:           SortName,
+		Limit:          MaxListLimit,
+		IncludeExpired: includeExpired}
+	if application != "" {
+		options.Applications = []string{application}
+	}
+
+	for {
+		page, err := store.List(options)
+		if err != nil {
+			return retval, err
+		}
+
+		for _, item := range page.Items {
+			if IsInConfigSubtree(item.Name, prefix) {
+				retval = append(retval, item)
+			}
+		}
+
+		if page.Next == "" {
+			return retval, nil
+		}
+		options.Cursor = page.Next
+	}
+}
+
+//	Removes the items in the subtree in a single batch, and returns them
+func removeConfigSubtree(store ConfigService, application, prefix string) ([]ConfigItem, error) {
+	if application == "" {
+		return []ConfigItem{}, newConfigError(CodeValidation, nil, "An application is required")
+	}
+
+	items, err := listConfigSubtree(store, application, prefix, true)
+	if err != nil || len(items) == 0 {
+		return items, err
+	}
+
+	if _, err := store.Batch(nil, items); err != nil {
+		return []ConfigItem{}, err
+	}
+
+	return items, nil
+}
+
+//	Moves the items in the subtree under a new prefix in a single batch.  Items
+//	keep their ids, and nothing is moved if an item already exists with one of
+//	the new names.  It returns the moved items and the items that were replaced
+func renameConfigSubtree(store ConfigService, application, from, to string) ([]ConfigItem, []ConfigItem, error) {
+	if application == "" {
+		return nil, nil, newConfigError(CodeValidation, nil, "An application is required")
+	}
+
+	if err := validateConfigPrefix(to); err != nil {
+		return nil, nil, err
+	}
+
+	if IsInConfigSubtree(to, from) || IsInConfigSubtree(from, to) {
+		return nil, nil, newConfigError(CodeValidation, nil, "Can't move '%s' to '%s': one is inside the other", from, to)
+	}
+
+	items, err := listConfigSubtree(store, application, from, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, nil, newConfigError(CodeNotFound, nil, "No config items found under '%s'", from)
+	}
+
+	existing, err := listConfigSubtree(store, application, to, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(existing) > 0 {
+		return nil, nil, newConfigError(CodeConflict, nil, "Can't move '%s' to '%s': config items already exist under '%s'", from, to, to)
+	}
+
+	set := []ConfigItem{}
+	for _, item := range items {
+		moved := item
+		moved.Name = to + strings.TrimPrefix(item.Name, from)
+		set = append(set, moved)
+	}
+
+	updated, err := store.Batch(set, items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updated, items, nil
+}
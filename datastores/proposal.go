@@ -0,0 +1,241 @@
+package datastores
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+//	Proposal actions
+const (
+	//	Set the proposal's item
+	ProposalSet = "set"
+
+	//	Remove the proposal's item
+	ProposalRemove = "remove"
+)
+
+//	Proposal statuses
+const (
+	//	Waiting for a review
+	ProposalPending = "pending"
+
+	//	Approved, and the change was made
+	ProposalApproved = "approved"
+
+	//	Rejected, so the change wasn't made
+	ProposalRejected = "rejected"
+
+	//	Approved, but the change couldn't be made (see Error)
+	ProposalFailed = "failed"
+)
+
+//	ChangeProposal is a change to a protected application that's waiting for
+//	(or has had) a review by someone other than the person who proposed it.
+//	Proposals are never removed, so they're an audit trail of the changes
+type ChangeProposal struct {
+	Id     int64      `json:"id"`
+	Action string     `json:"action"`
+	Item   ConfigItem `json:"item"`
+	Status string     `json:"status"`
+
+	ProposedBy string    `json:"proposedBy"`
+	Proposed   time.Time `json:"proposed"`
+
+	ReviewedBy string     `json:"reviewedBy,omitempty"`
+	Reviewed   *time.Time `json:"reviewed,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+
+	//	The item before the change was made (if there was one)
+	Previous *ConfigItem `json:"previous,omitempty"`
+
+	//	Why an approved change couldn't be made
+	Error string `json:"error,omitempty"`
+}
+
+//	Makes sure a proposal can be stored
+func validateChangeProposal(proposal ChangeProposal) error {
+	switch proposal.Action {
+	case ProposalSet:
+		if err := validateConfigItem(proposal.Item); err != nil {
+			return err
+		}
+	case ProposalRemove:
+		if proposal.Item.Application == "" || proposal.Item.Name == "" {
+			return newConfigError(CodeValidation, nil, "An application and name are required")
+		}
+	default:
+		return newConfigError(CodeValidation, nil, "The proposal action should be %s or %s", ProposalSet, ProposalRemove)
+	}
+
+	if strings.TrimSpace(proposal.ProposedBy) == "" {
+		return newConfigError(CodeForbidden, nil, "A user name is required to propose a change")
+	}
+
+	return nil
+}
+
+//	Gets the error for a proposal that doesn't exist
+func proposalNotFoundError(id int64) error {
+	return newConfigError(CodeNotFound, nil, "No proposal found with the id %d", id)
+}
+
+//	Gets the error for a proposal that isn't in the expected status anymore
+func proposalStatusError(proposal ChangeProposal) error {
+	return newConfigError(CodeConflict, nil, "Proposal %d is %s", proposal.Id, proposal.Status)
+}
+
+//	Makes sure the reviewer can review the proposal.  It has to be pending,
+//	and reviewed by someone other than the person who proposed it
+func checkProposalReview(proposal ChangeProposal, reviewer string) error {
+	if proposal.Status != ProposalPending {
+		return proposalStatusError(proposal)
+	}
+
+	if strings.TrimSpace(reviewer) == "" {
+		return newConfigError(CodeForbidden, nil, "A user name is required to review a proposal")
+	}
+
+	if strings.EqualFold(reviewer, proposal.ProposedBy) {
+		return newConfigError(CodeForbidden, nil, "Proposals have to be reviewed by someone other than the person who proposed them")
+	}
+
+	return nil
+}
+
+//	Approves the proposal and makes the change.  The review is recorded before
+//	the change is made, so if two reviewers approve at once only one of them
+//	makes the change.  If the change can't be made, the proposal is marked as
+//	failed.  Returns the proposal and the item that was set or removed
+func approveChangeProposal(store ConfigService, id int64, reviewer, comment string) (ChangeProposal, ConfigItem, error) {
+	proposal, err := store.GetProposal(id)
+	if err != nil {
+		return ChangeProposal{}, ConfigItem{}, err
+	}
+
+	if err := checkProposalReview(proposal, reviewer); err != nil {
+		return proposal, ConfigItem{}, err
+	}
+
+	previous, found, err := GetExactConfigItem(store, proposal.Item.Application, proposal.Item.Machine, proposal.Item.Name)
+	if err != nil {
+		return proposal, ConfigItem{}, err
+	}
+
+	reviewed := time.Now()
+	proposal.Status = ProposalApproved
+	proposal.ReviewedBy = reviewer
+	proposal.Reviewed = &reviewed
+	proposal.Comment = comment
+	if found {
+		proposal.Previous = &previous
+	}
+
+	proposal, err = store.UpdateProposal(proposal, ProposalPending)
+	if err != nil {
+		return proposal, ConfigItem{}, err
+	}
+
+	item, err := applyChangeProposal(store, proposal)
+	if err != nil {
+		proposal.Status = ProposalFailed
+		proposal.Error = err.Error()
+		if ErrorCode(err) == CodeInternal {
+			proposal.Error = "The change couldn't be made"
+		}
+
+		if failed, updateErr := store.UpdateProposal(proposal, ProposalApproved); updateErr == nil {
+			proposal = failed
+		}
+		return proposal, ConfigItem{}, err
+	}
+
+	return proposal, item, nil
+}
+
+//	Makes the proposal's change.  Returns the item that was set or removed
+func applyChangeProposal(store ConfigService, proposal ChangeProposal) (ConfigItem, error) {
+	if proposal.Action == ProposalRemove {
+		if proposal.Previous == nil {
+			return ConfigItem{}, newConfigError(CodeNotFound, nil, "The config item doesn't exist anymore")
+		}
+
+		return *proposal.Previous, store.Remove(*proposal.Previous)
+	}
+
+	item := proposal.Item
+	item.Id = 0
+	if proposal.Previous != nil {
+		item.Id = proposal.Previous.Id
+	}
+
+	return store.Set(item)
+}
+
+//	Rejects the proposal, so the change isn't made
+func rejectChangeProposal(store ConfigService, id int64, reviewer, comment string) (ChangeProposal, error) {
+	proposal, err := store.GetProposal(id)
+	if err != nil {
+		return ChangeProposal{}, err
+	}
+
+	if err := checkProposalReview(proposal, reviewer); err != nil {
+		return proposal, err
+	}
+
+	reviewed := time.Now()
+	proposal.Status = ProposalRejected
+	proposal.ReviewedBy = reviewer
+	proposal.Reviewed = &reviewed
+	proposal.Comment = comment
+
+	return store.UpdateProposal(proposal, ProposalPending)
+}
+
+//	The proposal columns, in the order scanSQLProposal reads them
+const sqlProposalColumns = "id, action, item, status, proposedby, proposed, reviewedby, reviewed, comment, previous, error"
+
+//	Reads a row (a *sql.Row or *sql.Rows)
+type sqlScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+//	Reads a proposal from a row with the sqlProposalColumns.  The items are
+//	stored as JSON, since they're a snapshot of the change
+func scanSQLProposal(row sqlScanner) (ChangeProposal, error) {
+	retval := ChangeProposal{}
+	var item string
+	var reviewed sql.NullTime
+	var previous sql.NullString
+
+	if err := row.Scan(&retval.Id, &retval.Action, &item, &retval.Status, &retval.ProposedBy, &retval.Proposed, &retval.ReviewedBy, &reviewed, &retval.Comment, &previous, &retval.Error); err != nil {
+		return ChangeProposal{}, err
+	}
+
+	if err := json.Unmarshal([]byte(item), &retval.Item); err != nil {
+		return ChangeProposal{}, err
+	}
+
+	if previous.Valid {
+		retval.Previous = &ConfigItem{}
+		if err := json.Unmarshal([]byte(previous.String), retval.Previous); err != nil {
+			return ChangeProposal{}, err
+		}
+	}
+
+	retval.Proposed = readSQLTime(retval.Proposed)
+	retval.Reviewed = readSQLNullTime(reviewed)
+
+	return retval, nil
+}
+
+//	Gets the previous item to store in a SQL column (null if there isn't one)
+func getSQLProposalPrevious(proposal ChangeProposal) (interface{}, error) {
+	if proposal.Previous == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(proposal.Previous)
+	return string(encoded), err
+}
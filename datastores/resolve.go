@@ -0,0 +1,67 @@
+package datastores
+
+import (
+	"sort"
+	"time"
+)
+
+//	ResolveConfigItems takes the items returned from GetAllForApplication
+//	and returns the effective item for each name for the given application and machine.
+//	An application item always wins over a default (*) item, and a
+//	machine specific item wins over one without a machine name.  Items for other
+//	machines (and expired items) are ignored.  The results are sorted by name.
+func ResolveConfigItems(items []ConfigItem, application, machine string) []ConfigItem {
+	//	Track the best item found for each name (and how good it is)
+	resolved := make(map[string]ConfigItem)
+	ranks := make(map[string]int)
+	now := time.Now()
+
+	for _, item := range items {
+		rank := getResolveRank(item, application, machine)
+		if rank == 0 || IsExpired(item, now) {
+			continue
+		}
+
+		if rank > ranks[item.Name] {
+			resolved[item.Name] = item
+			ranks[item.Name] = rank
+		}
+	}
+
+	//	Return the resolved items in name order
+	retval := []ConfigItem{}
+	for _, item := range resolved {
+		retval = append(retval, item)
+	}
+
+	sort.Slice(retval, func(i, j int) bool {
+		return retval[i].Name < retval[j].Name
+	})
+
+	return retval
+}
+
+//	Gets the precedence of an item for the given application and machine.
+//	0 means the item doesn't apply at all
+func getResolveRank(item ConfigItem, application, machine string) int {
+	if item.Machine != "" && item.Machine != machine {
+		return 0
+	}
+
+	rank := 0
+	switch item.Application {
+	case application:
+		rank = 3
+	case "*":
+		rank = 1
+	default:
+		return 0
+	}
+
+	//	Machine specific items beat items without a machine
+	if item.Machine != "" {
+		rank++
+	}
+
+	return rank
+}
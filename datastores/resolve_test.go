@@ -0,0 +1,79 @@
+package datastores_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Resolve should prefer application items over global items, and machine items over both
+func TestResolveConfigItems_Precedence_Successful(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Application: "*", Name: "Global", Value: "global"},
+		{Application: "*", Name: "Overridden", Value: "global"},
+		{Application: "*", Name: "MachineOverridden", Value: "global"},
+		{Application: "Billing", Name: "Overridden", Value: "app"},
+		{Application: "Billing", Name: "MachineOverridden", Value: "app"},
+		{Application: "Billing", Name: "MachineOverridden", Machine: "WEB01", Value: "machine"},
+		{Application: "Billing", Name: "MachineOverridden", Machine: "WEB02", Value: "othermachine"},
+	}
+
+	//	Act
+	resolved := datastores.ResolveConfigItems(items, "Billing", "WEB01")
+
+	//	Assert
+	expected := map[string]string{
+		"Global":            "global",
+		"MachineOverridden": "machine",
+		"Overridden":        "app",
+	}
+
+	if len(resolved) != len(expected) {
+		t.Fatalf("Resolve failed: Should have returned %d items but returned %d: %+v", len(expected), len(resolved), resolved)
+	}
+
+	for _, item := range resolved {
+		if item.Value != expected[item.Name] {
+			t.Errorf("Resolve failed: %s should be '%s' but was '%s'", item.Name, expected[item.Name], item.Value)
+		}
+	}
+}
+
+//	Resolve should ignore items for other machines and other applications
+func TestResolveConfigItems_OtherMachine_Ignored(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Application: "Billing", Name: "OnlyOnWEB02", Machine: "WEB02", Value: "machine"},
+		{Application: "Reporting", Name: "OtherApp", Value: "other"},
+	}
+
+	//	Act
+	resolved := datastores.ResolveConfigItems(items, "Billing", "")
+
+	//	Assert
+	if len(resolved) != 0 {
+		t.Errorf("Resolve failed: Shouldn't have returned any items: %+v", resolved)
+	}
+}
+
+//	Resolve should ignore expired items (so an expired override falls back)
+func TestResolveConfigItems_Expired_Ignored(t *testing.T) {
+	//	Arrange
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	items := []datastores.ConfigItem{
+		{Application: "Billing", Name: "LogLevel", Value: "info"},
+		{Application: "Billing", Name: "LogLevel", Machine: "WEB01", Value: "debug", Expires: &past},
+		{Application: "Billing", Name: "Maintenance", Value: "true", Expires: &future},
+	}
+
+	//	Act
+	resolved := datastores.ResolveConfigItems(items, "Billing", "WEB01")
+
+	//	Assert
+	if len(resolved) != 2 || resolved[0].Value != "info" || resolved[1].Value != "true" {
+		t.Errorf("Resolve failed: Should have skipped the expired override: %+v", resolved)
+	}
+}
@@ -5,27 +5,50 @@ import (
 	"time"
 )
 
-//	ConfigItem represents a configuration item
+//	ConfigItem represents a configuration item.  Items can have a description,
+//	labels (like owner: payments, or pii with a blank value) and an expiry time
+//	(for temporary overrides)
 type ConfigItem struct {
-	Id          int64     `sql:"id" json:"id"`
-	Application string    `sql:"application" json:"application"`
-	Machine     string    `sql:"machine" json:"machine"`
-	Name        string    `sql:"name" json:"name"`
-	Value       string    `sql:"value" json:"value"`
-	LastUpdated time.Time `sql:"updated" json:"updated"`
+	Id          int64             `sql:"id" json:"id"`
+	Application string            `sql:"application" json:"application"`
+	Machine     string            `sql:"machine" json:"machine"`
+	Name        string            `sql:"name" json:"name"`
+	Value       string            `sql:"value" json:"value"`
+	LastUpdated time.Time         `sql:"updated" json:"updated"`
+	Description string            `sql:"description" json:"description,omitempty"`
+	Labels      map[string]string `sql:"labels" json:"labels,omitempty"`
+	Expires     *time.Time        `sql:"expires" json:"expires,omitempty"`
 }
 
-//	ConfigResponse represents an API response
+//	ConfigResponse represents an API response.  Error responses have
+//	a code (like not_found) that identifies the kind of error, and
+//	paged lists have the cursor for the next page
 type ConfigResponse struct {
 	Status  int         `json:"status"`
+	Code    string      `json:"code,omitempty"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	Next    string      `json:"next,omitempty"`
 }
 
 //	WebSocketResponse represents a WebSocket event response
 type WebSocketResponse struct {
-	Type string     `json:"type"`
-	Data ConfigItem `json:"data"`
+	Type     string     `json:"type"`
+	Data     ConfigItem `json:"data"`
+	Sequence int64      `json:"sequence,omitempty"`
+	Message  string     `json:"message,omitempty"`
+}
+
+//	WebSocketCommand represents a command sent to the server over a WebSocket
+//	(subscribe, unsubscribe, ping or resume).  The application, machine and name
+//	are patterns (like billing-* or db.*) used by subscribe and unsubscribe.
+//	A blank pattern matches everything
+type WebSocketCommand struct {
+	Type        string `json:"type"`
+	Application string `json:"application,omitempty"`
+	Machine     string `json:"machine,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Since       int64  `json:"since,omitempty"`
 }
 
 //	ConfigService encapsulates account (user) based operations
@@ -40,7 +63,7 @@ type ConfigService interface {
 	//	Create / update a config item
 	Set(c ConfigItem) (ConfigItem, error)
 
-	//	Get a specific config item
+	//	Get a specific config item (or a CodeNotFound error if there isn't one)
 	Get(c ConfigItem) (ConfigItem, error)
 
 	//	Get all config items for the given application
@@ -49,11 +72,77 @@ type ConfigService interface {
 	//	Get all config items for all applications (including global)
 	GetAll() ([]ConfigItem, error)
 
+	//	Get a page of config items, filtered and sorted by the options
+	List(options ListOptions) (ConfigPage, error)
+
+	//	Get the config items in a subtree of dotted names (like everything under db.primary)
+	ListPrefix(application, prefix string) ([]ConfigItem, error)
+
+	//	Remove the config items in a subtree.  Returns the removed items
+	RemovePrefix(application, prefix string) ([]ConfigItem, error)
+
+	//	Move the config items in a subtree to a new prefix.  Returns the moved
+	//	items and the items they replaced
+	RenamePrefix(application, from, to string) ([]ConfigItem, []ConfigItem, error)
+
+	//	Search the names and values of config items (secret values aren't searched)
+	Search(options SearchOptions) ([]ConfigItem, error)
+
 	//	Get all applications (including global)
 	GetAllApplications() ([]string, error)
 
 	//	Remove a config item
 	Remove(c ConfigItem) error
+
+	//	Remove the config items that have expired by the given time.  Returns the removed items
+	RemoveExpired(now time.Time) ([]ConfigItem, error)
+
+	//	Schedule a config value change for its effective time.  Returns the
+	//	change with its id
+	ScheduleChange(change ScheduledChange) (ScheduledChange, error)
+
+	//	Get the scheduled changes that haven't been made yet, in the order they'll be made
+	GetScheduledChanges() ([]ScheduledChange, error)
+
+	//	Cancel a scheduled change (or return a CodeNotFound error if there isn't one)
+	CancelScheduledChange(id int64) (ScheduledChange, error)
+
+	//	Make the scheduled changes that are effective by the given time.  Returns the updated items
+	ApplyScheduledChanges(now time.Time) ([]ConfigItem, error)
+
+	//	Store a proposed change to a protected application.  Returns the
+	//	pending proposal with its id
+	ProposeChange(proposal ChangeProposal) (ChangeProposal, error)
+
+	//	Get all proposals (including the ones that have been reviewed), oldest first
+	GetProposals() ([]ChangeProposal, error)
+
+	//	Get a proposal (or a CodeNotFound error if there isn't one)
+	GetProposal(id int64) (ChangeProposal, error)
+
+	//	Record a review of a proposal that has the given status (or return a
+	//	CodeConflict error if it doesn't have that status anymore)
+	UpdateProposal(proposal ChangeProposal, status string) (ChangeProposal, error)
+
+	//	Approve a pending proposal and make the change.  Returns the proposal and
+	//	the item that was set or removed
+	ApproveProposal(id int64, reviewer, comment string) (ChangeProposal, ConfigItem, error)
+
+	//	Reject a pending proposal
+	RejectProposal(id int64, reviewer, comment string) (ChangeProposal, error)
+
+	//	Set and remove many config items in a single transaction
+	Batch(set []ConfigItem, remove []ConfigItem) ([]ConfigItem, error)
+
+	//	Get the sequence number of the last change event
+	GetEventSequence() (int64, error)
+
+	//	Get the sequence number for a new change event (and remember it)
+	NextEventSequence() (int64, error)
+
+	//	Get the modification index for the application.  Every change to an
+	//	application's config items sets its index to a new, higher value
+	GetApplicationIndex(application string) (int64, error)
 }
 
 //	Get the currently configured datastore
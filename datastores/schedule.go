@@ -0,0 +1,123 @@
+package datastores
+
+import (
+	"sort"
+	"time"
+)
+
+//	ScheduledChange is a config value change that's made at its effective time
+//	(like during a maintenance window).  Changes are kept in the datastore, so
+//	they're still made if the server restarts before then
+type ScheduledChange struct {
+	Id          int64     `sql:"id" json:"id"`
+	Application string    `sql:"application" json:"application"`
+	Machine     string    `sql:"machine" json:"machine"`
+	Name        string    `sql:"name" json:"name"`
+	Value       string    `sql:"value" json:"value"`
+	Effective   time.Time `sql:"effective" json:"effective"`
+	Created     time.Time `sql:"created" json:"created"`
+}
+
+//	Makes sure a scheduled change can be stored
+func validateScheduledChange(change ScheduledChange) error {
+	if change.Application == "" || change.Name == "" {
+		return newConfigError(CodeValidation, nil, "An application and name are required")
+	}
+
+	if change.Effective.IsZero() {
+		return newConfigError(CodeValidation, nil, "An effective time is required")
+	}
+
+	return nil
+}
+
+//	Gets the error for a scheduled change that doesn't exist
+func scheduledChangeNotFoundError(id int64) error {
+	return newConfigError(CodeNotFound, nil, "No scheduled change found with the id %d", id)
+}
+
+//	Sorts scheduled changes in the order they're made (by effective time, then
+//	in the order they were scheduled)
+func sortScheduledChanges(changes []ScheduledChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].Effective.Equal(changes[j].Effective) {
+			return changes[i].Effective.Before(changes[j].Effective)
+		}
+		return changes[i].Id < changes[j].Id
+	})
+}
+
+//	GetExactConfigItem gets the config item with exactly the application,
+//	machine and name (no falling back like Get does).  Unlike Get, it returns
+//	items that have expired but haven't been removed yet (they still have to
+//	be updated instead of being created again)
+func GetExactConfigItem(store ConfigService, application, machine, name string) (ConfigItem, bool, error) {
+	page, err := store.List(ListOptions{
+		Applications:   []string{application},
+		Machines:       []string{machine},
+		NamePrefix:     name,
+		Sort:           SortName,
+		Limit:          1,
+		IncludeExpired: true})
+	if err != nil {
+		return ConfigItem{}, false, err
+	}
+
+	for _, item := range page.Items {
+		if item.Name == name {
+			return item, true, nil
+		}
+	}
+
+	return ConfigItem{}, false, nil
+}
+
+//	Makes the scheduled changes that are effective by the given time, in order,
+//	and returns the updated items.  Each change is only removed after it's
+//	made, so a change that can't be made right now (like when the database is
+//	unavailable) is made the next time.  If several servers share the
+//	datastore they may both make a change, but it sets the same value.  Items
+//	keep their description, labels and expiry time -- only the value changes
+func applyScheduledChanges(store ConfigService, now time.Time) ([]ConfigItem, error) {
+	retval := []ConfigItem{}
+
+	changes, err := store.GetScheduledChanges()
+	if err != nil {
+		return retval, err
+	}
+
+	for _, change := range changes {
+		if change.Effective.After(now) {
+			break
+		}
+
+		item, found, err := GetExactConfigItem(store, change.Application, change.Machine, change.Name)
+		if err != nil {
+			return retval, err
+		}
+
+		if !found {
+			item = ConfigItem{Application: change.Application, Machine: change.Machine, Name: change.Name}
+		}
+		item.Value = change.Value
+
+		//	An expired item is replaced (it just hasn't been removed yet):
+		if IsExpired(item, now) {
+			item.Expires = nil
+		}
+
+		updated, err := store.Set(item)
+		if err != nil {
+			return retval, err
+		}
+
+		//	Another server may have made (or someone cancelled) the change at the same time:
+		if _, err := store.CancelScheduledChange(change.Id); err != nil && !IsNotFound(err) {
+			return retval, err
+		}
+
+		retval = append(retval, updated)
+	}
+
+	return retval, nil
+}
@@ -0,0 +1,196 @@
+package datastores
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//	How Search matches the query
+const (
+	//	The name or value contains the query (the default)
+	MatchSubstring = "substring"
+
+	//	The name or value matches the query as a regular expression
+	MatchRegex = "regex"
+
+	//	The name or value is the query
+	MatchExact = "exact"
+)
+
+//	The fields Search can match
+const (
+	SearchName  = "name"
+	SearchValue = "value"
+)
+
+//	Name patterns (path.Match syntax, matched without case) for items with secret
+//	values.  Search doesn't match secret values, so searching for part of a
+//	password doesn't reveal which items have it
+var DefaultSecretNames = []string{
+	"*password*",
+	"*passwd*",
+	"*secret*",
+	"*token*",
+	"*apikey*",
+	"*api_key*",
+	"*api-key*",
+	"*privatekey*",
+	"*private_key*",
+	"*credential*",
+	"*connectionstring*",
+}
+
+var (
+	secretNames   = DefaultSecretNames
+	secretNamesMx sync.RWMutex
+)
+
+//	SearchOptions is the query (and the scope) for Search
+type SearchOptions struct {
+	//	The text (or regular expression) to search for
+	Query string
+
+	//	How the query is matched: MatchSubstring (the default), MatchRegex or MatchExact
+	Match string
+
+	//	The fields to match: SearchName and / or SearchValue (both if there aren't any)
+	Fields []string
+
+	//	Match without case
+	IgnoreCase bool
+
+	//	Only items for these applications (every application if there aren't any)
+	Applications []string
+
+	//	Only items for these machines (use a blank machine for items that don't
+	//	have one).  If this is nil, items for every machine are searched
+	Machines []string
+
+	//	The most items to return (0 for all of them, up to MaxListLimit)
+	Limit int
+}
+
+//	SetSecretNames sets the name patterns for items with secret values
+//	(DefaultSecretNames if there aren't any)
+func SetSecretNames(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Secret name pattern '%s' isn't valid: %v", pattern, err)
+		}
+	}
+
+	if len(patterns) == 0 {
+		patterns = DefaultSecretNames
+	}
+
+	secretNamesMx.Lock()
+	defer secretNamesMx.Unlock()
+	secretNames = patterns
+	return nil
+}
+
+//	IsSecretConfigItem returns true if the item's name matches one of the
+//	secret name patterns
+func IsSecretConfigItem(item ConfigItem) bool {
+	secretNamesMx.RLock()
+	defer secretNamesMx.RUnlock()
+
+	name := strings.ToLower(item.Name)
+	for _, pattern := range secretNames {
+		if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+//	Gets a function that matches a name or value with the search options
+func getSearchMatcher(options SearchOptions) (func(string) bool, error) {
+	if options.Query == "" {
+		return nil, newConfigError(CodeValidation, nil, "A search query is required")
+	}
+
+	switch options.Match {
+	case "", MatchSubstring:
+		if options.IgnoreCase {
+			query := strings.ToLower(options.Query)
+			return func(s string) bool { return strings.Contains(strings.ToLower(s), query) }, nil
+		}
+		return func(s string) bool { return strings.Contains(s, options.Query) }, nil
+
+	case MatchExact:
+		if options.IgnoreCase {
+			return func(s string) bool { return strings.EqualFold(s, options.Query) }, nil
+		}
+		return func(s string) bool { return s == options.Query }, nil
+
+	case MatchRegex:
+		expression := options.Query
+		if options.IgnoreCase {
+			expression = "(?i)" + expression
+		}
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, newConfigError(CodeValidation, err, "The search query isn't a valid regular expression: %v", err)
+		}
+		return re.MatchString, nil
+	}
+
+	return nil, newConfigError(CodeValidation, nil, "The match should be substring, regex or exact")
+}
+
+//	Searches the items a store lists (scoped to the applications and machines in
+//	the options) a page at a time, so stores don't need to load everything at once
+func searchConfigItems(store ConfigService, options SearchOptions) ([]ConfigItem, error) {
+	if options.Limit < 0 || options.Limit > MaxListLimit {
+		return nil, newConfigError(CodeValidation, nil, "The limit should be between 0 (for no limit) and %d", MaxListLimit)
+	}
+
+	matches, err := getSearchMatcher(options)
+	if err != nil {
+		return nil, err
+	}
+
+	searchName, searchValue := len(options.Fields) == 0, len(options.Fields) == 0
+	for _, field := range options.Fields {
+		switch field {
+		case SearchName:
+			searchName = true
+		case SearchValue:
+			searchValue = true
+		default:
+			return nil, newConfigError(CodeValidation, nil, "The search fields should be name and / or value")
+		}
+	}
+
+	retval := []ConfigItem{}
+	listOptions := ListOptions{
+		Applications: options.Applications,
+		Machines:     options.Machines,
+		Limit:        MaxListLimit}
+
+	for {
+		page, err := store.List(listOptions)
+		if err != nil {
+			return retval, err
+		}
+
+		for _, item := range page.Items {
+			if (searchName && matches(item.Name)) || (searchValue && !IsSecretConfigItem(item) && matches(item.Value)) {
+				retval = append(retval, item)
+				if options.Limit > 0 && len(retval) == options.Limit {
+					return retval, nil
+				}
+			}
+		}
+
+		if page.Next == "" {
+			return retval, nil
+		}
+		listOptions.Cursor = page.Next
+	}
+}
@@ -1,5 +1,9 @@
 package datastores
 
+import (
+	"time"
+)
+
 //	The Unknown database information
 type UnknownDB struct{}
 
@@ -8,7 +12,7 @@ func (store UnknownDB) InitStore(overwrite bool) error {
 }
 
 func (store UnknownDB) Get(configItem ConfigItem) (ConfigItem, error) {
-	return ConfigItem{}, nil
+	return ConfigItem{}, notFoundError()
 }
 
 func (store UnknownDB) GetAllForApplication(application string) ([]ConfigItem, error) {
@@ -19,6 +23,70 @@ func (store UnknownDB) GetAll() ([]ConfigItem, error) {
 	return nil, nil
 }
 
+func (store UnknownDB) List(options ListOptions) (ConfigPage, error) {
+	return ConfigPage{Items: []ConfigItem{}}, nil
+}
+
+func (store UnknownDB) ListPrefix(application, prefix string) ([]ConfigItem, error) {
+	return []ConfigItem{}, nil
+}
+
+func (store UnknownDB) RemovePrefix(application, prefix string) ([]ConfigItem, error) {
+	return []ConfigItem{}, nil
+}
+
+func (store UnknownDB) RenamePrefix(application, from, to string) ([]ConfigItem, []ConfigItem, error) {
+	return []ConfigItem{}, []ConfigItem{}, nil
+}
+
+func (store UnknownDB) RemoveExpired(now time.Time) ([]ConfigItem, error) {
+	return []ConfigItem{}, nil
+}
+
+func (store UnknownDB) ScheduleChange(change ScheduledChange) (ScheduledChange, error) {
+	return change, nil
+}
+
+func (store UnknownDB) GetScheduledChanges() ([]ScheduledChange, error) {
+	return nil, nil
+}
+
+func (store UnknownDB) CancelScheduledChange(id int64) (ScheduledChange, error) {
+	return ScheduledChange{}, scheduledChangeNotFoundError(id)
+}
+
+func (store UnknownDB) ApplyScheduledChanges(now time.Time) ([]ConfigItem, error) {
+	return nil, nil
+}
+
+func (store UnknownDB) ProposeChange(proposal ChangeProposal) (ChangeProposal, error) {
+	return proposal, nil
+}
+
+func (store UnknownDB) GetProposals() ([]ChangeProposal, error) {
+	return nil, nil
+}
+
+func (store UnknownDB) GetProposal(id int64) (ChangeProposal, error) {
+	return ChangeProposal{}, proposalNotFoundError(id)
+}
+
+func (store UnknownDB) UpdateProposal(proposal ChangeProposal, status string) (ChangeProposal, error) {
+	return ChangeProposal{}, proposalNotFoundError(proposal.Id)
+}
+
+func (store UnknownDB) ApproveProposal(id int64, reviewer, comment string) (ChangeProposal, ConfigItem, error) {
+	return ChangeProposal{}, ConfigItem{}, proposalNotFoundError(id)
+}
+
+func (store UnknownDB) RejectProposal(id int64, reviewer, comment string) (ChangeProposal, error) {
+	return ChangeProposal{}, proposalNotFoundError(id)
+}
+
+func (store UnknownDB) Search(options SearchOptions) ([]ConfigItem, error) {
+	return []ConfigItem{}, nil
+}
+
 func (store UnknownDB) GetAllApplications() ([]string, error) {
 	return nil, nil
 }
@@ -30,3 +98,19 @@ func (store UnknownDB) Set(configItem ConfigItem) (ConfigItem, error) {
 func (store UnknownDB) Remove(configItem ConfigItem) error {
 	return nil
 }
+
+func (store UnknownDB) Batch(set []ConfigItem, remove []ConfigItem) ([]ConfigItem, error) {
+	return nil, nil
+}
+
+func (store UnknownDB) GetEventSequence() (int64, error) {
+	return 0, nil
+}
+
+func (store UnknownDB) NextEventSequence() (int64, error) {
+	return 0, nil
+}
+
+func (store UnknownDB) GetApplicationIndex(application string) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,73 @@
+package datastores
+
+import (
+	"database/sql"
+	"strings"
+)
+
+//	sqlUpgrade is a part of a SQL schema (a table or a column added since the
+//	first release).  Upgrades only make the changes a database doesn't have
+//	yet, so they're safe to run every time the server starts
+type sqlUpgrade struct {
+	//	Counts the table or column (0 if the database doesn't have it yet)
+	Check string
+
+	//	Adds the table or column (and its indexes)
+	Statements []string
+}
+
+//	Makes the upgrades the database doesn't have yet, in order.  A database
+//	that's up to date isn't changed, so the user only needs permission to
+//	change the schema when there's an upgrade to make
+func upgradeSQLStore(db *sql.DB, upgrades []sqlUpgrade) error {
+	for _, upgrade := range upgrades {
+		upgraded, err := hasSQLUpgrade(db, upgrade)
+		if err != nil {
+			return err
+		}
+
+		if upgraded {
+			continue
+		}
+
+		for _, statement := range upgrade.Statements {
+			if _, err := db.Exec(statement); err != nil {
+				//	Another server may have made the upgrade at the same time:
+				if upgraded, checkErr := hasSQLUpgrade(db, upgrade); checkErr == nil && upgraded {
+					break
+				}
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//	Returns true if the database already has the upgrade
+func hasSQLUpgrade(db *sql.DB, upgrade sqlUpgrade) (bool, error) {
+	var count int
+	if err := db.QueryRow(upgrade.Check).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+//	Gets the statements that create a new database, with the separator
+//	after each one
+func getSQLCreateDDL(header string, upgrades []sqlUpgrade, separator string) []byte {
+	ddl := strings.Builder{}
+	ddl.WriteString(header)
+
+	for _, upgrade := range upgrades {
+		for _, statement := range upgrade.Statements {
+			ddl.WriteString("\n")
+			ddl.WriteString(strings.TrimSpace(statement))
+			ddl.WriteString(separator)
+			ddl.WriteString("\n")
+		}
+	}
+
+	return []byte(ddl.String())
+}
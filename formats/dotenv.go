@@ -0,0 +1,118 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+//	Renders name=value lines.  Simple values are written as-is, everything else
+//	is single quoted (so shells and dotenv readers don't expand variables) or double
+//	quoted with escapes if the value contains a single quote or a line break
+func encodeDotEnv(values map[string]string) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	for _, name := range sortedNames(values) {
+		if name == "" || strings.ContainsAny(name, "= \t\r\n#\"'") {
+			return nil, fmt.Errorf("Can't write config item '%s' as a dotenv variable name", name)
+		}
+
+		fmt.Fprintf(&buf, "%s=%s\n", name, quoteDotEnvValue(values[name]))
+	}
+
+	return buf.Bytes(), nil
+}
+
+//	Quotes a dotenv value (if necessary)
+func quoteDotEnvValue(value string) string {
+	if isSimpleValue(value) {
+		return value
+	}
+
+	if !strings.ContainsAny(value, "'\r\n") {
+		return "'" + value + "'"
+	}
+
+	return strings.Replace(quoteValue(value), "$", `\$`, -1)
+}
+
+//	Simple values don't need quoting in any format
+func isSimpleValue(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("._-/:@,+%", r):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+//	Reads name=value lines.  Supports blank lines, # comments, an optional
+//	'export' prefix and unquoted, single quoted or double quoted values
+func decodeDotEnv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	rest := strings.Replace(string(data), "\r\n", "\n", -1)
+
+	for lineNumber := 1; rest != ""; lineNumber++ {
+		//	Get the next line:
+		line := rest
+		rest = ""
+		if i := strings.Index(line, "\n"); i >= 0 {
+			line, rest = line[:i], line[i+1:]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("Can't read dotenv line %d: expected name=value", lineNumber)
+		}
+		name := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("Can't read dotenv line %d: value is missing a closing single quote", lineNumber)
+			}
+			value = value[1 : end+1]
+
+		case strings.HasPrefix(value, `"`):
+			//	Double quoted values can span lines:
+			unquoted, remaining, err := unquoteValue(value + "\n" + rest)
+			if err != nil {
+				return nil, fmt.Errorf("Can't read dotenv line %d: %s", lineNumber, err)
+			}
+			value = unquoted
+
+			//	Skip past the end of the value (and anything else on that line):
+			lineNumber += strings.Count(value, "\n")
+			rest = ""
+			if i := strings.Index(remaining, "\n"); i >= 0 {
+				rest = remaining[i+1:]
+			}
+
+		default:
+			//	Unquoted values can have a trailing comment:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
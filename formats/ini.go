@@ -0,0 +1,113 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//	Renders an INI file.  With nesting, everything but the last
+//	part of a dotted name becomes the section
+func encodeINI(values map[string]string, opts Options) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	//	Group the values by section:
+	sections := make(map[string]map[string]string)
+	for name, value := range values {
+		section, key := "", name
+		if opts.Nest {
+			if i := strings.LastIndex(name, "."); i > 0 {
+				section, key = name[:i], name[i+1:]
+			}
+		}
+
+		if key == "" || strings.ContainsAny(key, "=[]\r\n;#") || strings.TrimSpace(key) != key {
+			return nil, fmt.Errorf("Can't write config item '%s' as an INI key", name)
+		}
+
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		sections[section][key] = value
+	}
+
+	sectionNames := []string{}
+	for section := range sections {
+		sectionNames = append(sectionNames, section)
+	}
+	sort.Strings(sectionNames)
+
+	//	The unnamed section sorts first, so it ends up at the top of the file:
+	for _, section := range sectionNames {
+		if section != "" {
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(&buf, "[%s]\n", section)
+		}
+
+		for _, key := range sortedNames(sections[section]) {
+			fmt.Fprintf(&buf, "%s = %s\n", key, quoteINIValue(sections[section][key]))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+//	Quotes an INI value if it would otherwise be misread
+func quoteINIValue(value string) string {
+	if value == "" {
+		return value
+	}
+
+	if strings.TrimSpace(value) != value || strings.ContainsAny(value, "\"';#\\\r\n") {
+		return quoteValue(value)
+	}
+
+	return value
+}
+
+//	Reads an INI file.  Keys in a section are prefixed with the section name
+//	(so [db.primary] host = db01 becomes db.primary.host)
+func decodeINI(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		//	Section headers:
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("Can't read INI line %d: section is missing a closing bracket", i+1)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		//	key = value (or key: value)
+		separator := strings.IndexAny(line, "=:")
+		if separator <= 0 {
+			return nil, fmt.Errorf("Can't read INI line %d: expected key = value", i+1)
+		}
+		key := strings.TrimSpace(line[:separator])
+		value := strings.TrimSpace(line[separator+1:])
+
+		if strings.HasPrefix(value, `"`) {
+			unquoted, _, err := unquoteValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("Can't read INI line %d: %s", i+1, err)
+			}
+			value = unquoted
+		}
+
+		values[joinName(section, key)] = value
+	}
+
+	return values, nil
+}
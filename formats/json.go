@@ -0,0 +1,49 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+//	Renders a JSON object
+func encodeJSON(values map[string]string, opts Options) ([]byte, error) {
+	var document interface{} = values
+
+	if opts.Nest {
+		tree, err := nestValues(values)
+		if err != nil {
+			return nil, err
+		}
+		document = tree
+	}
+
+	//	Don't escape HTML characters -- this is a config file, not a web page:
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+//	Reads a JSON object
+func decodeJSON(data []byte) (map[string]string, error) {
+	//	Keep numbers exactly as they were written:
+	document := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("Can't read JSON: %s", err)
+	}
+
+	values := make(map[string]string)
+	if err := flattenValue(values, "", document); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
@@ -0,0 +1,72 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+
+	"github.com/magiconair/properties"
+)
+
+//	Renders a Java .properties file.  Non-ASCII characters are written as
+//	\uXXXX escapes so the file can be loaded with the default ISO-8859-1 encoding
+func encodeProperties(values map[string]string) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	for _, name := range sortedNames(values) {
+		fmt.Fprintf(&buf, "%s=%s\n", escapeProperty(name, true), escapeProperty(values[name], false))
+	}
+
+	return buf.Bytes(), nil
+}
+
+//	Escapes a property key or value
+func escapeProperty(s string, isKey bool) string {
+	buf := bytes.Buffer{}
+
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			buf.WriteString(`\ `)
+		case (r == '=' || r == ':') && isKey:
+			buf.WriteRune('\\')
+			buf.WriteRune(r)
+		case (r == '#' || r == '!') && i == 0 && isKey:
+			buf.WriteRune('\\')
+			buf.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			//	Characters outside the BMP are written as surrogate pairs
+			if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(&buf, `\u%04x\u%04x`, r1, r2)
+			} else {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			}
+		default:
+			buf.WriteRune(r)
+		}
+	}
+
+	return buf.String()
+}
+
+//	Reads a Java .properties file.  ${name} references are left as-is
+func decodeProperties(data []byte) (map[string]string, error) {
+	loader := properties.Loader{Encoding: properties.UTF8, DisableExpansion: true}
+	props, err := loader.LoadBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read properties: %s", err)
+	}
+
+	return props.Map(), nil
+}
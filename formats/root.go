@@ -0,0 +1,330 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+)
+
+//	Format is a configuration file format
+type Format string
+
+const (
+	DotEnv     Format = "env"
+	YAML       Format = "yaml"
+	JSON       Format = "json"
+	TOML       Format = "toml"
+	INI        Format = "ini"
+	Properties Format = "properties"
+)
+
+//	Options control how config items are rendered
+type Options struct {
+	//	Nest dotted names (db.primary.host) into trees / sections
+	//	for the formats that support it (YAML, JSON, TOML and INI)
+	Nest bool
+}
+
+//	ParseFormat gets the format for the given name (or common file extension)
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "env", "dotenv":
+		return DotEnv, nil
+	case "yaml", "yml":
+		return YAML, nil
+	case "json":
+		return JSON, nil
+	case "toml":
+		return TOML, nil
+	case "ini":
+		return INI, nil
+	case "properties", "props":
+		return Properties, nil
+	}
+
+	return "", fmt.Errorf("Unknown format '%s'.  Should be one of: env, yaml, json, toml, ini, properties", name)
+}
+
+//	ContentType gets the MIME type to use when serving the format
+func (f Format) ContentType() string {
+	switch f {
+	case YAML:
+		return "application/x-yaml; charset=utf-8"
+	case JSON:
+		return "application/json; charset=utf-8"
+	case TOML:
+		return "application/toml; charset=utf-8"
+	}
+
+	return "text/plain; charset=utf-8"
+}
+
+//	Encode renders the config items in the given format.  Items are expected
+//	to already be resolved (one item per name)
+func Encode(items []datastores.ConfigItem, format Format, opts Options) ([]byte, error) {
+	values := make(map[string]string)
+	for _, item := range items {
+		values[item.Name] = item.Value
+	}
+
+	switch format {
+	case DotEnv:
+		return encodeDotEnv(values)
+	case YAML:
+		return encodeYAML(values, opts)
+	case JSON:
+		return encodeJSON(values, opts)
+	case TOML:
+		return encodeTOML(values, opts)
+	case INI:
+		return encodeINI(values, opts)
+	case Properties:
+		return encodeProperties(values)
+	}
+
+	return nil, fmt.Errorf("Unknown format '%s'", format)
+}
+
+//	Nest nests the dotted names of the config items (db.primary.host) into a tree
+//	of maps.  Items are expected to already be resolved (one item per name)
+func Nest(items []datastores.ConfigItem) (map[string]interface{}, error) {
+	values := make(map[string]string)
+	for _, item := range items {
+		values[item.Name] = item.Value
+	}
+
+	return nestValues(values)
+}
+
+//	Gets the names in sorted order
+func sortedNames(values map[string]string) []string {
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//	Nests dotted names into a tree of maps.  A name can't be both a value and
+//	a parent of other values (db and db.host), so that's reported as an error
+func nestValues(values map[string]string) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+
+	for _, name := range sortedNames(values) {
+		parts := strings.Split(name, ".")
+		node := tree
+
+		for i, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("Can't nest config item '%s': it has an empty path segment", name)
+			}
+
+			//	The last part is the value:
+			if i == len(parts)-1 {
+				if _, exists := node[part]; exists {
+					return nil, fmt.Errorf("Can't nest config item '%s': it conflicts with other items under the same name", name)
+				}
+				node[part] = values[name]
+				break
+			}
+
+			//	Otherwise, find (or create) the child node:
+			switch child := node[part].(type) {
+			case nil:
+				next := make(map[string]interface{})
+				node[part] = next
+				node = next
+			case map[string]interface{}:
+				node = child
+			default:
+				return nil, fmt.Errorf("Can't nest config item '%s': '%s' already has a value", name, strings.Join(parts[:i+1], "."))
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+//	Quotes a value using double quotes and backslash escapes
+//	(this is compatible with dotenv, TOML and INI readers)
+func quoteValue(value string) string {
+	buf := bytes.Buffer{}
+	buf.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+				continue
+			}
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+
+	return buf.String()
+}
+
+//	Reads a double quoted value (as written by quoteValue) from the start of s.
+//	Returns the value and whatever follows the closing quote
+func unquoteValue(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, fmt.Errorf("value should start with a double quote")
+	}
+
+	buf := bytes.Buffer{}
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return buf.String(), s[i+1:], nil
+
+		case '\\':
+			if i+1 >= len(s) {
+				return "", "", fmt.Errorf("value ends with an unfinished escape")
+			}
+			i++
+
+			switch e := s[i]; e {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'u':
+				if i+4 >= len(s) {
+					return "", "", fmt.Errorf("value has an invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", "", fmt.Errorf("value has an invalid unicode escape")
+				}
+				buf.WriteRune(rune(r))
+				i += 4
+			default:
+				//	\\, \" and \$ (and anything else) are the character itself
+				buf.WriteByte(e)
+			}
+
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return "", "", fmt.Errorf("value is missing a closing double quote")
+}
+
+//	Decode reads a config file in the given format and returns the values
+//	by name.  Nested documents are flattened into dotted names (db.primary.host),
+//	scalar values are converted to strings and lists of scalars are joined with commas
+func Decode(data []byte, format Format) (map[string]string, error) {
+	switch format {
+	case DotEnv:
+		return decodeDotEnv(data)
+	case YAML:
+		return decodeYAML(data)
+	case JSON:
+		return decodeJSON(data)
+	case TOML:
+		return decodeTOML(data)
+	case INI:
+		return decodeINI(data)
+	case Properties:
+		return decodeProperties(data)
+	}
+
+	return nil, fmt.Errorf("Unknown format '%s'", format)
+}
+
+//	Flattens a decoded document into the values map, using dotted names for nested items
+func flattenValue(values map[string]string, name string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if err := flattenValue(values, joinName(name, key), child); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case map[interface{}]interface{}:
+		for key, child := range v {
+			if err := flattenValue(values, joinName(name, fmt.Sprint(key)), child); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case []interface{}:
+		parts := []string{}
+		for _, child := range v {
+			part, err := scalarString(child)
+			if err != nil {
+				return fmt.Errorf("Can't read '%s': lists can only contain simple values", name)
+			}
+			parts = append(parts, part)
+		}
+		values[name] = strings.Join(parts, ",")
+		return nil
+	}
+
+	scalar, err := scalarString(value)
+	if err != nil {
+		return fmt.Errorf("Can't read '%s': %s", name, err)
+	}
+	values[name] = scalar
+
+	return nil
+}
+
+//	Joins a parent and child name with a dot
+func joinName(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+
+	return parent + "." + child
+}
+
+//	Converts a simple decoded value to a string
+func scalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
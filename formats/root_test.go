@@ -0,0 +1,235 @@
+package formats_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cagedtornado/centralconfig/datastores"
+	"github.com/cagedtornado/centralconfig/formats"
+)
+
+func getTestItems() []datastores.ConfigItem {
+	return []datastores.ConfigItem{
+		{Name: "db.primary.host", Value: "db01"},
+		{Name: "db.primary.port", Value: "3306"},
+		{Name: "greeting", Value: "It's a \"test\""},
+		{Name: "path", Value: `C:\config`},
+	}
+}
+
+//	Each format should render without error
+func TestEncode_AllFormats_Successful(t *testing.T) {
+	//	Arrange
+	items := getTestItems()
+	names := []string{"env", "yaml", "json", "toml", "ini", "properties"}
+
+	for _, name := range names {
+		format, err := formats.ParseFormat(name)
+		if err != nil {
+			t.Fatalf("ParseFormat failed for %s: %s", name, err)
+		}
+
+		for _, nest := range []bool{false, true} {
+			//	Act
+			output, err := formats.Encode(items, format, formats.Options{Nest: nest})
+
+			//	Assert
+			if err != nil {
+				t.Errorf("Encode failed for %s (nest: %v): %s", name, nest, err)
+			}
+
+			if !strings.Contains(string(output), "db01") {
+				t.Errorf("Encode failed for %s (nest: %v): value is missing from output: %s", name, nest, output)
+			}
+		}
+	}
+}
+
+//	Nesting should turn dotted names into trees
+func TestEncode_JSONNested_Successful(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Name: "db.primary.host", Value: "db01"},
+		{Name: "db.primary.port", Value: "3306"},
+	}
+
+	//	Act
+	output, err := formats.Encode(items, formats.JSON, formats.Options{Nest: true})
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	expected := `{
+  "db": {
+    "primary": {
+      "host": "db01",
+      "port": "3306"
+    }
+  }
+}
+`
+	if string(output) != expected {
+		t.Errorf("Encode failed: expected %s but got %s", expected, output)
+	}
+}
+
+//	Nesting should fail if a name is both a value and a parent
+func TestEncode_NestedConflict_ReturnsError(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Name: "db", Value: "mysql"},
+		{Name: "db.host", Value: "db01"},
+	}
+
+	//	Act
+	_, err := formats.Encode(items, formats.YAML, formats.Options{Nest: true})
+
+	//	Assert
+	if err == nil {
+		t.Errorf("Encode failed: Should have returned an error for conflicting names")
+	}
+}
+
+//	TOML should quote dotted names when not nesting
+func TestEncode_TOMLFlat_QuotesDottedNames(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Name: "db.host", Value: "db01"},
+	}
+
+	//	Act
+	output, err := formats.Encode(items, formats.TOML, formats.Options{})
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	if string(output) != "\"db.host\" = \"db01\"\n" {
+		t.Errorf("Encode failed: unexpected output %s", output)
+	}
+}
+
+//	Properties should escape backslashes, separators and non-ASCII characters
+func TestEncode_Properties_Escaped(t *testing.T) {
+	//	Arrange
+	items := []datastores.ConfigItem{
+		{Name: "key=name", Value: `C:\config`},
+		{Name: "café", Value: "naïve"},
+	}
+
+	//	Act
+	output, err := formats.Encode(items, formats.Properties, formats.Options{})
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	expected := "caf\\u00e9=na\\u00efve\nkey\\=name=C:\\\\config\n"
+	if string(output) != expected {
+		t.Errorf("Encode failed: expected %q but got %q", expected, output)
+	}
+}
+
+//	Anything we encode should decode back to the same values
+func TestDecode_RoundTrip_Successful(t *testing.T) {
+	//	Arrange
+	items := getTestItems()
+	items = append(items,
+		datastores.ConfigItem{Name: "multiline", Value: "line1\nline2"},
+		datastores.ConfigItem{Name: "unicode", Value: "naïve café"})
+
+	for _, format := range []formats.Format{formats.DotEnv, formats.YAML, formats.JSON, formats.TOML, formats.INI, formats.Properties} {
+		for _, nest := range []bool{false, true} {
+			output, err := formats.Encode(items, format, formats.Options{Nest: nest})
+			if err != nil {
+				t.Fatalf("Encode failed for %s: %s", format, err)
+			}
+
+			//	Act
+			values, err := formats.Decode(output, format)
+
+			//	Assert
+			if err != nil {
+				t.Fatalf("Decode failed for %s (nest: %v): %s", format, nest, err)
+			}
+
+			if len(values) != len(items) {
+				t.Errorf("Decode failed for %s (nest: %v): expected %d values but got %d: %+v", format, nest, len(items), len(values), values)
+			}
+
+			for _, item := range items {
+				if values[item.Name] != item.Value {
+					t.Errorf("Decode failed for %s (nest: %v): %s should be %q but was %q", format, nest, item.Name, item.Value, values[item.Name])
+				}
+			}
+		}
+	}
+}
+
+//	Nested documents should be flattened into dotted names
+func TestDecode_YAMLNested_Flattened(t *testing.T) {
+	//	Arrange
+	document := []byte(`
+db:
+  primary:
+    host: db01
+    port: 3306
+  enabled: true
+hosts: [web01, web02]
+`)
+
+	//	Act
+	values, err := formats.Decode(document, formats.YAML)
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	expected := map[string]string{
+		"db.primary.host": "db01",
+		"db.primary.port": "3306",
+		"db.enabled":      "true",
+		"hosts":           "web01,web02",
+	}
+
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Decode failed: %s should be %q but was %q", name, value, values[name])
+		}
+	}
+}
+
+//	Dotenv files should allow comments, export prefixes and quoting
+func TestDecode_DotEnv_Successful(t *testing.T) {
+	//	Arrange
+	document := []byte(`# A comment
+export PLAIN=value # trailing comment
+SINGLE='$HOME stays'
+DOUBLE="tab\there"
+`)
+
+	//	Act
+	values, err := formats.Decode(document, formats.DotEnv)
+
+	//	Assert
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	expected := map[string]string{
+		"PLAIN":  "value",
+		"SINGLE": "$HOME stays",
+		"DOUBLE": "tab\there",
+	}
+
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Decode failed: %s should be %q but was %q", name, value, values[name])
+		}
+	}
+}
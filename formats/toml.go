@@ -0,0 +1,112 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+//	Renders a TOML document.  Without nesting, dotted names are quoted so
+//	they stay flat keys when read back in
+func encodeTOML(values map[string]string, opts Options) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	if !opts.Nest {
+		for _, name := range sortedNames(values) {
+			fmt.Fprintf(&buf, "%s = %s\n", tomlKey(name), quoteValue(values[name]))
+		}
+		return buf.Bytes(), nil
+	}
+
+	tree, err := nestValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	writeTOMLTable(&buf, nil, tree)
+
+	return buf.Bytes(), nil
+}
+
+//	Writes the values of a table followed by each of its child tables
+func writeTOMLTable(buf *bytes.Buffer, path []string, table map[string]interface{}) {
+	keys := []string{}
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	//	Values first (they belong to the current table):
+	for _, key := range keys {
+		if value, ok := table[key].(string); ok {
+			fmt.Fprintf(buf, "%s = %s\n", tomlKey(key), quoteValue(value))
+		}
+	}
+
+	//	... then the child tables:
+	for _, key := range keys {
+		child, ok := table[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		childPath := append(append([]string{}, path...), key)
+		if hasTOMLValues(child) {
+			if buf.Len() > 0 {
+				buf.WriteString("\n")
+			}
+
+			quoted := []string{}
+			for _, part := range childPath {
+				quoted = append(quoted, tomlKey(part))
+			}
+			fmt.Fprintf(buf, "[%s]\n", strings.Join(quoted, "."))
+		}
+
+		writeTOMLTable(buf, childPath, child)
+	}
+}
+
+//	Tables that only contain other tables don't need their own header
+func hasTOMLValues(table map[string]interface{}) bool {
+	for _, value := range table {
+		if _, ok := value.(string); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+//	Gets the key as a bare key if possible, otherwise as a quoted key
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return quoteValue(key)
+		}
+	}
+
+	return key
+}
+
+//	Reads a TOML document
+func decodeTOML(data []byte) (map[string]string, error) {
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read TOML: %s", err)
+	}
+
+	values := make(map[string]string)
+	if err := flattenValue(values, "", tree.ToMap()); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
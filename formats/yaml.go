@@ -0,0 +1,36 @@
+package formats
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+//	Renders a YAML document
+func encodeYAML(values map[string]string, opts Options) ([]byte, error) {
+	if !opts.Nest {
+		return yaml.Marshal(values)
+	}
+
+	tree, err := nestValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(tree)
+}
+
+//	Reads a YAML document
+func decodeYAML(data []byte) (map[string]string, error) {
+	document := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("Can't read YAML: %s", err)
+	}
+
+	values := make(map[string]string)
+	if err := flattenValue(values, "", document); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}